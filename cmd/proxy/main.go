@@ -7,15 +7,20 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/golovatskygroup/mcp-lens/internal/presets"
 	"github.com/golovatskygroup/mcp-lens/internal/proxy"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
 	"github.com/golovatskygroup/mcp-lens/internal/server"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
 	"gopkg.in/yaml.v3"
 )
 
 // UpstreamConfig wraps proxy.Config with optional preset field
 type UpstreamConfig struct {
+	// Name namespaces the upstream's tools as "<name>.<tool>" (used with `upstreams:`).
+	Name string `yaml:"name,omitempty"`
 	// Preset selects a built-in preset (e.g., "github")
 	Preset string `yaml:"preset,omitempty"`
 	// Command to execute
@@ -24,15 +29,46 @@ type UpstreamConfig struct {
 	Args []string `yaml:"args,omitempty"`
 	// Environment variables to set
 	Env map[string]string `yaml:"env,omitempty"`
+	// Type is the upstream transport: stdio (default), http (Streamable HTTP) or sse (legacy HTTP+SSE)
+	Type string `yaml:"type,omitempty"`
+	// URL of a remote upstream (type http/sse)
+	URL string `yaml:"url,omitempty"`
+	// Headers for remote upstreams; values expand env vars (e.g. "Bearer ${API_TOKEN}")
+	Headers map[string]string `yaml:"headers,omitempty"`
 }
 
 // Config wraps the upstream configuration
 type Config struct {
 	Upstream UpstreamConfig `yaml:"upstream"`
+	// Upstreams aggregates several upstream servers; when set, Upstream is ignored.
+	Upstreams []UpstreamConfig `yaml:"upstreams,omitempty"`
+	// Listen is the HTTP listen address; --listen takes precedence.
+	Listen string `yaml:"listen,omitempty"`
+	// AllowedOrigins are browser Origins accepted by the HTTP listener besides localhost.
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+	// SessionIdleTimeout ends HTTP sessions idle for longer (e.g. "30m", the default); negative keeps them until DELETE.
+	SessionIdleTimeout time.Duration `yaml:"session_idle_timeout,omitempty"`
+	// Policy configures which tools the router may run (inline).
+	Policy *router.PolicyConfig `yaml:"policy,omitempty"`
+	// PolicyFile points to a YAML/JSON policy file (used when Policy is not set).
+	PolicyFile string `yaml:"policy_file,omitempty"`
+	// LLM selects the router's LLM provider and models; unset fields fall back to env vars.
+	LLM *router.LLMConfig `yaml:"llm,omitempty"`
+	// Recipes are named, parameterized plans run with query {recipe, params}.
+	Recipes []router.Recipe `yaml:"recipes,omitempty"`
+	// ContextExtractors add regex extractors for URLs/IDs in the query (after the built-ins).
+	ContextExtractors []router.ContextExtractorConfig `yaml:"context_extractors,omitempty"`
+	// ToolViews declares output view presets (summary/metadata paths) per tool, e.g. for upstream tools.
+	ToolViews map[string]router.ViewPresets `yaml:"tool_views,omitempty"`
+	// Timeouts sets the per-step (default and per-tool) and per-query deadlines; unset fields fall back to env vars.
+	Timeouts *router.TimeoutsConfig `yaml:"timeouts,omitempty"`
+	// Redaction configures the secret/PII redaction of tool results (on by default).
+	Redaction *router.RedactionConfig `yaml:"redaction,omitempty"`
 }
 
 func main() {
 	configPath := flag.String("config", "", "Path to config file")
+	listenAddr := flag.String("listen", "", "Serve MCP over Streamable HTTP on this address (e.g. 127.0.0.1:8080) instead of stdio")
 	flag.Parse()
 
 	// Default config with github preset
@@ -61,7 +97,7 @@ func main() {
 
 	// Load upstream config with preset registry and environment variable support
 	// Priority: env vars > config file > defaults
-	upstreamCfg, err := resolveUpstreamConfig(cfg.Upstream)
+	upstreams, err := resolveUpstreams(cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error resolving upstream config: %v\n", err)
 		os.Exit(1)
@@ -79,28 +115,136 @@ func main() {
 		cancel()
 	}()
 
+	if *listenAddr != "" {
+		cfg.Listen = *listenAddr
+	}
+
+	policy, err := resolvePolicy(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading policy: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create and run server
-	srv := server.New(ctx, upstreamCfg)
+	srv, err := server.NewWithUpstreams(ctx, upstreams)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring upstreams: %v\n", err)
+		os.Exit(1)
+	}
+	if policy != nil {
+		srv.SetPolicy(*policy)
+	}
+	if cfg.LLM != nil {
+		srv.SetLLMConfig(*cfg.LLM)
+	}
+	if err := srv.SetRecipes(cfg.Recipes); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading recipes: %v\n", err)
+		os.Exit(1)
+	}
+	if err := srv.SetContextExtractors(cfg.ContextExtractors); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading context extractors: %v\n", err)
+		os.Exit(1)
+	}
+	if err := srv.SetToolViews(cfg.ToolViews); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading tool views: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.Timeouts != nil {
+		if err := srv.SetTimeouts(*cfg.Timeouts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error configuring timeouts: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.Redaction != nil {
+		if err := srv.SetRedaction(*cfg.Redaction); err != nil {
+			fmt.Fprintf(os.Stderr, "Error configuring redaction: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.Listen != "" {
+		httpTransport := mcp.NewHTTPTransport(cfg.Listen)
+		httpTransport.SetAllowedOrigins(cfg.AllowedOrigins)
+		if cfg.SessionIdleTimeout != 0 {
+			httpTransport.SetSessionIdleTimeout(cfg.SessionIdleTimeout)
+		}
+		srv.SetTransport(httpTransport)
+		go func() {
+			<-ctx.Done()
+			httpTransport.Close()
+		}()
+		go func() {
+			fmt.Fprintf(os.Stderr, "[mcp-proxy] Listening on %s%s\n", cfg.Listen, mcp.DefaultHTTPPath)
+			if err := httpTransport.ListenAndServe(); err != nil {
+				fmt.Fprintf(os.Stderr, "HTTP listener error: %v\n", err)
+				cancel()
+			}
+		}()
+	}
 	if err := srv.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// resolveUpstreams returns the upstream list: `upstreams:` entries (preset + inline fields, each
+// namespaced by name) or the single legacy `upstream:` (which also honors MCP_LENS_UPSTREAM_* env overrides).
+func resolveUpstreams(cfg Config) ([]proxy.NamedConfig, error) {
+	if len(cfg.Upstreams) == 0 {
+		upstreamCfg, err := resolveUpstreamConfig(cfg.Upstream)
+		if err != nil {
+			return nil, err
+		}
+		return []proxy.NamedConfig{{Name: cfg.Upstream.Name, Config: upstreamCfg}}, nil
+	}
+
+	reg := presets.NewRegistry()
+	out := make([]proxy.NamedConfig, 0, len(cfg.Upstreams))
+	for i, u := range cfg.Upstreams {
+		if len(cfg.Upstreams) > 1 && u.Name == "" {
+			return nil, fmt.Errorf("upstreams[%d]: name is required when several upstreams are configured", i)
+		}
+		c, err := applyPreset(reg, u)
+		if err != nil {
+			return nil, fmt.Errorf("upstreams[%d]: %w", i, err)
+		}
+		if c.Type == "" || c.Type == proxy.TypeStdio {
+			if c.Command == "" {
+				return nil, fmt.Errorf("upstreams[%d]: command or preset is required", i)
+			}
+		} else if c.URL == "" {
+			return nil, fmt.Errorf("upstreams[%d]: url is required for type %s", i, c.Type)
+		}
+		out = append(out, proxy.NamedConfig{Name: u.Name, Config: c})
+	}
+	return out, nil
+}
+
 // resolveUpstreamConfig resolves the upstream config using preset registry and env vars
 // Priority:
 // 1. If preset is specified, use it as base
 // 2. Override with inline fields from config file
 // 3. Apply environment variable overrides on top
 func resolveUpstreamConfig(upstreamCfg UpstreamConfig) (proxy.Config, error) {
-	reg := presets.NewRegistry()
+	base, err := applyPreset(presets.NewRegistry(), upstreamCfg)
+	if err != nil {
+		return proxy.Config{}, err
+	}
+
+	// ENV overrides (including MCP_LENS_PRESET) have highest priority.
+	return presets.LoadConfig(base)
+}
 
+// applyPreset uses upstream.preset (if set) as the base and lets inline fields override it.
+func applyPreset(reg *presets.Registry, upstreamCfg UpstreamConfig) (proxy.Config, error) {
 	// Base config comes from YAML (or defaults already loaded into upstreamCfg).
 	// If upstream.preset is set in YAML, we use that preset as the base first.
 	base := proxy.Config{
+		Type:    upstreamCfg.Type,
 		Command: upstreamCfg.Command,
 		Args:    upstreamCfg.Args,
 		Env:     upstreamCfg.Env,
+		URL:     upstreamCfg.URL,
+		Headers: upstreamCfg.Headers,
 	}
 
 	if upstreamCfg.Preset != "" {
@@ -112,22 +256,55 @@ func resolveUpstreamConfig(upstreamCfg UpstreamConfig) (proxy.Config, error) {
 		base = presetCfg
 
 		// YAML inline fields override preset values.
+		if upstreamCfg.Type != "" {
+			base.Type = upstreamCfg.Type
+		}
 		if upstreamCfg.Command != "" {
 			base.Command = upstreamCfg.Command
 		}
+		if upstreamCfg.URL != "" {
+			base.URL = upstreamCfg.URL
+		}
+		if len(upstreamCfg.Headers) > 0 {
+			base.Headers = upstreamCfg.Headers
+		}
 		if len(upstreamCfg.Args) > 0 {
 			base.Args = upstreamCfg.Args
 		}
 		if len(upstreamCfg.Env) > 0 {
-			if base.Env == nil {
-				base.Env = make(map[string]string)
+			// Copy so several upstreams using the same preset do not share one env map.
+			env := make(map[string]string, len(base.Env)+len(upstreamCfg.Env))
+			for k, v := range base.Env {
+				env[k] = v
 			}
 			for k, v := range upstreamCfg.Env {
-				base.Env[k] = v
+				env[k] = v
 			}
+			base.Env = env
 		}
 	}
+	return base, nil
+}
 
-	// ENV overrides (including MCP_LENS_PRESET) have highest priority.
-	return presets.LoadConfig(base)
+// resolvePolicy builds the router policy from the inline `policy:` section or `policy_file:`.
+// Returns nil when neither is set (the router keeps its built-in default policy).
+func resolvePolicy(cfg Config) (*router.Policy, error) {
+	var pc router.PolicyConfig
+	switch {
+	case cfg.Policy != nil:
+		pc = *cfg.Policy
+	case cfg.PolicyFile != "":
+		loaded, err := router.LoadPolicyFile(os.ExpandEnv(cfg.PolicyFile))
+		if err != nil {
+			return nil, err
+		}
+		pc = loaded
+	default:
+		return nil, nil
+	}
+	p, err := router.NewPolicy(pc)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
 }
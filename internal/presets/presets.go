@@ -30,6 +30,14 @@ func NewRegistry() *Registry {
 					"GITHUB_PERSONAL_ACCESS_TOKEN": "${GITHUB_TOKEN}",
 				},
 			},
+			"gitlab": {
+				Command: "npx",
+				Args:    []string{"-y", "@modelcontextprotocol/server-gitlab"},
+				Env: map[string]string{
+					"GITLAB_PERSONAL_ACCESS_TOKEN": "${GITLAB_TOKEN}",
+					"GITLAB_API_URL":               "${GITLAB_API_URL}",
+				},
+			},
 		},
 	}
 }
@@ -131,5 +139,5 @@ func isZeroConfig(cfg proxy.Config) bool {
 
 // ListAvailable returns names of all available presets
 func ListAvailable() []string {
-	return []string{"github"}
+	return []string{"github", "gitlab"}
 }
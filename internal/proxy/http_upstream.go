@@ -0,0 +1,477 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+const sseEndpointTimeout = 30 * time.Second
+
+// HTTPUpstream talks to a remote MCP server over Streamable HTTP (POST, JSON or SSE replies)
+// or the legacy HTTP+SSE transport (GET event stream + POST to the announced endpoint).
+type HTTPUpstream struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	legacy  bool
+
+	nextID    atomic.Int64
+	sessionMu sync.Mutex
+	sessionID string
+	// initialized is guarded by sessionMu: an expired session needs a new handshake.
+	initialized bool
+	initMu      sync.Mutex
+
+	// Legacy SSE only: responses arrive on the GET stream.
+	endpoint     string
+	pending      map[int64]chan *mcp.Response
+	pendingMu    sync.Mutex
+	streamCancel context.CancelFunc
+	// streamDone is closed when the event stream ends; calls still waiting for a response fail.
+	streamDone chan struct{}
+}
+
+// errSessionExpired is returned when a Streamable HTTP server no longer knows our session (404).
+var errSessionExpired = errors.New("upstream session expired")
+
+// NewHTTPUpstream creates a remote upstream. legacySSE selects the HTTP+SSE transport.
+func NewHTTPUpstream(cfg Config, legacySSE bool) (*HTTPUpstream, error) {
+	raw := strings.TrimSpace(os.ExpandEnv(cfg.URL))
+	if raw == "" {
+		return nil, fmt.Errorf("http upstream requires url")
+	}
+	if u, err := url.Parse(raw); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid upstream url %q", raw)
+	}
+	headers := make(map[string]string, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	return &HTTPUpstream{
+		url:     raw,
+		headers: headers,
+		client:  &http.Client{},
+		legacy:  legacySSE,
+		pending: make(map[int64]chan *mcp.Response),
+	}, nil
+}
+
+// Start opens the event stream for legacy SSE upstreams; Streamable HTTP needs no connection up front.
+func (u *HTTPUpstream) Start(ctx context.Context) error {
+	if !u.legacy {
+		return nil
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, u.url, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+	u.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to open SSE stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("failed to open SSE stream: %s", resp.Status)
+	}
+	u.streamCancel = cancel
+	u.streamDone = make(chan struct{})
+
+	endpointCh := make(chan string, 1)
+	go func() {
+		defer close(u.streamDone)
+		defer resp.Body.Close()
+		_ = readSSE(resp.Body, func(event, data string) bool {
+			switch event {
+			case "endpoint":
+				select {
+				case endpointCh <- data:
+				default:
+				}
+			case "", "message":
+				var r mcp.Response
+				if json.Unmarshal([]byte(data), &r) == nil {
+					u.deliver(&r)
+				}
+			}
+			return true
+		})
+	}()
+
+	timer := time.NewTimer(sseEndpointTimeout)
+	defer timer.Stop()
+	select {
+	case ep := <-endpointCh:
+		base, _ := url.Parse(u.url)
+		ref, err := url.Parse(strings.TrimSpace(ep))
+		if err != nil {
+			u.Stop()
+			return fmt.Errorf("invalid SSE endpoint %q: %w", ep, err)
+		}
+		u.endpoint = base.ResolveReference(ref).String()
+		return nil
+	case <-timer.C:
+		u.Stop()
+		return fmt.Errorf("SSE upstream did not announce an endpoint within %s", sseEndpointTimeout)
+	case <-ctx.Done():
+		u.Stop()
+		return ctx.Err()
+	}
+}
+
+// Initialize performs the MCP handshake once.
+func (u *HTTPUpstream) Initialize(ctx context.Context) error {
+	u.initMu.Lock()
+	defer u.initMu.Unlock()
+
+	u.sessionMu.Lock()
+	done := u.initialized
+	u.sessionMu.Unlock()
+	if done {
+		return nil
+	}
+
+	resp, err := u.call(ctx, "initialize", initializeParams())
+	if err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("initialize error: %s", resp.Error.Message)
+	}
+	if err := u.notify(ctx, "notifications/initialized", nil); err != nil {
+		return fmt.Errorf("initialized notification failed: %w", err)
+	}
+
+	u.sessionMu.Lock()
+	u.initialized = true
+	u.sessionMu.Unlock()
+	return nil
+}
+
+// ListTools fetches all tools from the upstream.
+func (u *HTTPUpstream) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	if err := u.Initialize(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := u.call(ctx, "tools/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseListToolsResponse(resp)
+}
+
+// CallTool executes a tool on the upstream.
+func (u *HTTPUpstream) CallTool(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	if err := u.Initialize(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := u.call(ctx, "tools/call", mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		return nil, err
+	}
+	return parseCallToolResponse(resp)
+}
+
+// Stop closes the event stream (legacy SSE) or ends the session (Streamable HTTP, best effort).
+func (u *HTTPUpstream) Stop() error {
+	if u.streamCancel != nil {
+		u.streamCancel()
+		return nil
+	}
+	u.sessionMu.Lock()
+	sid := u.sessionID
+	u.sessionMu.Unlock()
+	if sid == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.url, nil)
+	if err != nil {
+		return err
+	}
+	u.setHeaders(req)
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (u *HTTPUpstream) call(ctx context.Context, method string, params any) (*mcp.Response, error) {
+	id := u.nextID.Add(1)
+	body, err := marshalMessage(id, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.legacy {
+		respCh := make(chan *mcp.Response, 1)
+		u.pendingMu.Lock()
+		u.pending[id] = respCh
+		u.pendingMu.Unlock()
+		defer func() {
+			u.pendingMu.Lock()
+			delete(u.pending, id)
+			u.pendingMu.Unlock()
+		}()
+
+		if _, err := u.post(ctx, u.endpoint, body, 0); err != nil {
+			return nil, err
+		}
+		select {
+		case resp := <-respCh:
+			return resp, nil
+		case <-u.streamDone:
+			return nil, fmt.Errorf("upstream SSE stream closed before the response to %s", method)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	resp, err := u.post(ctx, u.url, body, id)
+	if errors.Is(err, errSessionExpired) && method != "initialize" {
+		// The server dropped our session (e.g. after a restart): start a new one and retry once.
+		if err := u.Initialize(ctx); err != nil {
+			return nil, err
+		}
+		resp, err = u.post(ctx, u.url, body, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("upstream returned no response for %s", method)
+	}
+	return resp, nil
+}
+
+func (u *HTTPUpstream) notify(ctx context.Context, method string, params any) error {
+	body, err := marshalMessage(nil, method, params)
+	if err != nil {
+		return err
+	}
+	target := u.url
+	if u.legacy {
+		target = u.endpoint
+	}
+	_, err = u.post(ctx, target, body, 0)
+	return err
+}
+
+// post sends one JSON-RPC message. For Streamable HTTP it returns the response with wantID
+// (from a JSON body or an SSE stream); for notifications and legacy SSE it returns nil.
+func (u *HTTPUpstream) post(ctx context.Context, target string, body []byte, wantID int64) (*mcp.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	u.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("upstream request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if sid := req.Header.Get(mcp.SessionHeader); sid != "" && resp.StatusCode == http.StatusNotFound && !u.legacy {
+		u.expireSession(sid)
+		return nil, errSessionExpired
+	}
+	if resp.StatusCode >= 400 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("upstream HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	if sid := resp.Header.Get(mcp.SessionHeader); sid != "" {
+		u.sessionMu.Lock()
+		u.sessionID = sid
+		u.sessionMu.Unlock()
+	}
+	if wantID == 0 || resp.StatusCode == http.StatusAccepted {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, nil
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var found *mcp.Response
+		err := readSSE(resp.Body, func(event, data string) bool {
+			if event != "" && event != "message" {
+				return true
+			}
+			if r := matchResponse([]byte(data), wantID); r != nil {
+				found = r
+				return false
+			}
+			return true
+		})
+		if found != nil {
+			return found, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("upstream stream failed: %w", err)
+		}
+		return nil, fmt.Errorf("upstream stream ended without a response")
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if r := matchResponse(data, wantID); r != nil {
+		return r, nil
+	}
+	return nil, fmt.Errorf("upstream returned no matching response")
+}
+
+func (u *HTTPUpstream) setHeaders(req *http.Request) {
+	for k, v := range u.headers {
+		req.Header.Set(k, v)
+	}
+	u.sessionMu.Lock()
+	if u.sessionID != "" {
+		req.Header.Set(mcp.SessionHeader, u.sessionID)
+	}
+	u.sessionMu.Unlock()
+}
+
+// expireSession forgets sid (unless a concurrent call already replaced it) so the next call re-initializes.
+func (u *HTTPUpstream) expireSession(sid string) {
+	u.sessionMu.Lock()
+	defer u.sessionMu.Unlock()
+	if u.sessionID == sid {
+		u.sessionID = ""
+		u.initialized = false
+	}
+}
+
+func (u *HTTPUpstream) deliver(resp *mcp.Response) {
+	id, ok := responseID(resp.ID)
+	if !ok {
+		return
+	}
+	u.pendingMu.Lock()
+	ch, ok := u.pending[id]
+	u.pendingMu.Unlock()
+	if ok {
+		select {
+		case ch <- resp:
+		default:
+		}
+	}
+}
+
+func marshalMessage(id any, method string, params any) ([]byte, error) {
+	var paramsData json.RawMessage
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		paramsData = b
+	}
+	if id == nil {
+		return json.Marshal(mcp.Notification{JSONRPC: "2.0", Method: method, Params: paramsData})
+	}
+	return json.Marshal(mcp.Request{JSONRPC: "2.0", ID: id, Method: method, Params: paramsData})
+}
+
+// matchResponse finds the response with wantID in a single message or a batch.
+func matchResponse(data []byte, wantID int64) *mcp.Response {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	var batch []mcp.Response
+	if data[0] == '[' {
+		if json.Unmarshal(data, &batch) != nil {
+			return nil
+		}
+	} else {
+		var one mcp.Response
+		if json.Unmarshal(data, &one) != nil {
+			return nil
+		}
+		batch = []mcp.Response{one}
+	}
+	for i := range batch {
+		if id, ok := responseID(batch[i].ID); ok && id == wantID {
+			return &batch[i]
+		}
+	}
+	return nil
+}
+
+func responseID(v any) (int64, bool) {
+	switch id := v.(type) {
+	case float64:
+		return int64(id), true
+	case int64:
+		return id, true
+	case int:
+		return int64(id), true
+	default:
+		return 0, false
+	}
+}
+
+// readSSE parses a text/event-stream and calls fn per event until fn returns false or the stream ends.
+func readSSE(r io.Reader, fn func(event, data string) bool) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var event string
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if len(data) > 0 {
+				if !fn(event, strings.Join(data, "\n")) {
+					return nil
+				}
+			}
+			event, data = "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := sc.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	if len(data) > 0 {
+		fn(event, strings.Join(data, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+// fakeResult answers the handful of methods an upstream needs.
+func fakeResult(req *mcp.Request) json.RawMessage {
+	var result any
+	switch req.Method {
+	case "initialize":
+		result = map[string]any{"protocolVersion": "2024-11-05", "capabilities": map[string]any{}, "serverInfo": map[string]any{"name": "remote", "version": "0"}}
+	case "tools/list":
+		result = mcp.ListToolsResult{Tools: []mcp.Tool{{Name: "remote_echo", InputSchema: json.RawMessage(`{"type":"object"}`)}}}
+	case "tools/call":
+		var p mcp.CallToolParams
+		_ = json.Unmarshal(req.Params, &p)
+		result = mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: "called " + p.Name + " " + string(p.Arguments)}}}
+	default:
+		return nil
+	}
+	b, _ := json.Marshal(result)
+	return b
+}
+
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestHTTPUpstreamStreamableHTTP(t *testing.T) {
+	t.Setenv("REMOTE_MCP_TOKEN", "secret-token")
+
+	srvTransport := mcp.NewHTTPTransport("")
+	ts := httptest.NewServer(requireAuth(srvTransport))
+	defer ts.Close()
+	defer srvTransport.Close()
+	go func() {
+		for {
+			req, err := srvTransport.ReadMessage()
+			if err != nil {
+				return
+			}
+			if req.ID == nil {
+				continue
+			}
+			_ = srvTransport.WriteResponse(&mcp.Response{JSONRPC: "2.0", ID: req.ID, Result: fakeResult(req)})
+		}
+	}()
+
+	up, err := NewUpstream(Config{Type: "http", URL: ts.URL + "/mcp", Headers: map[string]string{"Authorization": "Bearer ${REMOTE_MCP_TOKEN}"}})
+	if err != nil {
+		t.Fatalf("NewUpstream: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := up.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer up.Stop()
+
+	tools, err := up.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "remote_echo" {
+		t.Fatalf("unexpected tools: %+v", tools)
+	}
+	res, err := up.CallTool(ctx, "remote_echo", json.RawMessage(`{"x":1}`))
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if got := res.Content[0].Text; got != `called remote_echo {"x":1}` {
+		t.Fatalf("unexpected result %q", got)
+	}
+}
+
+func TestHTTPUpstreamReportsHTTPErrors(t *testing.T) {
+	ts := httptest.NewServer(requireAuth(http.NotFoundHandler()))
+	defer ts.Close()
+
+	up, err := NewUpstream(Config{Type: "http", URL: ts.URL})
+	if err != nil {
+		t.Fatalf("NewUpstream: %v", err)
+	}
+	_, err = up.ListTools(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401 error, got %v", err)
+	}
+}
+
+// legacySSEServer implements the HTTP+SSE transport: GET opens the stream and announces
+// the POST endpoint; responses to POSTed requests are delivered on the stream.
+func legacySSEServer(t *testing.T) *httptest.Server {
+	events := make(chan []byte, 16)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		fmt.Fprintf(w, "event: endpoint\ndata: /messages?session=1\n\n")
+		flusher.Flush()
+		for {
+			select {
+			case data := <-events:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session") != "1" {
+			http.Error(w, "bad session", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req mcp.Request
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		if req.ID != nil {
+			b, _ := json.Marshal(mcp.Response{JSONRPC: "2.0", ID: req.ID, Result: fakeResult(&req)})
+			events <- b
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestHTTPUpstreamLegacySSE(t *testing.T) {
+	ts := legacySSEServer(t)
+	defer ts.Close()
+
+	m, err := NewMulti([]NamedConfig{{Name: "remote", Config: Config{Type: "sse", URL: ts.URL + "/sse"}}})
+	if err != nil {
+		t.Fatalf("NewMulti: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer m.Stop()
+
+	tools, err := m.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "remote.remote_echo" {
+		t.Fatalf("unexpected tools: %+v", tools)
+	}
+	res, err := m.CallTool(ctx, "remote.remote_echo", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if got := res.Content[0].Text; got != "called remote_echo {}" {
+		t.Fatalf("unexpected result %q", got)
+	}
+}
+
+func TestNewUpstreamValidatesConfig(t *testing.T) {
+	for _, cfg := range []Config{{Type: "http"}, {Type: "sse", URL: "not a url"}, {Type: "grpc", URL: "http://x"}, {}} {
+		if _, err := NewUpstream(cfg); err == nil {
+			t.Fatalf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestHTTPUpstreamReinitializesExpiredSession(t *testing.T) {
+	srvTransport := mcp.NewHTTPTransport("")
+	ts := httptest.NewServer(srvTransport)
+	defer ts.Close()
+	defer srvTransport.Close()
+	inits := 0
+	go func() {
+		for {
+			req, err := srvTransport.ReadMessage()
+			if err != nil {
+				return
+			}
+			if req.ID == nil {
+				continue
+			}
+			if req.Method == "initialize" {
+				inits++
+			}
+			_ = srvTransport.WriteResponse(&mcp.Response{JSONRPC: "2.0", ID: req.ID, Result: fakeResult(req)})
+		}
+	}()
+
+	up, err := NewHTTPUpstream(Config{URL: ts.URL}, false)
+	if err != nil {
+		t.Fatalf("NewHTTPUpstream: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := up.ListTools(ctx); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	old := up.sessionID
+
+	// The server forgets the session (as after a restart); the next call gets 404.
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	req.Header.Set(mcp.SessionHeader, old)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	resp.Body.Close()
+
+	res, err := up.CallTool(ctx, "remote_echo", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("CallTool after session expiry: %v", err)
+	}
+	if res.Content[0].Text != "called remote_echo {}" || up.sessionID == old || up.sessionID == "" || inits != 2 {
+		t.Fatalf("expected a new session and handshake, got session=%q inits=%d result=%+v", up.sessionID, inits, res)
+	}
+}
+
+func TestHTTPUpstreamLegacySSEFailsPendingCallsWhenStreamCloses(t *testing.T) {
+	drop := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: endpoint\ndata: /messages\n\n")
+		w.(http.Flusher).Flush()
+		<-drop
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		// Accept the request; the response would come on the stream, which is about to drop.
+		w.WriteHeader(http.StatusAccepted)
+		close(drop)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	up, err := NewHTTPUpstream(Config{URL: ts.URL + "/sse"}, true)
+	if err != nil {
+		t.Fatalf("NewHTTPUpstream: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := up.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer up.Stop()
+
+	start := time.Now()
+	_, err = up.ListTools(ctx)
+	if err == nil || !strings.Contains(err.Error(), "SSE stream closed") {
+		t.Fatalf("expected stream closed error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("pending call waited for its deadline instead of failing")
+	}
+}
@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+// NamespaceSep separates the upstream name from the tool name (e.g. "gitlab.list_merge_requests").
+const NamespaceSep = "."
+
+// NamedConfig is one upstream in a multi-upstream setup.
+// Tools of a named upstream are exposed as "<name>.<tool>"; an unnamed upstream keeps its tool names.
+type NamedConfig struct {
+	Name   string
+	Config Config
+}
+
+type namedProxy struct {
+	name  string
+	proxy Upstream
+}
+
+// Multi aggregates several upstream MCP servers behind one ListTools/CallTool API.
+type Multi struct {
+	upstreams []*namedProxy
+	byName    map[string]*namedProxy
+	// fallback receives tools without a known namespace (the unnamed upstream, if any).
+	fallback *namedProxy
+
+	onToolsChanged func(upstream string, tools []mcp.Tool)
+}
+
+// NewMulti creates proxies for every upstream. At most one upstream may be unnamed.
+func NewMulti(cfgs []NamedConfig) (*Multi, error) {
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("no upstreams configured")
+	}
+	m := &Multi{byName: make(map[string]*namedProxy)}
+	for i, c := range cfgs {
+		name := strings.TrimSpace(c.Name)
+		if strings.Contains(name, NamespaceSep) {
+			return nil, fmt.Errorf("upstream %d: name %q must not contain %q", i, name, NamespaceSep)
+		}
+		up, err := NewUpstream(c.Config)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %d: %w", i, err)
+		}
+		np := &namedProxy{name: name, proxy: up}
+		if name == "" {
+			if m.fallback != nil {
+				return nil, fmt.Errorf("upstream %d: only one upstream may omit name", i)
+			}
+			m.fallback = np
+		} else {
+			if _, dup := m.byName[name]; dup {
+				return nil, fmt.Errorf("upstream %d: duplicate name %q", i, name)
+			}
+			m.byName[name] = np
+		}
+		m.upstreams = append(m.upstreams, np)
+	}
+	return m, nil
+}
+
+// NewSingle wraps one unnamed upstream (tool names are not namespaced).
+func NewSingle(cfg Config) *Multi {
+	np := &namedProxy{proxy: NewSupervised(cfg)}
+	return &Multi{upstreams: []*namedProxy{np}, byName: map[string]*namedProxy{}, fallback: np}
+}
+
+// Names returns upstream names in config order ("" for the unnamed upstream).
+func (m *Multi) Names() []string {
+	out := make([]string, 0, len(m.upstreams))
+	for _, u := range m.upstreams {
+		out = append(out, u.name)
+	}
+	return out
+}
+
+// OnToolsChanged registers a callback for upstreams whose tool list changed after a restart.
+// Tools are already namespaced. Must be called before Start.
+func (m *Multi) OnToolsChanged(fn func(upstream string, tools []mcp.Tool)) {
+	m.onToolsChanged = fn
+}
+
+// Start starts all upstream processes. On failure, already started ones are stopped.
+func (m *Multi) Start(ctx context.Context) error {
+	for _, u := range m.upstreams {
+		u := u
+		if sup, ok := u.proxy.(*Supervised); ok {
+			sup.OnRestart(func(tools []mcp.Tool) {
+				if m.onToolsChanged != nil {
+					m.onToolsChanged(u.name, u.namespace(tools))
+				}
+			})
+		}
+	}
+	for i, u := range m.upstreams {
+		if err := u.proxy.Start(ctx); err != nil {
+			for _, started := range m.upstreams[:i] {
+				started.proxy.Stop()
+			}
+			return fmt.Errorf("%s: %w", u.label(), err)
+		}
+	}
+	return nil
+}
+
+// ListTools returns the merged tool catalog with namespaced names.
+func (m *Multi) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	var out []mcp.Tool
+	for _, u := range m.upstreams {
+		tools, err := m.ListUpstreamTools(ctx, u.name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, tools...)
+	}
+	return out, nil
+}
+
+// ListUpstreamTools returns the namespaced tools of one upstream ("" for the unnamed one).
+func (m *Multi) ListUpstreamTools(ctx context.Context, name string) ([]mcp.Tool, error) {
+	u := m.fallback
+	if name != "" {
+		u = m.byName[name]
+	}
+	if u == nil {
+		return nil, fmt.Errorf("unknown upstream %q", name)
+	}
+	tools, err := u.proxy.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", u.label(), err)
+	}
+	return u.namespace(tools), nil
+}
+
+// CallTool routes a (possibly namespaced) tool call to its upstream.
+func (m *Multi) CallTool(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	u, tool := m.route(name)
+	if u == nil {
+		return nil, fmt.Errorf("no upstream for tool %q", name)
+	}
+	return u.proxy.CallTool(ctx, tool, args)
+}
+
+// Stop stops all upstream processes and returns the first error.
+func (m *Multi) Stop() error {
+	var first error
+	for _, u := range m.upstreams {
+		if err := u.proxy.Stop(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (m *Multi) route(name string) (*namedProxy, string) {
+	if prefix, rest, ok := strings.Cut(name, NamespaceSep); ok {
+		if u, found := m.byName[prefix]; found {
+			return u, rest
+		}
+	}
+	return m.fallback, name
+}
+
+func (u *namedProxy) namespace(tools []mcp.Tool) []mcp.Tool {
+	if u.name == "" {
+		return tools
+	}
+	out := make([]mcp.Tool, len(tools))
+	for i, t := range tools {
+		t.Name = u.name + NamespaceSep + t.Name
+		out[i] = t
+	}
+	return out
+}
+
+func (u *namedProxy) label() string {
+	if u.name == "" {
+		return "upstream"
+	}
+	return fmt.Sprintf("upstream %q", u.name)
+}
@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+// The test binary doubles as a fake stdio MCP upstream when MCP_LENS_FAKE_UPSTREAM is set.
+func TestMain(m *testing.M) {
+	if name := os.Getenv("MCP_LENS_FAKE_UPSTREAM"); name != "" {
+		runFakeUpstream(name)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runFakeUpstream(label string) {
+	in := bufio.NewScanner(os.Stdin)
+	in.Buffer(make([]byte, 1024*1024), 1024*1024)
+	out := json.NewEncoder(os.Stdout)
+	for in.Scan() {
+		var req mcp.Request
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil || req.ID == nil {
+			continue
+		}
+		var result any
+		switch req.Method {
+		case "initialize":
+			result = map[string]any{"protocolVersion": "2024-11-05", "capabilities": map[string]any{}, "serverInfo": map[string]any{"name": label, "version": "0"}}
+		case "tools/list":
+			result = mcp.ListToolsResult{Tools: []mcp.Tool{{Name: "whoami", Description: "Returns the upstream label", InputSchema: json.RawMessage(`{"type":"object"}`)}}}
+		case "tools/call":
+			var p mcp.CallToolParams
+			_ = json.Unmarshal(req.Params, &p)
+			if p.Name == "crash" {
+				os.Exit(3)
+			}
+			result = mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: fmt.Sprintf("%s:%s", label, p.Name)}}}
+		default:
+			_ = out.Encode(mcp.NewErrorResponse(req.ID, mcp.MethodNotFound, req.Method))
+			continue
+		}
+		b, _ := json.Marshal(result)
+		_ = out.Encode(mcp.Response{JSONRPC: "2.0", ID: req.ID, Result: b})
+	}
+}
+
+func fakeUpstreamConfig(label string) Config {
+	return Config{Command: os.Args[0], Env: map[string]string{"MCP_LENS_FAKE_UPSTREAM": label}}
+}
+
+func TestMultiNamespacesAndRoutesTools(t *testing.T) {
+	m, err := NewMulti([]NamedConfig{
+		{Name: "gitlab", Config: fakeUpstreamConfig("gl")},
+		{Name: "fs", Config: fakeUpstreamConfig("fs")},
+	})
+	if err != nil {
+		t.Fatalf("NewMulti: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer m.Stop()
+
+	tools, err := m.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	names := map[string]bool{}
+	for _, tool := range tools {
+		names[tool.Name] = true
+	}
+	if !names["gitlab.whoami"] || !names["fs.whoami"] || len(names) != 2 {
+		t.Fatalf("unexpected tools: %v", names)
+	}
+
+	res, err := m.CallTool(ctx, "fs.whoami", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if got := res.Content[0].Text; got != "fs:whoami" {
+		t.Fatalf("expected call routed to fs, got %q", got)
+	}
+	if _, err := m.CallTool(ctx, "whoami", json.RawMessage(`{}`)); err == nil {
+		t.Fatalf("expected error for tool without namespace and no unnamed upstream")
+	}
+}
+
+func TestNewMultiRejectsInvalidNames(t *testing.T) {
+	cases := [][]NamedConfig{
+		{{Name: "a"}, {Name: "a"}},
+		{{}, {}},
+		{{Name: "a.b"}},
+		nil,
+	}
+	for i, c := range cases {
+		if _, err := NewMulti(c); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
 )
@@ -27,13 +28,24 @@ type Proxy struct {
 
 	initialized bool
 	initMu      sync.Mutex
+
+	// done is closed when the process exits or closes stdout; exitErr explains why.
+	done     chan struct{}
+	exitErr  error
+	exitOnce sync.Once
 }
 
 // Config holds upstream server configuration
 type Config struct {
+	// Type selects the transport: stdio (default, child process), http (Streamable HTTP) or sse (legacy HTTP+SSE).
+	Type    string            `yaml:"type,omitempty"`
 	Command string            `yaml:"command"`
 	Args    []string          `yaml:"args"`
 	Env     map[string]string `yaml:"env"`
+	// URL is the endpoint of a remote upstream (type http/sse).
+	URL string `yaml:"url,omitempty"`
+	// Headers are sent with every HTTP request; values expand env vars (e.g. "Bearer ${API_TOKEN}").
+	Headers map[string]string `yaml:"headers,omitempty"`
 }
 
 // New creates a new proxy to upstream MCP server
@@ -51,6 +63,7 @@ func New(cfg Config) *Proxy {
 	return &Proxy{
 		cmd:     cmd,
 		pending: make(map[int64]chan *mcp.Response),
+		done:    make(chan struct{}),
 	}
 }
 
@@ -78,15 +91,55 @@ func (p *Proxy) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start upstream: %w", err)
 	}
 
-	// Start response reader
-	go p.readResponses()
-
 	// Start stderr reader (for debugging)
-	go p.readStderr()
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		p.readStderr()
+	}()
+
+	// Start response reader; EOF on stdout means the upstream is gone.
+	go func() {
+		p.readResponses()
+		// Give stderr a moment to drain so the crash reason is logged, then reap the process.
+		select {
+		case <-stderrDone:
+		case <-time.After(time.Second):
+		}
+		_ = p.cmd.Process.Kill()
+		err := p.cmd.Wait()
+		if err == nil {
+			err = fmt.Errorf("upstream closed stdout")
+		}
+		p.exit(fmt.Errorf("upstream process exited: %w", err))
+	}()
 
 	return nil
 }
 
+// Done is closed when the upstream process exits (or closes stdout).
+func (p *Proxy) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns why the upstream exited; nil while it is running.
+func (p *Proxy) Err() error {
+	select {
+	case <-p.done:
+		return p.exitErr
+	default:
+		return nil
+	}
+}
+
+// exit records the exit reason and fails all pending calls.
+func (p *Proxy) exit(err error) {
+	p.exitOnce.Do(func() {
+		p.exitErr = err
+		close(p.done)
+	})
+}
+
 // Initialize sends initialize request to upstream
 func (p *Proxy) Initialize(ctx context.Context) error {
 	p.initMu.Lock()
@@ -96,18 +149,7 @@ func (p *Proxy) Initialize(ctx context.Context) error {
 		return nil
 	}
 
-	params := mcp.InitializeParams{
-		ProtocolVersion: "2024-11-05",
-		Capabilities: mcp.ClientCapability{
-			Roots: &mcp.RootsCapability{ListChanged: true},
-		},
-		ClientInfo: mcp.ClientInfo{
-			Name:    "mcp-proxy",
-			Version: "1.0.0",
-		},
-	}
-
-	resp, err := p.call(ctx, "initialize", params)
+	resp, err := p.call(ctx, "initialize", initializeParams())
 	if err != nil {
 		return fmt.Errorf("initialize failed: %w", err)
 	}
@@ -136,16 +178,7 @@ func (p *Proxy) ListTools(ctx context.Context) ([]mcp.Tool, error) {
 		return nil, err
 	}
 
-	if resp.Error != nil {
-		return nil, fmt.Errorf("list tools error: %s", resp.Error.Message)
-	}
-
-	var result mcp.ListToolsResult
-	if err := json.Unmarshal(resp.Result, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse tools: %w", err)
-	}
-
-	return result.Tools, nil
+	return parseListToolsResponse(resp)
 }
 
 // CallTool executes a tool on the upstream server
@@ -164,21 +197,7 @@ func (p *Proxy) CallTool(ctx context.Context, name string, args json.RawMessage)
 		return nil, err
 	}
 
-	if resp.Error != nil {
-		return &mcp.CallToolResult{
-			Content: []mcp.ContentBlock{
-				{Type: "text", Text: fmt.Sprintf("Error: %s", resp.Error.Message)},
-			},
-			IsError: true,
-		}, nil
-	}
-
-	var result mcp.CallToolResult
-	if err := json.Unmarshal(resp.Result, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse result: %w", err)
-	}
-
-	return &result, nil
+	return parseCallToolResponse(resp)
 }
 
 // Stop stops the upstream process
@@ -211,6 +230,10 @@ func (p *Proxy) call(ctx context.Context, method string, params any) (*mcp.Respo
 		Params:  paramsData,
 	}
 
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+
 	// Create response channel
 	respCh := make(chan *mcp.Response, 1)
 	p.pendingMu.Lock()
@@ -237,6 +260,8 @@ func (p *Proxy) call(ctx context.Context, method string, params any) (*mcp.Respo
 	select {
 	case resp := <-respCh:
 		return resp, nil
+	case <-p.done:
+		return nil, p.exitErr
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
@@ -281,15 +306,8 @@ func (p *Proxy) readResponses() {
 
 		// Match response to pending request
 		if resp.ID != nil {
-			var id int64
-			switch v := resp.ID.(type) {
-			case float64:
-				id = int64(v)
-			case int64:
-				id = v
-			case int:
-				id = int64(v)
-			default:
+			id, ok := responseID(resp.ID)
+			if !ok {
 				continue
 			}
 
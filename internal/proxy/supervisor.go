@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+const (
+	restartMinBackoff  = 500 * time.Millisecond
+	restartMaxBackoff  = 30 * time.Second
+	restartInitTimeout = 30 * time.Second
+)
+
+// Supervised runs a stdio upstream and restarts it with exponential backoff when it exits.
+// Calls made while the process is down fail immediately with the exit reason.
+type Supervised struct {
+	cfg Config
+
+	mu      sync.RWMutex
+	cur     *Proxy
+	stopped bool
+	stopCh  chan struct{}
+
+	onRestart func(tools []mcp.Tool)
+
+	// Backoff bounds (overridable in tests).
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// NewSupervised creates a supervised stdio upstream.
+func NewSupervised(cfg Config) *Supervised {
+	return &Supervised{
+		cfg:        cfg,
+		cur:        New(cfg),
+		stopCh:     make(chan struct{}),
+		minBackoff: restartMinBackoff,
+		maxBackoff: restartMaxBackoff,
+	}
+}
+
+// OnRestart registers a callback invoked with the fresh tool list after each successful restart.
+// Must be called before Start.
+func (s *Supervised) OnRestart(fn func(tools []mcp.Tool)) {
+	s.onRestart = fn
+}
+
+// Start starts the process and the supervisor loop.
+func (s *Supervised) Start(ctx context.Context) error {
+	p := s.current()
+	if err := p.Start(ctx); err != nil {
+		return err
+	}
+	go s.supervise(p)
+	return nil
+}
+
+// ListTools fetches all tools from the current process.
+func (s *Supervised) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	return s.current().ListTools(ctx)
+}
+
+// CallTool executes a tool on the current process.
+func (s *Supervised) CallTool(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	return s.current().CallTool(ctx, name, args)
+}
+
+// Stop stops the supervisor and the process.
+func (s *Supervised) Stop() error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	close(s.stopCh)
+	p := s.cur
+	s.mu.Unlock()
+	return p.Stop()
+}
+
+func (s *Supervised) current() *Proxy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cur
+}
+
+func (s *Supervised) supervise(p *Proxy) {
+	for {
+		select {
+		case <-p.Done():
+		case <-s.stopCh:
+			return
+		}
+		if s.isStopped() {
+			return
+		}
+		logf("%v; restarting", p.Err())
+
+		next, tools, ok := s.restart()
+		if !ok {
+			return
+		}
+		p = next
+		if s.onRestart != nil {
+			s.onRestart(tools)
+		}
+	}
+}
+
+// restart retries until a new process is up and answers initialize + tools/list.
+func (s *Supervised) restart() (*Proxy, []mcp.Tool, bool) {
+	backoff := s.minBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-s.stopCh:
+			return nil, nil, false
+		}
+
+		p := New(s.cfg)
+		tools, err := s.startAndList(p)
+		if err == nil {
+			s.mu.Lock()
+			if s.stopped {
+				s.mu.Unlock()
+				p.Stop()
+				return nil, nil, false
+			}
+			s.cur = p
+			s.mu.Unlock()
+			logf("upstream restarted after %d attempt(s) (%d tools)", attempt, len(tools))
+			return p, tools, true
+		}
+		p.Stop()
+		logf("upstream restart attempt %d failed: %v", attempt, err)
+
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+func (s *Supervised) startAndList(p *Proxy) ([]mcp.Tool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), restartInitTimeout)
+	defer cancel()
+	if err := p.Start(ctx); err != nil {
+		return nil, err
+	}
+	if err := p.Initialize(ctx); err != nil {
+		return nil, err
+	}
+	return p.ListTools(ctx)
+}
+
+func (s *Supervised) isStopped() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stopped
+}
+
+func logf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "[mcp-proxy] "+format+"\n", args...)
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+func TestSupervisedRestartsCrashedUpstream(t *testing.T) {
+	s := NewSupervised(fakeUpstreamConfig("sup"))
+	s.minBackoff = 10 * time.Millisecond
+	restarted := make(chan []mcp.Tool, 1)
+	s.OnRestart(func(tools []mcp.Tool) { restarted <- tools })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.Stop()
+
+	if _, err := s.CallTool(ctx, "whoami", json.RawMessage(`{}`)); err != nil {
+		t.Fatalf("CallTool before crash: %v", err)
+	}
+
+	// The pending call must fail with the exit reason instead of hanging.
+	_, err := s.CallTool(ctx, "crash", json.RawMessage(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "upstream process exited") {
+		t.Fatalf("expected exit error, got %v", err)
+	}
+
+	select {
+	case tools := <-restarted:
+		if len(tools) != 1 || tools[0].Name != "whoami" {
+			t.Fatalf("unexpected tools after restart: %+v", tools)
+		}
+	case <-ctx.Done():
+		t.Fatalf("upstream was not restarted")
+	}
+
+	res, err := s.CallTool(ctx, "whoami", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("CallTool after restart: %v", err)
+	}
+	if got := res.Content[0].Text; got != "sup:whoami" {
+		t.Fatalf("unexpected result %q", got)
+	}
+}
+
+func TestSupervisedStopDoesNotRestart(t *testing.T) {
+	s := NewSupervised(fakeUpstreamConfig("sup"))
+	s.minBackoff = 10 * time.Millisecond
+	restarted := make(chan struct{}, 1)
+	s.OnRestart(func([]mcp.Tool) { restarted <- struct{}{} })
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	p := s.current()
+	s.Stop()
+
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("process did not exit after Stop")
+	}
+	select {
+	case <-restarted:
+		t.Fatalf("stopped upstream must not be restarted")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
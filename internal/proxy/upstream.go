@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+// Upstream is a connection to one upstream MCP server, local (stdio child) or remote (HTTP).
+type Upstream interface {
+	Start(ctx context.Context) error
+	ListTools(ctx context.Context) ([]mcp.Tool, error)
+	CallTool(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error)
+	Stop() error
+}
+
+// Upstream transport types (Config.Type).
+const (
+	TypeStdio = "stdio"
+	TypeHTTP  = "http"
+	TypeSSE   = "sse"
+)
+
+// NewUpstream creates an upstream for cfg.Type (default: stdio).
+func NewUpstream(cfg Config) (Upstream, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case "", TypeStdio:
+		if strings.TrimSpace(cfg.Command) == "" {
+			return nil, fmt.Errorf("stdio upstream requires command")
+		}
+		return NewSupervised(cfg), nil
+	case TypeHTTP:
+		return NewHTTPUpstream(cfg, false)
+	case TypeSSE:
+		return NewHTTPUpstream(cfg, true)
+	default:
+		return nil, fmt.Errorf("unknown upstream type %q (expected stdio, http or sse)", cfg.Type)
+	}
+}
+
+func initializeParams() mcp.InitializeParams {
+	return mcp.InitializeParams{
+		ProtocolVersion: "2024-11-05",
+		Capabilities: mcp.ClientCapability{
+			Roots: &mcp.RootsCapability{ListChanged: true},
+		},
+		ClientInfo: mcp.ClientInfo{
+			Name:    "mcp-proxy",
+			Version: "1.0.0",
+		},
+	}
+}
+
+func parseListToolsResponse(resp *mcp.Response) ([]mcp.Tool, error) {
+	if resp.Error != nil {
+		return nil, fmt.Errorf("list tools error: %s", resp.Error.Message)
+	}
+	var result mcp.ListToolsResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse tools: %w", err)
+	}
+	return result.Tools, nil
+}
+
+func parseCallToolResponse(resp *mcp.Response) (*mcp.CallToolResult, error) {
+	if resp.Error != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.ContentBlock{
+				{Type: "text", Text: fmt.Sprintf("Error: %s", resp.Error.Message)},
+			},
+			IsError: true,
+		}, nil
+	}
+	var result mcp.CallToolResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse result: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+// BM25 parameters (the usual defaults).
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// nameBoost repeats name tokens so a term in the tool name outweighs one in its description.
+	nameBoost = 3
+)
+
+// ToolScore is a tool's BM25 relevance for a query.
+type ToolScore struct {
+	Name  string
+	Score float64
+}
+
+// RankTools scores tools against a query with BM25 over their names, descriptions and
+// input schema property names. The query is expanded like in Search (e.g. "review" adds "diff").
+// All tools are returned, best first; ties keep the input order.
+func RankTools(query string, tools []mcp.Tool) []ToolScore {
+	queryTerms := tokenize(strings.Join(expandQuery(strings.ToLower(query)), " "))
+
+	docs := make([][]string, len(tools))
+	df := make(map[string]int)
+	totalLen := 0
+	for i, t := range tools {
+		docs[i] = toolTokens(t)
+		totalLen += len(docs[i])
+		seen := make(map[string]struct{})
+		for _, term := range docs[i] {
+			if _, ok := seen[term]; ok {
+				continue
+			}
+			seen[term] = struct{}{}
+			df[term]++
+		}
+	}
+
+	n := float64(len(tools))
+	avgLen := 1.0
+	if len(tools) > 0 && totalLen > 0 {
+		avgLen = float64(totalLen) / n
+	}
+
+	out := make([]ToolScore, len(tools))
+	for i, t := range tools {
+		tf := make(map[string]int, len(docs[i]))
+		for _, term := range docs[i] {
+			tf[term]++
+		}
+		score := 0.0
+		for _, term := range queryTerms {
+			f := float64(tf[term])
+			if f == 0 {
+				continue
+			}
+			idf := math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(len(docs[i]))/avgLen)
+			score += idf * f * (bm25K1 + 1) / (f + norm)
+		}
+		out[i] = ToolScore{Name: t.Name, Score: score}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// toolTokens returns the indexed terms of a tool: boosted name, description and schema property names.
+func toolTokens(t mcp.Tool) []string {
+	var terms []string
+	name := tokenize(t.Name)
+	for i := 0; i < nameBoost; i++ {
+		terms = append(terms, name...)
+	}
+	terms = append(terms, tokenize(t.Description)...)
+	for _, prop := range schemaPropertyNames(t.InputSchema) {
+		terms = append(terms, tokenize(prop)...)
+	}
+	return terms
+}
+
+// schemaPropertyNames returns the top-level property names of a JSON schema.
+func schemaPropertyNames(schema json.RawMessage) []string {
+	if len(schema) == 0 {
+		return nil
+	}
+	var s struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tokenize lowercases text and splits it on anything but letters and digits
+// (so "gitlab.list_merge_requests" yields gitlab, list, merge, request).
+// Trailing plural "s" is dropped so "issues" matches "issue".
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		if len(f) < 2 {
+			continue
+		}
+		if len(f) > 3 && strings.HasSuffix(f, "s") && !strings.HasSuffix(f, "ss") {
+			f = f[:len(f)-1]
+		}
+		out = append(out, f)
+	}
+	return out
+}
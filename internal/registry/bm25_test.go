@@ -0,0 +1,34 @@
+package registry
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+func TestRankToolsBM25(t *testing.T) {
+	tools := []mcp.Tool{
+		{Name: "list_issues", Description: "List issues in a repository."},
+		{Name: "gitlab.list_merge_requests", Description: "List merge requests of a project."},
+		{Name: "get_file_contents", Description: "Get a file.", InputSchema: json.RawMessage(`{"type":"object","properties":{"pipeline_id":{"type":"integer"}}}`)},
+		{Name: "search_code", Description: "Search code across repositories."},
+	}
+
+	ranked := RankTools("open merge requests", tools)
+	if ranked[0].Name != "gitlab.list_merge_requests" || ranked[0].Score <= 0 {
+		t.Fatalf("expected merge request tool first, got %+v", ranked)
+	}
+	if len(ranked) != len(tools) {
+		t.Fatalf("expected every tool ranked, got %d", len(ranked))
+	}
+
+	// Schema property names are indexed.
+	if got := RankTools("pipeline", tools)[0]; got.Name != "get_file_contents" {
+		t.Fatalf("expected schema property match first, got %+v", got)
+	}
+	// Unmatched queries keep the input order.
+	if got := RankTools("zzz", tools); got[0].Name != "list_issues" || got[0].Score != 0 {
+		t.Fatalf("expected stable order for no matches, got %+v", got)
+	}
+}
@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"sort"
 	"strings"
 	"sync"
 
@@ -22,6 +23,8 @@ type Registry struct {
 	categories []Category                // Tool categories for search
 	active     map[string]struct{}       // Currently activated tools
 	summaries  map[string]mcp.ToolSummary // Tool summaries for search results
+	origin     map[string]string          // Tool name -> upstream name, for tools loaded via SetUpstreamTools
+	views      map[string]map[string][]string // Tool name -> view presets from config (override mcp.Tool.Views)
 	mu         sync.RWMutex
 }
 
@@ -31,6 +34,8 @@ func NewRegistry() *Registry {
 		tools:     make(map[string]mcp.Tool),
 		active:    make(map[string]struct{}),
 		summaries: make(map[string]mcp.ToolSummary),
+		origin:    make(map[string]string),
+		views:     make(map[string]map[string][]string),
 		categories: defaultCategories(),
 	}
 }
@@ -50,6 +55,34 @@ func (r *Registry) LoadTools(tools []mcp.Tool) {
 	}
 }
 
+// SetUpstreamTools replaces the tools of one upstream ("" for the unnamed upstream):
+// tools it no longer reports are removed, the rest are (re)loaded.
+func (r *Registry) SetUpstreamTools(upstream string, tools []mcp.Tool) {
+	r.mu.Lock()
+	keep := make(map[string]struct{}, len(tools))
+	for _, t := range tools {
+		keep[t.Name] = struct{}{}
+	}
+	for name, from := range r.origin {
+		if from != upstream {
+			continue
+		}
+		if _, ok := keep[name]; ok {
+			continue
+		}
+		delete(r.tools, name)
+		delete(r.summaries, name)
+		delete(r.active, name)
+		delete(r.origin, name)
+	}
+	for _, t := range tools {
+		r.origin[t.Name] = upstream
+	}
+	r.mu.Unlock()
+
+	r.LoadTools(tools)
+}
+
 // Search finds tools matching the query
 func (r *Registry) Search(query string, category string, limit int) []mcp.ToolSummary {
 	r.mu.RLock()
@@ -67,12 +100,23 @@ func (r *Registry) Search(query string, category string, limit int) []mcp.ToolSu
 	var toolNames []string
 	if category != "" {
 		// Filter by category first
+		found := false
 		for _, cat := range r.categories {
 			if strings.EqualFold(cat.Name, category) {
 				toolNames = cat.Tools
+				found = true
 				break
 			}
 		}
+		if !found {
+			// Namespaced upstreams act as categories ("gitlab" -> "gitlab.*").
+			prefix := strings.ToLower(category) + "."
+			for name := range r.tools {
+				if strings.HasPrefix(strings.ToLower(name), prefix) {
+					toolNames = append(toolNames, name)
+				}
+			}
+		}
 	} else {
 		for name := range r.tools {
 			toolNames = append(toolNames, name)
@@ -165,6 +209,48 @@ func (r *Registry) GetTool(name string) (mcp.Tool, bool) {
 	return tool, ok
 }
 
+// ListTools returns all known tools sorted by name.
+func (r *Registry) ListTools() []mcp.Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]mcp.Tool, 0, len(r.tools))
+	for _, t := range r.tools {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// SetViews replaces the configured view presets (tool name -> view -> paths); per view they
+// override the presets the tool declares, also for tools loaded later.
+func (r *Registry) SetViews(views map[string]map[string][]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.views = make(map[string]map[string][]string, len(views))
+	for name, v := range views {
+		r.views[name] = v
+	}
+}
+
+// Views returns the view presets of a tool: declared by the tool, overridden by config.
+func (r *Registry) Views(name string) map[string][]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	declared := r.tools[name].Views
+	configured := r.views[name]
+	if len(configured) == 0 {
+		return declared
+	}
+	out := make(map[string][]string, len(declared)+len(configured))
+	for view, paths := range declared {
+		out[view] = paths
+	}
+	for view, paths := range configured {
+		out[view] = paths
+	}
+	return out
+}
+
 // Activate marks a tool as active for this session
 func (r *Registry) Activate(name string) bool {
 	r.mu.Lock()
@@ -211,6 +297,10 @@ func (r *Registry) findCategory(toolName string) string {
 			}
 		}
 	}
+	// Namespaced upstream tools ("gitlab.list_merge_requests") are grouped by upstream name.
+	if prefix, _, ok := strings.Cut(toolName, "."); ok && prefix != "" {
+		return prefix
+	}
 	return "other"
 }
 
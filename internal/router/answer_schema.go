@@ -0,0 +1,82 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// structuredAnswerAttempts bounds the summarizer calls for one structured answer
+// (invalid answers are sent back with the validation error).
+const structuredAnswerAttempts = 3
+
+// CompileAnswerSchema checks that schema is a valid JSON Schema for a structured answer.
+func CompileAnswerSchema(schema json.RawMessage) error {
+	var v any
+	if err := json.Unmarshal(schema, &v); err != nil {
+		return fmt.Errorf("answer_schema must be a JSON Schema object: %w", err)
+	}
+	if _, ok := v.(map[string]any); !ok {
+		return errors.New("answer_schema must be a JSON Schema object")
+	}
+	if _, err := compileSchema("answer_schema", schema); err != nil {
+		return fmt.Errorf("invalid answer_schema: %w", err)
+	}
+	return nil
+}
+
+// SummarizeStructured asks the summarizer for a final answer as JSON valid against schema.
+// Invalid answers are retried with the validation error; the last error is returned when all attempts fail.
+func SummarizeStructured(ctx context.Context, cl LLMClient, userInput string, res RouterResult, schema json.RawMessage) (json.RawMessage, error) {
+	s, err := compileSchema("answer_schema", schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid answer_schema: %w", err)
+	}
+	system := BuildStructuredSummarizeSystemPrompt()
+	user, err := BuildStructuredSummarizeUserPrompt(userInput, res, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := user
+	for attempt := 1; ; attempt++ {
+		raw, finish, err := cl.ChatCompletionTextWithFinishReason(ctx, system, prompt)
+		if err != nil {
+			return nil, err
+		}
+		answer := extractJSON(raw)
+		verr := validateAnswer(s, answer)
+		if verr == nil {
+			return answer, nil
+		}
+		if finish == "length" {
+			verr = fmt.Errorf("answer truncated (finish_reason=length): %w", verr)
+		}
+		if attempt >= structuredAnswerAttempts || ctx.Err() != nil {
+			return nil, fmt.Errorf("structured answer invalid after %d attempt(s): %w", attempt, verr)
+		}
+		prompt = fmt.Sprintf("%s\n\nYour previous answer was rejected: %v\nPrevious answer:\n%s\n\nReturn a corrected JSON value only.", user, verr, string(answer))
+	}
+}
+
+func validateAnswer(s *jsonschema.Schema, answer []byte) error {
+	var v any
+	if err := json.Unmarshal(answer, &v); err != nil {
+		return fmt.Errorf("answer is not valid JSON: %w", err)
+	}
+	if err := s.Validate(v); err != nil {
+		if ve, ok := err.(*jsonschema.ValidationError); ok {
+			leaf := firstLeafValidationError(ve)
+			loc := leaf.InstanceLocation
+			if loc == "" {
+				loc = "/"
+			}
+			return fmt.Errorf("answer does not match answer_schema at %s: %s", loc, leaf.Message)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	anthropicVersion = "2023-06-01"
+	// The Messages API requires max_tokens.
+	anthropicDefaultMaxTokens = 4096
+)
+
+// AnthropicClient talks to the Anthropic Messages API.
+type AnthropicClient struct {
+	baseURL          string
+	apiKey           string
+	model            string
+	summaryModel     string
+	maxTokensPlan    int
+	maxTokensSummary int
+	price            TokenPrice
+	c                *http.Client
+}
+
+func newAnthropicClient(cfg LLMConfig) (*AnthropicClient, error) {
+	apiKey := cfg.apiKey("ANTHROPIC_API_KEY")
+	if apiKey == "" || cfg.Model == "" {
+		return nil, errors.New("missing ANTHROPIC_API_KEY or MCP_LENS_ROUTER_MODEL")
+	}
+	baseURL := cfg.baseURL(ProviderAnthropic)
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	return &AnthropicClient{
+		baseURL:          baseURL,
+		apiKey:           apiKey,
+		model:            cfg.Model,
+		summaryModel:     cfg.SummaryModel,
+		maxTokensPlan:    cfg.MaxTokensPlan,
+		maxTokensSummary: cfg.MaxTokensSummary,
+		price:            cfg.price(),
+		c:                cfg.httpClient(),
+	}, nil
+}
+
+func (cl *AnthropicClient) messages(ctx context.Context, system string, user string, kind string) (string, string, error) {
+	maxTokens := cl.maxTokensPlan
+	if kind == "summary" {
+		maxTokens = cl.maxTokensSummary
+	}
+	if maxTokens <= 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+	model := modelFor(kind, cl.model, cl.summaryModel)
+	body := map[string]any{
+		"model":       model,
+		"system":      system,
+		"messages":    []map[string]string{{"role": "user", "content": user}},
+		"max_tokens":  maxTokens,
+		"temperature": 0,
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", "", err
+	}
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(cl.baseURL, "/")+"/v1/messages", bytes.NewReader(b))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("x-api-key", cl.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cl.c.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", "", fmt.Errorf("anthropic error (%d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var parsed struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Model      string `json:"model"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", "", err
+	}
+	if parsed.Model != "" {
+		model = parsed.Model
+	}
+	reportUsage(ctx, LLMUsage{
+		Kind:             kind,
+		Provider:         ProviderAnthropic,
+		Model:            model,
+		PromptTokens:     parsed.Usage.InputTokens,
+		CompletionTokens: parsed.Usage.OutputTokens,
+	}, cl.price, start)
+	var sb strings.Builder
+	for _, c := range parsed.Content {
+		if c.Type == "text" {
+			sb.WriteString(c.Text)
+		}
+	}
+	content := strings.TrimSpace(sb.String())
+	if content == "" {
+		return "", "", fmt.Errorf("anthropic: %w", errEmptyContent)
+	}
+	finish := parsed.StopReason
+	if finish == "max_tokens" {
+		finish = "length"
+	}
+	return content, finish, nil
+}
+
+func (cl *AnthropicClient) ChatCompletionJSONWithFinishReason(ctx context.Context, system string, user string) ([]byte, string, error) {
+	content, finish, err := cl.messages(ctx, system, user, "plan")
+	if err != nil {
+		return nil, finish, err
+	}
+	return extractJSON(content), finish, nil
+}
+
+func (cl *AnthropicClient) ChatCompletionTextWithFinishReason(ctx context.Context, system string, user string) (string, string, error) {
+	return cl.messages(ctx, system, user, "summary")
+}
@@ -0,0 +1,168 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ContextExtractorConfig declares a regex extractor in config: named captures become context keys,
+// and Args fills tool arguments from context like the built-in extractors do.
+type ContextExtractorConfig struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// Context maps capture names to context keys.
+	Context map[string]string `yaml:"context" json:"context"`
+	// Integers lists captures stored as integers (e.g. PR numbers).
+	Integers []string         `yaml:"integers,omitempty" json:"integers,omitempty"`
+	Args     []ContextArgRule `yaml:"args,omitempty" json:"args,omitempty"`
+}
+
+// ContextArgRule fills args of matching steps from context keys; explicit (non-empty) args are kept.
+type ContextArgRule struct {
+	// Tools are tool names or glob patterns (e.g. "get_pull_request_*", "ghe.*").
+	Tools []string `yaml:"tools" json:"tools"`
+	// Set maps arg names to context keys.
+	Set map[string]string `yaml:"set" json:"set"`
+}
+
+// RegexContextExtractor is a ContextExtractor built from ContextExtractorConfig.
+type RegexContextExtractor struct {
+	cfg      ContextExtractorConfig
+	re       *regexp.Regexp
+	integers map[string]bool
+}
+
+// NewRegexContextExtractor validates cfg and compiles its pattern.
+func NewRegexContextExtractor(cfg ContextExtractorConfig) (*RegexContextExtractor, error) {
+	if strings.TrimSpace(cfg.Name) == "" {
+		return nil, errors.New("context extractor: name is required")
+	}
+	re, err := regexp.Compile(cfg.Pattern)
+	if err != nil || cfg.Pattern == "" {
+		if err == nil {
+			err = errors.New("pattern is required")
+		}
+		return nil, fmt.Errorf("context extractor %s: %w", cfg.Name, err)
+	}
+	if len(cfg.Context) == 0 {
+		return nil, fmt.Errorf("context extractor %s: context must map at least one capture", cfg.Name)
+	}
+	captures := map[string]bool{}
+	for _, n := range re.SubexpNames() {
+		if n != "" {
+			captures[n] = true
+		}
+	}
+	for c, key := range cfg.Context {
+		if !captures[c] {
+			return nil, fmt.Errorf("context extractor %s: pattern has no capture %q", cfg.Name, c)
+		}
+		if strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("context extractor %s: empty context key for capture %q", cfg.Name, c)
+		}
+	}
+	integers := map[string]bool{}
+	for _, c := range cfg.Integers {
+		if _, ok := cfg.Context[c]; !ok {
+			return nil, fmt.Errorf("context extractor %s: integer capture %q is not mapped in context", cfg.Name, c)
+		}
+		integers[c] = true
+	}
+	for i, r := range cfg.Args {
+		if len(r.Tools) == 0 || len(r.Set) == 0 {
+			return nil, fmt.Errorf("context extractor %s: args[%d] needs tools and set", cfg.Name, i)
+		}
+		for _, t := range r.Tools {
+			if _, err := path.Match(t, ""); err != nil || strings.TrimSpace(t) == "" {
+				return nil, fmt.Errorf("context extractor %s: args[%d]: invalid tool pattern %q", cfg.Name, i, t)
+			}
+		}
+		for arg, key := range r.Set {
+			if strings.TrimSpace(arg) == "" || strings.TrimSpace(key) == "" {
+				return nil, fmt.Errorf("context extractor %s: args[%d]: empty arg or context key", cfg.Name, i)
+			}
+		}
+	}
+	return &RegexContextExtractor{cfg: cfg, re: re, integers: integers}, nil
+}
+
+// NewRegexContextExtractors builds the configured extractors; names must be unique.
+func NewRegexContextExtractors(cfgs []ContextExtractorConfig) ([]*RegexContextExtractor, error) {
+	out := make([]*RegexContextExtractor, 0, len(cfgs))
+	seen := map[string]bool{}
+	for _, c := range cfgs {
+		ex, err := NewRegexContextExtractor(c)
+		if err != nil {
+			return nil, err
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate context extractor %q", c.Name)
+		}
+		seen[c.Name] = true
+		out = append(out, ex)
+	}
+	return out, nil
+}
+
+func (e *RegexContextExtractor) Name() string { return e.cfg.Name }
+
+// TryExtract returns the captures of the first match whose integer captures parse.
+func (e *RegexContextExtractor) TryExtract(input string) (map[string]any, bool) {
+	names := e.re.SubexpNames()
+	for _, m := range e.re.FindAllStringSubmatch(input, -1) {
+		out := map[string]any{}
+		ok := true
+		for i, c := range names {
+			key, mapped := e.cfg.Context[c]
+			if c == "" || !mapped || m[i] == "" {
+				continue
+			}
+			if e.integers[c] {
+				n, err := strconv.Atoi(m[i])
+				if err != nil {
+					ok = false
+					break
+				}
+				out[key] = n
+				continue
+			}
+			out[key] = m[i]
+		}
+		if ok && len(out) > 0 {
+			return out, true
+		}
+	}
+	return nil, false
+}
+
+// ArgsFor returns the arg -> context key mapping for tool; later rules do not override earlier ones.
+func (e *RegexContextExtractor) ArgsFor(tool string) map[string]string {
+	var out map[string]string
+	for _, r := range e.cfg.Args {
+		if !matchesAnyTool(r.Tools, tool) {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		for a, key := range r.Set {
+			if _, exists := out[a]; !exists {
+				out[a] = key
+			}
+		}
+	}
+	return out
+}
+
+func matchesAnyTool(patterns []string, tool string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, tool); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -21,9 +21,11 @@ func DefaultContextExtractors() []ContextExtractor {
 	}
 }
 
-func ExtractStructuredContext(input string) map[string]any {
+// ExtractStructuredContext runs the built-in extractors, then extra (e.g. from config);
+// the first value found for a key wins.
+func ExtractStructuredContext(input string, extra ...ContextExtractor) map[string]any {
 	out := map[string]any{}
-	for _, ex := range DefaultContextExtractors() {
+	for _, ex := range append(DefaultContextExtractors(), extra...) {
 		m, ok := ex.TryExtract(input)
 		if !ok || len(m) == 0 {
 			continue
@@ -15,3 +15,43 @@ func TestExtractStructuredContextURLs(t *testing.T) {
 		t.Fatalf("grafana uid: %v", ctx["grafana_dashboard_uid"])
 	}
 }
+
+func TestRegexContextExtractors(t *testing.T) {
+	exs, err := NewRegexContextExtractors([]ContextExtractorConfig{
+		{
+			Name:     "ghe_pr",
+			Pattern:  `https://github\.acme\.corp/(?P<repo>[\w.-]+/[\w.-]+)/pull/(?P<number>\d+)`,
+			Context:  map[string]string{"repo": "github_repo", "number": "github_pr_number"},
+			Integers: []string{"number"},
+		},
+		{
+			Name:    "jira_key",
+			Pattern: `\b(?P<key>[A-Z][A-Z0-9]+-\d+)\b`,
+			Context: map[string]string{"key": "jira_issue_key"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	extra := []ContextExtractor{exs[0], exs[1]}
+	ctx := ExtractStructuredContext("check https://github.acme.corp/pay/api/pull/77 for PAY-1234", extra...)
+	if ctx["github_repo"] != "pay/api" || ctx["github_pr_number"] != 77 || ctx["jira_issue_key"] != "PAY-1234" {
+		t.Fatalf("unexpected context %v", ctx)
+	}
+
+	// Built-ins win over configured extractors for the same key.
+	ctx = ExtractStructuredContext("https://acme.atlassian.net/browse/OPS-1 and PAY-2", extra...)
+	if ctx["jira_issue_key"] != "OPS-1" {
+		t.Fatalf("expected built-in value, got %v", ctx["jira_issue_key"])
+	}
+
+	for _, bad := range []ContextExtractorConfig{
+		{Name: "x", Pattern: `(?P<a>\d+`, Context: map[string]string{"a": "k"}},
+		{Name: "x", Pattern: `(?P<a>\d+)`, Context: map[string]string{"b": "k"}},
+		{Name: "x", Pattern: `(?P<a>\d+)`, Context: map[string]string{"a": "k"}, Args: []ContextArgRule{{Tools: []string{"["}, Set: map[string]string{"id": "k"}}}},
+	} {
+		if _, err := NewRegexContextExtractor(bad); err == nil {
+			t.Fatalf("expected error for %+v", bad)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LLMClient is the chat model used by the router. JSON completions are planning calls and
+// text completions are summarizing calls, so providers can use a different model for each.
+// The finish reason is normalized to "length" when the output was truncated.
+type LLMClient interface {
+	ChatCompletionJSONWithFinishReason(ctx context.Context, system string, user string) ([]byte, string, error)
+	ChatCompletionTextWithFinishReason(ctx context.Context, system string, user string) (string, string, error)
+}
+
+// LLM providers (LLMConfig.Provider).
+const (
+	ProviderOpenRouter = "openrouter"
+	ProviderOpenAI     = "openai" // any OpenAI-compatible /chat/completions server (vLLM, llama.cpp, LM Studio, ...)
+	ProviderAnthropic  = "anthropic"
+	ProviderOllama     = "ollama"
+)
+
+// LLMConfig selects and configures the router's LLM provider.
+//
+// Example (YAML, `llm:` in the proxy config):
+//
+//	llm:
+//	  provider: ollama
+//	  model: qwen2.5:14b
+//	  summary_model: llama3.1:8b
+type LLMConfig struct {
+	// Provider is openrouter (default), openai, anthropic or ollama.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	// BaseURL overrides the provider's default endpoint. Default: the provider's own env var
+	// (see providerBaseURLEnv), so a URL set for one provider never redirects another.
+	BaseURL string `yaml:"base_url,omitempty" json:"base_url,omitempty"`
+	// APIKey expands env vars (e.g. "${ANTHROPIC_API_KEY}"). Default: the provider's usual env var.
+	APIKey string `yaml:"api_key,omitempty" json:"api_key,omitempty"`
+	// Model is used for planning.
+	Model string `yaml:"model,omitempty" json:"model,omitempty"`
+	// SummaryModel is used for final answers (default: Model).
+	SummaryModel     string `yaml:"summary_model,omitempty" json:"summary_model,omitempty"`
+	TimeoutMS        int    `yaml:"timeout_ms,omitempty" json:"timeout_ms,omitempty"`
+	MaxTokensPlan    int    `yaml:"max_tokens_plan,omitempty" json:"max_tokens_plan,omitempty"`
+	MaxTokensSummary int    `yaml:"max_tokens_summary,omitempty" json:"max_tokens_summary,omitempty"`
+	// Prices in USD per million tokens, for providers that do not report cost (OpenRouter does).
+	InputCostPerMTok  float64 `yaml:"input_cost_per_mtok,omitempty" json:"input_cost_per_mtok,omitempty"`
+	OutputCostPerMTok float64 `yaml:"output_cost_per_mtok,omitempty" json:"output_cost_per_mtok,omitempty"`
+	// MaxTokensTotal and MaxCostUSD bound the router's LLM usage per session (0: unlimited).
+	MaxTokensTotal int     `yaml:"max_tokens_total,omitempty" json:"max_tokens_total,omitempty"`
+	MaxCostUSD     float64 `yaml:"max_cost_usd,omitempty" json:"max_cost_usd,omitempty"`
+}
+
+// LLMConfigFromEnv reads MCP_LENS_LLM_PROVIDER, MCP_LENS_LLM_API_KEY and the MCP_LENS_ROUTER_* settings.
+func LLMConfigFromEnv() LLMConfig {
+	return LLMConfig{
+		Provider:          strings.TrimSpace(os.Getenv("MCP_LENS_LLM_PROVIDER")),
+		APIKey:            strings.TrimSpace(os.Getenv("MCP_LENS_LLM_API_KEY")),
+		Model:             strings.TrimSpace(os.Getenv("MCP_LENS_ROUTER_MODEL")),
+		SummaryModel:      strings.TrimSpace(os.Getenv("MCP_LENS_ROUTER_SUMMARY_MODEL")),
+		TimeoutMS:         positiveIntFromEnv("MCP_LENS_ROUTER_TIMEOUT_MS"),
+		MaxTokensPlan:     positiveIntFromEnv("MCP_LENS_ROUTER_MAX_TOKENS_PLAN"),
+		MaxTokensSummary:  positiveIntFromEnv("MCP_LENS_ROUTER_MAX_TOKENS_SUMMARY"),
+		InputCostPerMTok:  positiveFloatFromEnv("MCP_LENS_LLM_INPUT_COST_PER_MTOK"),
+		OutputCostPerMTok: positiveFloatFromEnv("MCP_LENS_LLM_OUTPUT_COST_PER_MTOK"),
+		MaxTokensTotal:    positiveIntFromEnv("MCP_LENS_LLM_MAX_TOKENS_TOTAL"),
+		MaxCostUSD:        positiveFloatFromEnv("MCP_LENS_LLM_MAX_COST_USD"),
+	}
+}
+
+func positiveIntFromEnv(key string) int {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+func positiveFloatFromEnv(key string) float64 {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			return f
+		}
+	}
+	return 0
+}
+
+// Merge returns c with empty fields taken from base.
+func (c LLMConfig) Merge(base LLMConfig) LLMConfig {
+	pick := func(a, b string) string {
+		if strings.TrimSpace(a) != "" {
+			return a
+		}
+		return b
+	}
+	pickInt := func(a, b int) int {
+		if a > 0 {
+			return a
+		}
+		return b
+	}
+	pickFloat := func(a, b float64) float64 {
+		if a > 0 {
+			return a
+		}
+		return b
+	}
+	return LLMConfig{
+		Provider:          pick(c.Provider, base.Provider),
+		BaseURL:           pick(c.BaseURL, base.BaseURL),
+		APIKey:            pick(c.APIKey, base.APIKey),
+		Model:             pick(c.Model, base.Model),
+		SummaryModel:      pick(c.SummaryModel, base.SummaryModel),
+		TimeoutMS:         pickInt(c.TimeoutMS, base.TimeoutMS),
+		MaxTokensPlan:     pickInt(c.MaxTokensPlan, base.MaxTokensPlan),
+		MaxTokensSummary:  pickInt(c.MaxTokensSummary, base.MaxTokensSummary),
+		InputCostPerMTok:  pickFloat(c.InputCostPerMTok, base.InputCostPerMTok),
+		OutputCostPerMTok: pickFloat(c.OutputCostPerMTok, base.OutputCostPerMTok),
+		MaxTokensTotal:    pickInt(c.MaxTokensTotal, base.MaxTokensTotal),
+		MaxCostUSD:        pickFloat(c.MaxCostUSD, base.MaxCostUSD),
+	}
+}
+
+// Budget returns the configured session budget.
+func (c LLMConfig) Budget() Budget {
+	return Budget{MaxTokensTotal: c.MaxTokensTotal, MaxCostUSD: c.MaxCostUSD}
+}
+
+func (c LLMConfig) price() TokenPrice {
+	return TokenPrice{InputPerMTok: c.InputCostPerMTok, OutputPerMTok: c.OutputCostPerMTok}
+}
+
+// NewLLMClientFromEnv creates the LLM client configured by environment variables.
+func NewLLMClientFromEnv() (LLMClient, error) {
+	return NewLLMClient(LLMConfigFromEnv())
+}
+
+// NewLLMClient creates a client for cfg.Provider.
+func NewLLMClient(cfg LLMConfig) (LLMClient, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "", ProviderOpenRouter:
+		return newOpenAICompatibleClient(cfg, ProviderOpenRouter)
+	case ProviderOpenAI:
+		return newOpenAICompatibleClient(cfg, ProviderOpenAI)
+	case ProviderAnthropic:
+		return newAnthropicClient(cfg)
+	case ProviderOllama:
+		return newOllamaClient(cfg)
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q (expected openrouter, openai, anthropic or ollama)", cfg.Provider)
+	}
+}
+
+// providerBaseURLEnv names the env var holding each provider's endpoint override.
+// MCP_LENS_ROUTER_BASE_URL predates the other providers and only applies to OpenRouter.
+var providerBaseURLEnv = map[string]string{
+	ProviderOpenRouter: "MCP_LENS_ROUTER_BASE_URL",
+	ProviderOpenAI:     "MCP_LENS_OPENAI_BASE_URL",
+	ProviderAnthropic:  "MCP_LENS_ANTHROPIC_BASE_URL",
+	ProviderOllama:     "MCP_LENS_OLLAMA_BASE_URL",
+}
+
+// baseURL returns the configured endpoint or the provider's env override ("" for the provider default).
+func (c LLMConfig) baseURL(provider string) string {
+	if u := strings.TrimSpace(c.BaseURL); u != "" {
+		return u
+	}
+	return strings.TrimSpace(os.Getenv(providerBaseURLEnv[provider]))
+}
+
+// apiKey returns the configured key or the first non-empty env var.
+func (c LLMConfig) apiKey(envKeys ...string) string {
+	if k := strings.TrimSpace(os.ExpandEnv(c.APIKey)); k != "" {
+		return k
+	}
+	for _, e := range envKeys {
+		if k := strings.TrimSpace(os.Getenv(e)); k != "" {
+			return k
+		}
+	}
+	return ""
+}
+
+func (c LLMConfig) httpClient() *http.Client {
+	timeout := 30 * time.Second
+	if c.TimeoutMS > 0 {
+		timeout = time.Duration(c.TimeoutMS) * time.Millisecond
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// modelFor returns the model for a completion kind ("plan" or "summary").
+func modelFor(kind string, model string, summaryModel string) string {
+	if kind == "summary" && summaryModel != "" {
+		return summaryModel
+	}
+	return model
+}
+
+// extractJSON trims leading/trailing prose or markdown fences around a JSON object/array.
+func extractJSON(content string) []byte {
+	startObj := strings.Index(content, "{")
+	startArr := strings.Index(content, "[")
+	start := startObj
+	if start < 0 || (startArr >= 0 && startArr < start) {
+		start = startArr
+	}
+
+	var end int
+	if start == startArr {
+		end = strings.LastIndex(content, "]")
+	} else {
+		end = strings.LastIndex(content, "}")
+	}
+	if start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+	return []byte(strings.TrimSpace(content))
+}
+
+var errEmptyContent = errors.New("empty message content")
@@ -0,0 +1,173 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+func TestAnthropicClientMessagesAPI(t *testing.T) {
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "k" || r.Header.Get("anthropic-version") == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		models = append(models, body["model"].(string))
+		if body["system"] == "" || body["max_tokens"] == nil {
+			http.Error(w, "missing system or max_tokens", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"content":     []any{map[string]any{"type": "text", "text": "Here you go:\n```json\n{\"steps\":[],\"final_answer_needed\":false}\n```"}},
+			"stop_reason": "max_tokens",
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	cl, err := NewLLMClient(LLMConfig{Provider: "anthropic", BaseURL: srv.URL, APIKey: "k", Model: "planner", SummaryModel: "writer"})
+	if err != nil {
+		t.Fatalf("NewLLMClient: %v", err)
+	}
+	raw, finish, err := cl.ChatCompletionJSONWithFinishReason(context.Background(), "sys", "user")
+	if err != nil {
+		t.Fatalf("plan call: %v", err)
+	}
+	if string(raw) != `{"steps":[],"final_answer_needed":false}` || finish != "length" {
+		t.Fatalf("unexpected plan output %q finish=%q", raw, finish)
+	}
+	if _, _, err := cl.ChatCompletionTextWithFinishReason(context.Background(), "sys", "user"); err != nil {
+		t.Fatalf("summary call: %v", err)
+	}
+	if len(models) != 2 || models[0] != "planner" || models[1] != "writer" {
+		t.Fatalf("expected planner then summary model, got %v", models)
+	}
+}
+
+func TestOllamaClientChatAPI(t *testing.T) {
+	var formats []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		formats = append(formats, body["format"])
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"message":     map[string]any{"role": "assistant", "content": `{"steps":[]}`},
+			"done_reason": "stop",
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	cl, err := NewLLMClient(LLMConfig{Provider: "ollama", BaseURL: srv.URL, Model: "qwen"})
+	if err != nil {
+		t.Fatalf("NewLLMClient: %v", err)
+	}
+	if _, _, err := Plan(context.Background(), cl, "task", nil, nil, 3); err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if _, _, err := cl.ChatCompletionTextWithFinishReason(context.Background(), "sys", "user"); err != nil {
+		t.Fatalf("summary call: %v", err)
+	}
+	if len(formats) != 2 || formats[0] != "json" || formats[1] != nil {
+		t.Fatalf("expected JSON format for planning only, got %v", formats)
+	}
+}
+
+func TestNewLLMClientConfig(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	for _, cfg := range []LLMConfig{
+		{Model: "m"},                        // openrouter without key
+		{Provider: "openai", Model: "m"},    // openai without key or base URL
+		{Provider: "anthropic", Model: "m"}, // anthropic without key
+		{Provider: "ollama"},                // no model
+		{Provider: "bedrock", Model: "m"},
+	} {
+		if _, err := NewLLMClient(cfg); err == nil {
+			t.Fatalf("expected error for %+v", cfg)
+		}
+	}
+	// A local OpenAI-compatible server (llama.cpp, vLLM) needs no key.
+	if _, err := NewLLMClient(LLMConfig{Provider: "openai", BaseURL: "http://localhost:8080/v1", Model: "m"}); err != nil {
+		t.Fatalf("openai with base URL: %v", err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "from-env")
+	if _, err := NewLLMClient(LLMConfig{Provider: "anthropic", Model: "m"}); err != nil {
+		t.Fatalf("anthropic with env key: %v", err)
+	}
+
+	merged := LLMConfig{Provider: "ollama", SummaryModel: "small"}.Merge(LLMConfig{Provider: "openrouter", Model: "big", TimeoutMS: 5})
+	if merged.Provider != "ollama" || merged.Model != "big" || merged.SummaryModel != "small" || merged.TimeoutMS != 5 {
+		t.Fatalf("unexpected merge: %+v", merged)
+	}
+}
+
+func TestSamplingClientUsesClientLLM(t *testing.T) {
+	var got []mcp.CreateMessageParams
+	cl := NewSamplingClient(func(ctx context.Context, p mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+		got = append(got, p)
+		return &mcp.CreateMessageResult{
+			Role:       "assistant",
+			Content:    mcp.ContentBlock{Type: "text", Text: "```json\n{\"steps\":[]}\n```"},
+			Model:      "client-model",
+			StopReason: "maxTokens",
+		}, nil
+	}, LLMConfig{Model: "planner", MaxTokensPlan: 512})
+
+	raw, finish, err := cl.ChatCompletionJSONWithFinishReason(context.Background(), "sys", "user")
+	if err != nil {
+		t.Fatalf("plan call: %v", err)
+	}
+	if string(raw) != `{"steps":[]}` || finish != "length" {
+		t.Fatalf("unexpected plan output %q finish=%q", raw, finish)
+	}
+	p := got[0]
+	if p.SystemPrompt != "sys" || p.MaxTokens != 512 || len(p.Messages) != 1 || p.Messages[0].Content.Text != "user" {
+		t.Fatalf("unexpected sampling params: %+v", p)
+	}
+	if p.ModelPreferences == nil || p.ModelPreferences.Hints[0].Name != "planner" {
+		t.Fatalf("expected model hint, got %+v", p.ModelPreferences)
+	}
+	if _, _, err := cl.ChatCompletionTextWithFinishReason(context.Background(), "sys", "user"); err != nil {
+		t.Fatalf("summary call: %v", err)
+	}
+	if got[1].MaxTokens <= 0 {
+		t.Fatalf("sampling requires maxTokens, got %d", got[1].MaxTokens)
+	}
+}
+
+func TestLLMBaseURLEnvIsPerProvider(t *testing.T) {
+	t.Setenv("MCP_LENS_ROUTER_BASE_URL", "https://openrouter.example/api/v1")
+	t.Setenv("MCP_LENS_ANTHROPIC_BASE_URL", "")
+	t.Setenv("MCP_LENS_OLLAMA_BASE_URL", "http://gpu-box:11434")
+
+	cfg := LLMConfig{Provider: "anthropic", APIKey: "k", Model: "m"}.Merge(LLMConfigFromEnv())
+	a, err := NewLLMClient(cfg)
+	if err != nil {
+		t.Fatalf("anthropic: %v", err)
+	}
+	if got := a.(*AnthropicClient).baseURL; got != "https://api.anthropic.com" {
+		t.Fatalf("leftover OpenRouter URL must not redirect Anthropic, got %q", got)
+	}
+	o, err := NewLLMClient(LLMConfig{Provider: "ollama", Model: "m"})
+	if err != nil {
+		t.Fatalf("ollama: %v", err)
+	}
+	if got := o.(*OllamaClient).baseURL; got != "http://gpu-box:11434" {
+		t.Fatalf("expected ollama env URL, got %q", got)
+	}
+	if got := (LLMConfig{BaseURL: "http://explicit"}).baseURL(ProviderOllama); got != "http://explicit" {
+		t.Fatalf("explicit base_url must win, got %q", got)
+	}
+}
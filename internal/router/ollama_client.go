@@ -0,0 +1,130 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaClient talks to a local Ollama server (/api/chat). For llama.cpp, vLLM and other
+// OpenAI-compatible servers use the openai provider with a base URL instead.
+type OllamaClient struct {
+	baseURL          string
+	model            string
+	summaryModel     string
+	maxTokensPlan    int
+	maxTokensSummary int
+	price            TokenPrice
+	c                *http.Client
+}
+
+func newOllamaClient(cfg LLMConfig) (*OllamaClient, error) {
+	if cfg.Model == "" {
+		return nil, errors.New("missing MCP_LENS_ROUTER_MODEL")
+	}
+	baseURL := cfg.baseURL(ProviderOllama)
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	return &OllamaClient{
+		baseURL:          baseURL,
+		model:            cfg.Model,
+		summaryModel:     cfg.SummaryModel,
+		maxTokensPlan:    cfg.MaxTokensPlan,
+		maxTokensSummary: cfg.MaxTokensSummary,
+		price:            cfg.price(),
+		c:                cfg.httpClient(),
+	}, nil
+}
+
+func (cl *OllamaClient) chat(ctx context.Context, system string, user string, kind string) (string, string, error) {
+	options := map[string]any{"temperature": 0}
+	maxTokens := cl.maxTokensPlan
+	if kind == "summary" {
+		maxTokens = cl.maxTokensSummary
+	}
+	if maxTokens > 0 {
+		options["num_predict"] = maxTokens
+	}
+	model := modelFor(kind, cl.model, cl.summaryModel)
+	body := map[string]any{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": user},
+		},
+		"stream":  false,
+		"options": options,
+	}
+	if kind == "plan" {
+		// Constrain decoding to JSON; small local models drift into prose otherwise.
+		body["format"] = "json"
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", "", err
+	}
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(cl.baseURL, "/")+"/api/chat", bytes.NewReader(b))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cl.c.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", "", fmt.Errorf("ollama error (%d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var parsed struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		DoneReason      string `json:"done_reason"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", "", err
+	}
+	reportUsage(ctx, LLMUsage{
+		Kind:             kind,
+		Provider:         ProviderOllama,
+		Model:            model,
+		PromptTokens:     parsed.PromptEvalCount,
+		CompletionTokens: parsed.EvalCount,
+	}, cl.price, start)
+	content := strings.TrimSpace(parsed.Message.Content)
+	if content == "" {
+		return "", "", fmt.Errorf("ollama: %w", errEmptyContent)
+	}
+	return content, parsed.DoneReason, nil
+}
+
+func (cl *OllamaClient) ChatCompletionJSONWithFinishReason(ctx context.Context, system string, user string) ([]byte, string, error) {
+	content, finish, err := cl.chat(ctx, system, user, "plan")
+	if err != nil {
+		return nil, finish, err
+	}
+	return extractJSON(content), finish, nil
+}
+
+func (cl *OllamaClient) ChatCompletionTextWithFinishReason(ctx context.Context, system string, user string) (string, string, error) {
+	return cl.chat(ctx, system, user, "summary")
+}
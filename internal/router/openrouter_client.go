@@ -8,60 +8,63 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 )
 
+// OpenRouterClient talks to OpenRouter or any OpenAI-compatible /chat/completions endpoint.
 type OpenRouterClient struct {
+	provider         string
 	baseURL          string
 	apiKey           string
 	model            string
+	summaryModel     string
 	maxTokensPlan    int
 	maxTokensSummary int
+	price            TokenPrice
 	c                *http.Client
 }
 
+// NewOpenRouterClientFromEnv creates an OpenRouter client from OPENROUTER_API_KEY and MCP_LENS_ROUTER_*.
 func NewOpenRouterClientFromEnv() (*OpenRouterClient, error) {
-	apiKey := strings.TrimSpace(os.Getenv("OPENROUTER_API_KEY"))
-	model := strings.TrimSpace(os.Getenv("MCP_LENS_ROUTER_MODEL"))
-	if apiKey == "" || model == "" {
-		return nil, errors.New("missing OPENROUTER_API_KEY or MCP_LENS_ROUTER_MODEL")
-	}
-
-	baseURL := strings.TrimSpace(os.Getenv("MCP_LENS_ROUTER_BASE_URL"))
-	if baseURL == "" {
-		baseURL = "https://openrouter.ai/api/v1"
-	}
+	return newOpenAICompatibleClient(LLMConfigFromEnv(), ProviderOpenRouter)
+}
 
-	timeout := 30 * time.Second
-	if ms := strings.TrimSpace(os.Getenv("MCP_LENS_ROUTER_TIMEOUT_MS")); ms != "" {
-		if v, err := strconv.Atoi(ms); err == nil && v > 0 {
-			timeout = time.Duration(v) * time.Millisecond
+func newOpenAICompatibleClient(cfg LLMConfig, provider string) (*OpenRouterClient, error) {
+	baseURL := cfg.baseURL(provider)
+	var apiKey string
+	switch provider {
+	case ProviderOpenRouter:
+		apiKey = cfg.apiKey("OPENROUTER_API_KEY")
+		if apiKey == "" || cfg.Model == "" {
+			return nil, errors.New("missing OPENROUTER_API_KEY or MCP_LENS_ROUTER_MODEL")
 		}
-	}
-
-	maxPlan := 0
-	if v := strings.TrimSpace(os.Getenv("MCP_LENS_ROUTER_MAX_TOKENS_PLAN")); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			maxPlan = n
+		if baseURL == "" {
+			baseURL = "https://openrouter.ai/api/v1"
 		}
-	}
-	maxSummary := 0
-	if v := strings.TrimSpace(os.Getenv("MCP_LENS_ROUTER_MAX_TOKENS_SUMMARY")); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			maxSummary = n
+	default:
+		apiKey = cfg.apiKey("OPENAI_API_KEY")
+		if cfg.Model == "" {
+			return nil, errors.New("missing MCP_LENS_ROUTER_MODEL")
+		}
+		if baseURL == "" {
+			if apiKey == "" {
+				return nil, errors.New("missing OPENAI_API_KEY (or set MCP_LENS_OPENAI_BASE_URL for a local OpenAI-compatible server)")
+			}
+			baseURL = "https://api.openai.com/v1"
 		}
 	}
 
 	return &OpenRouterClient{
+		provider:         provider,
 		baseURL:          baseURL,
 		apiKey:           apiKey,
-		model:            model,
-		maxTokensPlan:    maxPlan,
-		maxTokensSummary: maxSummary,
-		c:                &http.Client{Timeout: timeout},
+		model:            cfg.Model,
+		summaryModel:     cfg.SummaryModel,
+		maxTokensPlan:    cfg.MaxTokensPlan,
+		maxTokensSummary: cfg.MaxTokensSummary,
+		price:            cfg.price(),
+		c:                cfg.httpClient(),
 	}, nil
 }
 
@@ -71,8 +74,9 @@ func (cl *OpenRouterClient) chatCompletion(ctx context.Context, system string, u
 		Content string `json:"content"`
 	}
 
+	model := modelFor(kind, cl.model, cl.summaryModel)
 	body := map[string]any{
-		"model": cl.model,
+		"model": model,
 		"messages": []msg{
 			{Role: "system", Content: system},
 			{Role: "user", Content: user},
@@ -90,20 +94,30 @@ func (cl *OpenRouterClient) chatCompletion(ctx context.Context, system string, u
 		}
 	}
 
+	if cl.provider == "" || cl.provider == ProviderOpenRouter {
+		// Ask OpenRouter to report the cost of the call in usage.cost.
+		body["usage"] = map[string]any{"include": true}
+	}
+
 	b, err := json.Marshal(body)
 	if err != nil {
 		return "", "", err
 	}
 
+	start := time.Now()
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(cl.baseURL, "/")+"/chat/completions", bytes.NewReader(b))
 	if err != nil {
 		return "", "", err
 	}
-	req.Header.Set("Authorization", "Bearer "+cl.apiKey)
+	if cl.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+cl.apiKey)
+	}
 	req.Header.Set("Content-Type", "application/json")
-	// Optional, but helps OpenRouter attribution/routing.
-	req.Header.Set("HTTP-Referer", "https://github.com/golovatskygroup/mcp-lens")
-	req.Header.Set("X-Title", "mcp-lens")
+	if cl.provider == "" || cl.provider == ProviderOpenRouter {
+		// Optional, but helps OpenRouter attribution/routing.
+		req.Header.Set("HTTP-Referer", "https://github.com/golovatskygroup/mcp-lens")
+		req.Header.Set("X-Title", "mcp-lens")
+	}
 
 	resp, err := cl.c.Do(req)
 	if err != nil {
@@ -116,7 +130,7 @@ func (cl *OpenRouterClient) chatCompletion(ctx context.Context, system string, u
 		return "", "", err
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", "", fmt.Errorf("openrouter error (%d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		return "", "", fmt.Errorf("%s error (%d): %s", cl.providerName(), resp.StatusCode, strings.TrimSpace(string(respBody)))
 	}
 
 	var parsed struct {
@@ -126,48 +140,44 @@ func (cl *OpenRouterClient) chatCompletion(ctx context.Context, system string, u
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
+		Model string `json:"model"`
+		Usage struct {
+			PromptTokens     int     `json:"prompt_tokens"`
+			CompletionTokens int     `json:"completion_tokens"`
+			TotalTokens      int     `json:"total_tokens"`
+			Cost             float64 `json:"cost"`
+		} `json:"usage"`
 	}
 	if err := json.Unmarshal(respBody, &parsed); err != nil {
 		return "", "", err
 	}
+	if parsed.Model != "" {
+		model = parsed.Model
+	}
+	reportUsage(ctx, LLMUsage{
+		Kind:             kind,
+		Provider:         cl.provider,
+		Model:            model,
+		PromptTokens:     parsed.Usage.PromptTokens,
+		CompletionTokens: parsed.Usage.CompletionTokens,
+		TotalTokens:      parsed.Usage.TotalTokens,
+		CostUSD:          parsed.Usage.Cost,
+	}, cl.price, start)
 	if len(parsed.Choices) == 0 {
-		return "", "", errors.New("openrouter: empty choices")
+		return "", "", fmt.Errorf("%s: empty choices", cl.providerName())
 	}
 
 	content := strings.TrimSpace(parsed.Choices[0].Message.Content)
 	if content == "" {
-		return "", "", errors.New("openrouter: empty message content")
+		return "", "", fmt.Errorf("%s: empty message content", cl.providerName())
 	}
 	return content, strings.TrimSpace(parsed.Choices[0].FinishReason), nil
 }
 
 // ChatCompletionJSON asks the model to return strict JSON in the assistant content.
 func (cl *OpenRouterClient) ChatCompletionJSON(ctx context.Context, system string, user string) ([]byte, error) {
-	content, _, err := cl.chatCompletion(ctx, system, user, "plan")
-	if err != nil {
-		return nil, err
-	}
-
-	// The model should return pure JSON. We still defensively try to extract a JSON object/array
-	// from the assistant content (handles occasional leading/trailing prose or markdown fences).
-	startObj := strings.Index(content, "{")
-	startArr := strings.Index(content, "[")
-	start := startObj
-	if start < 0 || (startArr >= 0 && startArr < start) {
-		start = startArr
-	}
-
-	var end int
-	if start == startArr {
-		end = strings.LastIndex(content, "]")
-	} else {
-		end = strings.LastIndex(content, "}")
-	}
-	if start >= 0 && end > start {
-		content = content[start : end+1]
-	}
-
-	return []byte(strings.TrimSpace(content)), nil
+	raw, _, err := cl.ChatCompletionJSONWithFinishReason(ctx, system, user)
+	return raw, err
 }
 
 // ChatCompletionText returns assistant content as plain text (no JSON extraction).
@@ -185,22 +195,15 @@ func (cl *OpenRouterClient) ChatCompletionJSONWithFinishReason(ctx context.Conte
 	if err != nil {
 		return nil, finish, err
 	}
+	// The model should return pure JSON. We still defensively try to extract a JSON object/array
+	// from the assistant content (handles occasional leading/trailing prose or markdown fences).
+	return extractJSON(content), finish, nil
+}
 
-	startObj := strings.Index(content, "{")
-	startArr := strings.Index(content, "[")
-	start := startObj
-	if start < 0 || (startArr >= 0 && startArr < start) {
-		start = startArr
-	}
-
-	var end int
-	if start == startArr {
-		end = strings.LastIndex(content, "]")
-	} else {
-		end = strings.LastIndex(content, "}")
-	}
-	if start >= 0 && end > start {
-		content = content[start : end+1]
+// providerName names the provider in errors.
+func (cl *OpenRouterClient) providerName() string {
+	if cl.provider == "" {
+		return ProviderOpenRouter
 	}
-	return []byte(strings.TrimSpace(content)), finish, nil
+	return cl.provider
 }
@@ -81,3 +81,68 @@ func TestSummarizeFallbackOnLength(t *testing.T) {
 		t.Fatalf("expected fallback summary, got %q", out)
 	}
 }
+
+func TestOpenRouterClientReportsUsage(t *testing.T) {
+	var gotUsage any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotUsage = body["usage"]
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   "vendor/actual",
+			"choices": []any{map[string]any{"message": map[string]any{"content": "hi"}, "finish_reason": "stop"}},
+			"usage":   map[string]any{"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15, "cost": 0.002},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	cl, err := NewLLMClient(LLMConfig{BaseURL: srv.URL, APIKey: "x", Model: "m"})
+	if err != nil {
+		t.Fatalf("NewLLMClient: %v", err)
+	}
+	var session UsageTotals
+	rec := NewUsageRecorder(session.Add)
+	if _, _, err := cl.ChatCompletionTextWithFinishReason(WithUsageRecorder(context.Background(), rec), "sys", "user"); err != nil {
+		t.Fatalf("summary call: %v", err)
+	}
+	if m, _ := gotUsage.(map[string]any); m["include"] != true {
+		t.Fatalf("expected usage accounting to be requested, got %v", gotUsage)
+	}
+	calls := rec.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected one call, got %+v", calls)
+	}
+	u := calls[0]
+	if u.Kind != "summary" || u.Provider != ProviderOpenRouter || u.Model != "vendor/actual" || u.TotalTokens != 15 || u.CostUSD != 0.002 {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+	if session.Calls != 1 || session.TotalTokens != 15 {
+		t.Fatalf("expected onRecord to receive the call, got %+v", session)
+	}
+	if reason, over := (Budget{MaxTokensTotal: 10}).Exceeded(rec.Totals()); !over || !strings.Contains(reason, "15/10 tokens") {
+		t.Fatalf("expected budget to be exceeded, got %q", reason)
+	}
+}
+
+func TestOpenAICompatibleClientErrorsNameProvider(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls > 1 {
+			_ = json.NewEncoder(w).Encode(map[string]any{"choices": []any{}})
+			return
+		}
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	cl := &OpenRouterClient{baseURL: srv.URL, model: "m", provider: ProviderOllama, c: &http.Client{Timeout: 5 * time.Second}}
+	_, _, err := cl.ChatCompletionTextWithFinishReason(context.Background(), "sys", "user")
+	if err == nil || !strings.HasPrefix(err.Error(), "ollama error (503)") {
+		t.Fatalf("expected the provider in the error, got %v", err)
+	}
+	_, _, err = cl.ChatCompletionTextWithFinishReason(context.Background(), "sys", "user")
+	if err == nil || err.Error() != "ollama: empty choices" {
+		t.Fatalf("expected the provider in the error, got %v", err)
+	}
+}
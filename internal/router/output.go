@@ -8,22 +8,27 @@ import (
 )
 
 type OutputOptions struct {
-	View          string   `json:"view,omitempty"` // full|summary|metadata|errors_only
-	IncludeFields []string `json:"include_fields,omitempty"`
-	ExcludeFields []string `json:"exclude_fields,omitempty"`
-	MaxItems      int      `json:"max_items,omitempty"`
-	MaxDepth      int      `json:"max_depth,omitempty"`
-	Redact        []string `json:"redact,omitempty"`
+	View          string   `json:"view,omitempty" yaml:"view,omitempty"` // full|summary|metadata|errors_only
+	IncludeFields []string `json:"include_fields,omitempty" yaml:"include_fields,omitempty"`
+	ExcludeFields []string `json:"exclude_fields,omitempty" yaml:"exclude_fields,omitempty"`
+	MaxItems      int      `json:"max_items,omitempty" yaml:"max_items,omitempty"`
+	MaxDepth      int      `json:"max_depth,omitempty" yaml:"max_depth,omitempty"`
+	Redact        []string `json:"redact,omitempty" yaml:"redact,omitempty"`
+	// Expr is a jq expression applied to the result before the other options (see ApplyOutputExpr).
+	Expr string `json:"expr,omitempty" yaml:"expr,omitempty"`
 }
 
-func ApplyOutputShaping(toolName string, v any, opts *OutputOptions) (any, error) {
+// ApplyOutputShaping applies the view, field and size options to a step result. Expr is not applied
+// here: the executor evaluates it first (ApplyOutputExpr), with the tool's timeout.
+// views are the presets declared by the tool (nil: generic field lists).
+func ApplyOutputShaping(views ViewPresets, v any, opts *OutputOptions) (any, error) {
 	if opts == nil {
 		return v, nil
 	}
 
 	out := v
 	if view := strings.ToLower(strings.TrimSpace(opts.View)); view != "" && view != "full" {
-		out = applyViewPreset(toolName, out, view)
+		out = applyViewPreset(views, out, view)
 	}
 
 	var err error
@@ -57,7 +62,7 @@ func ApplyOutputShaping(toolName string, v any, opts *OutputOptions) (any, error
 	return out, nil
 }
 
-func applyViewPreset(toolName string, v any, view string) any {
+func applyViewPreset(views ViewPresets, v any, view string) any {
 	view = strings.ToLower(strings.TrimSpace(view))
 
 	switch view {
@@ -74,29 +79,29 @@ func applyViewPreset(toolName string, v any, view string) any {
 		case []any:
 			out := make([]any, 0, len(vv))
 			for _, it := range vv {
-				out = append(out, applyViewPreset(toolName, it, view))
+				out = append(out, applyViewPreset(views, it, view))
 			}
 			return out
 		default:
 			return v
 		}
 	case "metadata":
-		paths := viewPresetPaths(toolName, view)
-		if len(paths) == 0 {
-			paths = []string{"id", "uid", "key", "name", "title", "url", "html_url", "web_url", "number", "repo"}
+		if paths := views[view]; len(paths) > 0 {
+			return projectPaths(v, paths)
 		}
+		paths := []string{"id", "uid", "key", "name", "title", "url", "html_url", "web_url", "number", "repo"}
 		out, err := applyInclude(v, paths)
 		if err == nil {
 			return out
 		}
 		return v
 	case "summary":
-		paths := viewPresetPaths(toolName, view)
-		if len(paths) == 0 {
-			paths = []string{
-				"id", "uid", "key", "name", "title", "summary", "description",
-				"url", "html_url", "web_url", "state", "status", "created", "updated",
-			}
+		if paths := views[view]; len(paths) > 0 {
+			return projectPaths(v, paths)
+		}
+		paths := []string{
+			"id", "uid", "key", "name", "title", "summary", "description",
+			"url", "html_url", "web_url", "state", "status", "created", "updated",
 		}
 		out, err := applyInclude(v, paths)
 		if err == nil {
@@ -108,30 +113,6 @@ func applyViewPreset(toolName string, v any, view string) any {
 	}
 }
 
-func viewPresetPaths(toolName string, view string) []string {
-	name := strings.ToLower(strings.TrimSpace(toolName))
-	switch view {
-	case "summary":
-		switch name {
-		case "grafana_get_dashboard":
-			return []string{
-				"meta.slug",
-				"dashboard.uid",
-				"dashboard.title",
-				"dashboard.tags",
-				"dashboard.time",
-				"dashboard.templating.list",
-			}
-		}
-	case "metadata":
-		switch name {
-		case "grafana_get_dashboard":
-			return []string{"dashboard.uid", "dashboard.title", "meta.slug"}
-		}
-	}
-	return nil
-}
-
 func applyInclude(v any, paths []string) (any, error) {
 	if v == nil {
 		return nil, nil
@@ -247,30 +228,35 @@ func getPath(v any, p string) (val any, ok bool, err error) {
 	if err != nil {
 		return nil, false, err
 	}
+	val, ok = getSegs(v, segs)
+	return val, ok, nil
+}
+
+func getSegs(v any, segs []pathSeg) (any, bool) {
 	cur := v
 	for _, s := range segs {
 		if s.index != nil {
 			arr, ok := cur.([]any)
 			if !ok {
-				return nil, false, nil
+				return nil, false
 			}
 			if *s.index < 0 || *s.index >= len(arr) {
-				return nil, false, nil
+				return nil, false
 			}
 			cur = arr[*s.index]
 			continue
 		}
 		obj, ok := cur.(map[string]any)
 		if !ok {
-			return nil, false, nil
+			return nil, false
 		}
 		nxt, ok := obj[s.field]
 		if !ok {
-			return nil, false, nil
+			return nil, false
 		}
 		cur = nxt
 	}
-	return cur, true, nil
+	return cur, true
 }
 
 func setPathIntoMap(dst map[string]any, p string, val any) error {
@@ -0,0 +1,80 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// compileOutputExpr parses and compiles a jq expression (OutputOptions.Expr).
+func compileOutputExpr(expr string) (*gojq.Code, error) {
+	q, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output expr: %w", err)
+	}
+	code, err := gojq.Compile(q)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output expr: %w", err)
+	}
+	return code, nil
+}
+
+// Validate checks the output options that can be rejected before execution (the expr syntax).
+func (o *OutputOptions) Validate() error {
+	if o == nil || strings.TrimSpace(o.Expr) == "" {
+		return nil
+	}
+	_, err := compileOutputExpr(o.Expr)
+	return err
+}
+
+// ApplyOutputExpr evaluates a jq expression against a step result. A single output is returned as is;
+// none or several are returned as an array.
+func ApplyOutputExpr(ctx context.Context, v any, expr string) (any, error) {
+	code, err := compileOutputExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	var outs []any
+	iter := code.RunWithContext(ctx, normalizeForExpr(v))
+	for {
+		out, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := out.(error); ok {
+			if herr, ok := err.(*gojq.HaltError); ok && herr.Value() == nil {
+				break
+			}
+			return nil, fmt.Errorf("output expr: %w", err)
+		}
+		outs = append(outs, out)
+	}
+	if len(outs) == 1 {
+		return outs[0], nil
+	}
+	if outs == nil {
+		outs = []any{}
+	}
+	return outs, nil
+}
+
+// normalizeForExpr converts values gojq does not accept (e.g. []map[string]any, float32) via JSON.
+func normalizeForExpr(v any) any {
+	switch v.(type) {
+	case nil, bool, float64, int, string, map[string]any, []any:
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return v
+	}
+	return out
+}
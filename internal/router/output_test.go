@@ -1,6 +1,10 @@
 package router
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
 
 func TestOutputIncludeExclude(t *testing.T) {
 	in := map[string]any{
@@ -11,7 +15,7 @@ func TestOutputIncludeExclude(t *testing.T) {
 		"x": 9,
 	}
 
-	out, err := ApplyOutputShaping("t", in, &OutputOptions{IncludeFields: []string{"a.b"}})
+	out, err := ApplyOutputShaping(nil, in, &OutputOptions{IncludeFields: []string{"a.b"}})
 	if err != nil {
 		t.Fatalf("include: %v", err)
 	}
@@ -33,7 +37,7 @@ func TestOutputIncludeExclude(t *testing.T) {
 		t.Fatalf("expected a.c excluded")
 	}
 
-	out2, err := ApplyOutputShaping("t", in, &OutputOptions{ExcludeFields: []string{"a.c"}})
+	out2, err := ApplyOutputShaping(nil, in, &OutputOptions{ExcludeFields: []string{"a.c"}})
 	if err != nil {
 		t.Fatalf("exclude: %v", err)
 	}
@@ -45,8 +49,71 @@ func TestOutputIncludeExclude(t *testing.T) {
 }
 
 func TestOutputInvalidPath(t *testing.T) {
-	_, err := ApplyOutputShaping("t", map[string]any{"a": 1}, &OutputOptions{IncludeFields: []string{"a["}})
+	_, err := ApplyOutputShaping(nil, map[string]any{"a": 1}, &OutputOptions{IncludeFields: []string{"a["}})
 	if err == nil {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestOutputExpr(t *testing.T) {
+	in := map[string]any{"check_runs": []any{
+		map[string]any{"name": "lint", "conclusion": "success", "id": 1.0},
+		map[string]any{"name": "test", "conclusion": "failure", "id": 2.0},
+	}}
+	out, err := ApplyOutputExpr(context.Background(), in, `.check_runs[] | select(.conclusion == "failure") | {name, conclusion}`)
+	if err != nil {
+		t.Fatalf("expr: %v", err)
+	}
+	m, ok := out.(map[string]any)
+	if !ok || m["name"] != "test" || len(m) != 2 {
+		t.Fatalf("unexpected single output %v", out)
+	}
+
+	// Several outputs (or none) become an array.
+	out, err = ApplyOutputExpr(context.Background(), in, `.check_runs[].name`)
+	if arr, ok := out.([]any); err != nil || !ok || len(arr) != 2 {
+		t.Fatalf("expected array of names, got %v (%v)", out, err)
+	}
+	out, err = ApplyOutputExpr(context.Background(), in, `.check_runs[] | select(.id > 5)`)
+	if arr, ok := out.([]any); err != nil || !ok || len(arr) != 0 {
+		t.Fatalf("expected empty array, got %v (%v)", out, err)
+	}
+
+	if err := (&OutputOptions{Expr: `.a |`}).Validate(); err == nil {
+		t.Fatalf("expected parse error")
+	}
+	if _, err := ApplyOutputExpr(context.Background(), in, `.check_runs + 1`); err == nil {
+		t.Fatalf("expected runtime error")
+	}
+}
+
+func TestOutputViewPresets(t *testing.T) {
+	in := map[string]any{
+		"total": 1.0,
+		"issues": []any{map[string]any{
+			"key":    "PAY-1",
+			"fields": map[string]any{"summary": "s", "status": map[string]any{"name": "Open", "id": "1"}, "description": "long"},
+		}},
+	}
+	views := ViewPresets{"summary": {"total", "issues.key", "issues.fields.status.name"}}
+	out, err := ApplyOutputShaping(views, in, &OutputOptions{View: "summary"})
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	b, _ := json.Marshal(out)
+	if string(b) != `{"issues":[{"fields":{"status":{"name":"Open"}},"key":"PAY-1"}],"total":1}` {
+		t.Fatalf("unexpected projection %s", b)
+	}
+
+	// Tools without a preset for the view keep the generic field list.
+	out, _ = ApplyOutputShaping(views, map[string]any{"key": "PAY-1", "x": 1}, &OutputOptions{View: "metadata"})
+	if b, _ := json.Marshal(out); string(b) != `{"key":"PAY-1"}` {
+		t.Fatalf("unexpected metadata %s", b)
+	}
+
+	for _, bad := range []ViewPresets{{"full": {"a"}}, {"summary": {}}, {"summary": {"a[0]"}}} {
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("expected error for %v", bad)
+		}
+	}
+}
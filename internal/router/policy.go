@@ -1,13 +1,66 @@
 package router
 
 import (
+	"fmt"
 	"os"
+	"sort"
 	"strings"
 )
 
 type Policy struct {
 	AllowLocal    map[string]struct{}
 	AllowUpstream map[string]struct{}
+
+	// Rules refine the allowlists; they are evaluated in order and the last matching rule wins.
+	Rules []PolicyRule
+	// AllowMutating disables the mutating-name heuristic. Even when false, a rule that
+	// allows a tool by its exact name (no glob) lets that one mutating tool through.
+	AllowMutating bool
+	// Profile is the name of the profile this policy was built from (informational).
+	Profile string
+	// WriteMode lets the planner propose the confirmable write tools (see IsConfirmableWrite).
+	// They pass validation but only run after the caller confirms the pending actions.
+	WriteMode bool
+}
+
+// confirmableWrites are the mutating local tools that write mode unlocks behind a confirmation.
+var confirmableWrites = map[string]struct{}{
+	"jira_add_comment":      {},
+	"jira_transition_issue": {},
+	"jira_create_issue":     {},
+	"jira_update_issue":     {},
+	"jira_add_attachment":   {},
+}
+
+// IsConfirmableWrite reports whether a tool is one of the write-mode tools that need human confirmation.
+func IsConfirmableWrite(source string, toolName string) bool {
+	if !strings.EqualFold(strings.TrimSpace(source), "local") {
+		return false
+	}
+	_, ok := confirmableWrites[toolName]
+	return ok
+}
+
+// ConfirmableWriteTools lists the write-mode tools in stable order.
+func ConfirmableWriteTools() []string {
+	out := make([]string, 0, len(confirmableWrites))
+	for k := range confirmableWrites {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// AllowedWrites lists the confirmable write tools the policy lets run (after confirmation),
+// whether through write mode or explicit allow rules.
+func (p Policy) AllowedWrites() []string {
+	var out []string
+	for _, name := range ConfirmableWriteTools() {
+		if p.IsAllowed("local", name) {
+			out = append(out, name)
+		}
+	}
+	return out
 }
 
 func DefaultPolicy() Policy {
@@ -78,25 +131,80 @@ func devModeEnabled() bool {
 	return v == "1" || strings.EqualFold(v, "true") || strings.EqualFold(v, "yes")
 }
 
+// IsAllowed reports whether a tool may run, ignoring per-argument constraints.
 func (p Policy) IsAllowed(source string, toolName string) bool {
+	return p.check(source, toolName, nil, false) == nil
+}
+
+// Check reports whether a tool may run with the given args. The error explains the denial.
+func (p Policy) Check(source string, toolName string, args map[string]any) error {
+	return p.check(source, toolName, args, true)
+}
+
+func (p Policy) check(source string, toolName string, args map[string]any, checkArgs bool) error {
 	nameLower := strings.ToLower(strings.TrimSpace(toolName))
 	if nameLower == "" {
-		return false
-	}
-	if isMutatingName(nameLower) {
-		return false
+		return fmt.Errorf("tool blocked by policy: empty tool name")
 	}
+	src := strings.ToLower(strings.TrimSpace(source))
 
-	switch strings.ToLower(strings.TrimSpace(source)) {
+	allowed := false
+	switch src {
 	case "local":
-		_, ok := p.AllowLocal[toolName]
-		return ok
+		_, allowed = p.AllowLocal[toolName]
 	case "upstream":
-		_, ok := p.AllowUpstream[toolName]
-		return ok
+		_, allowed = p.AllowUpstream[toolName]
 	default:
-		return false
+		return fmt.Errorf("tool blocked by policy: %s (invalid source %q)", toolName, source)
 	}
+
+	// Write mode allows the confirmable write tools; later deny rules still win.
+	writeTool := p.WriteMode && IsConfirmableWrite(src, toolName)
+	if writeTool {
+		allowed = true
+	}
+
+	explicit := false
+	var argErr error
+	for _, r := range p.Rules {
+		if !r.matches(src, toolName) {
+			continue
+		}
+		switch r.Effect {
+		case "allow":
+			allowed = true
+			explicit = r.names(toolName)
+			argErr = nil
+			if checkArgs {
+				argErr = r.checkArgs(args)
+			}
+		case "deny":
+			if len(r.Args) > 0 && (!checkArgs || !r.argsMatch(args)) {
+				// Deny rules with args only apply to calls whose args match.
+				continue
+			}
+			allowed = false
+			explicit = false
+			writeTool = false
+			argErr = nil
+		}
+	}
+
+	if !allowed {
+		return fmt.Errorf("tool blocked by policy: %s", toolName)
+	}
+	if !p.AllowMutating && !explicit && !writeTool && isMutatingName(nameLower) {
+		return fmt.Errorf("tool blocked by policy: %s (mutating)", toolName)
+	}
+	if argErr != nil {
+		return fmt.Errorf("tool blocked by policy: %s (%v)", toolName, argErr)
+	}
+	return nil
+}
+
+// IsMutatingName reports whether a tool name looks like it changes state (create_, update_, delete_, ...).
+func IsMutatingName(toolName string) bool {
+	return isMutatingName(strings.ToLower(strings.TrimSpace(toolName)))
 }
 
 func isMutatingName(nameLower string) bool {
@@ -0,0 +1,321 @@
+package router
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyConfig is the file/config representation of a Policy.
+//
+// Example (YAML; JSON works too):
+//
+//	profile: triage
+//	profiles:
+//	  ops:
+//	    extends: read-only
+//	    rules:
+//	      - tools: ["grafana_*"]
+//	        effect: allow
+//	rules:
+//	  - tools: ["get_pull_request_*", "list_pull_request_*"]
+//	    effect: allow
+//	    args:
+//	      repo: {pattern: "myorg/.+"}
+//	  - tools: ["jira_search_issues"]
+//	    effect: allow
+//	    args:
+//	      # project = PAY|OPS, optionally AND-ed with one parenthesized condition
+//	      jql: {pattern: "(?i)project\\s*=\\s*(PAY|OPS)(\\s+AND\\s+\\([^()]*\\))?"}
+type PolicyConfig struct {
+	// Profile selects the base profile (built-in or from Profiles). Default: "default".
+	Profile string `yaml:"profile,omitempty" json:"profile,omitempty"`
+	// Profiles defines or overrides named profiles.
+	Profiles map[string]PolicyProfile `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+	// Rules are applied on top of the selected profile.
+	Rules []PolicyRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+	// WriteMode enables confirmed Jira writes (same as MCP_LENS_WRITE_MODE=confirm).
+	WriteMode bool `yaml:"write_mode,omitempty" json:"write_mode,omitempty"`
+}
+
+// PolicyProfile is a named set of rules, optionally layered on another profile.
+type PolicyProfile struct {
+	Extends       string       `yaml:"extends,omitempty" json:"extends,omitempty"`
+	AllowMutating bool         `yaml:"allow_mutating,omitempty" json:"allow_mutating,omitempty"`
+	Rules         []PolicyRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// PolicyRule allows or denies tools matching glob patterns, optionally for one source only.
+type PolicyRule struct {
+	// Tools are glob patterns (path.Match syntax) over tool names.
+	Tools []string `yaml:"tools" json:"tools"`
+	// Source restricts the rule to local or upstream tools (empty or "*" = any).
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+	// Effect is allow or deny.
+	Effect string `yaml:"effect" json:"effect"`
+	// Args constrains argument values. For allow rules every constraint must hold;
+	// deny rules only apply when every constraint holds.
+	Args map[string]ArgConstraint `yaml:"args,omitempty" json:"args,omitempty"`
+}
+
+// ArgConstraint restricts one argument. Arrays are checked element by element.
+type ArgConstraint struct {
+	// Pattern is a regular expression the whole value must match (it is implicitly anchored,
+	// so "PAY" does not accept "PAY OR HR").
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	// Enum lists allowed values.
+	Enum []string `yaml:"enum,omitempty" json:"enum,omitempty"`
+	// Required rejects calls that omit the argument.
+	Required bool `yaml:"required,omitempty" json:"required,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// BuiltinPolicyProfiles lists the profile names available without configuration.
+func BuiltinPolicyProfiles() []string {
+	return []string{"default", "read-only", "triage", "dev"}
+}
+
+// LoadPolicyFile reads a YAML or JSON policy file.
+func LoadPolicyFile(p string) (PolicyConfig, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return PolicyConfig{}, fmt.Errorf("read policy file: %w", err)
+	}
+	var cfg PolicyConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return PolicyConfig{}, fmt.Errorf("parse policy file %s: %w", p, err)
+	}
+	return cfg, nil
+}
+
+// NewPolicy builds a Policy from config: the selected profile (resolving extends chains),
+// followed by the top-level rules.
+func NewPolicy(cfg PolicyConfig) (Policy, error) {
+	name := strings.TrimSpace(cfg.Profile)
+	if name == "" {
+		name = "default"
+	}
+	p, err := resolveProfile(name, cfg.Profiles, map[string]bool{})
+	if err != nil {
+		return Policy{}, err
+	}
+	rules, err := compileRules(cfg.Rules)
+	if err != nil {
+		return Policy{}, err
+	}
+	p.Rules = append(p.Rules, rules...)
+	p.Profile = name
+	p.WriteMode = p.WriteMode || cfg.WriteMode
+	return p, nil
+}
+
+func resolveProfile(name string, profiles map[string]PolicyProfile, seen map[string]bool) (Policy, error) {
+	if seen[name] {
+		return Policy{}, fmt.Errorf("policy profile %q extends itself", name)
+	}
+	seen[name] = true
+
+	prof, ok := profiles[name]
+	if !ok {
+		return builtinProfile(name, profiles)
+	}
+
+	base := Policy{AllowLocal: map[string]struct{}{}, AllowUpstream: map[string]struct{}{}}
+	if ext := strings.TrimSpace(prof.Extends); ext != "" {
+		var err error
+		base, err = resolveProfile(ext, profiles, seen)
+		if err != nil {
+			return Policy{}, err
+		}
+	}
+	rules, err := compileRules(prof.Rules)
+	if err != nil {
+		return Policy{}, fmt.Errorf("policy profile %q: %w", name, err)
+	}
+	base.Rules = append(append([]PolicyRule{}, base.Rules...), rules...)
+	base.AllowMutating = base.AllowMutating || prof.AllowMutating
+	return base, nil
+}
+
+func builtinProfile(name string, profiles map[string]PolicyProfile) (Policy, error) {
+	switch name {
+	case "default":
+		// Legacy behavior: local allowlist only, no upstream tools.
+		return DefaultPolicy(), nil
+	case "read-only":
+		p := DefaultPolicy()
+		// Upstream tools are allowed unless they look mutating.
+		p.Rules = append(p.Rules, PolicyRule{Tools: []string{"*"}, Source: "upstream", Effect: "allow"})
+		return p, nil
+	case "triage":
+		p, err := resolveProfile("read-only", profiles, map[string]bool{})
+		if err != nil {
+			return Policy{}, err
+		}
+		p.Rules = append(p.Rules, PolicyRule{
+			Tools:  []string{"jira_add_comment", "jira_transition_issue"},
+			Source: "local",
+			Effect: "allow",
+		})
+		return p, nil
+	case "dev":
+		p, err := resolveProfile("read-only", profiles, map[string]bool{})
+		if err != nil {
+			return Policy{}, err
+		}
+		p.Rules = append(p.Rules, PolicyRule{Tools: []string{"dev_scaffold_tool"}, Source: "local", Effect: "allow"})
+		return p, nil
+	}
+
+	known := append([]string{}, BuiltinPolicyProfiles()...)
+	for k := range profiles {
+		known = append(known, k)
+	}
+	sort.Strings(known)
+	return Policy{}, fmt.Errorf("unknown policy profile %q (available: %s)", name, strings.Join(known, ", "))
+}
+
+func compileRules(in []PolicyRule) ([]PolicyRule, error) {
+	out := make([]PolicyRule, 0, len(in))
+	for i, r := range in {
+		r.Effect = strings.ToLower(strings.TrimSpace(r.Effect))
+		if r.Effect != "allow" && r.Effect != "deny" {
+			return nil, fmt.Errorf("policy rule %d: effect must be allow or deny", i)
+		}
+		r.Source = strings.ToLower(strings.TrimSpace(r.Source))
+		switch r.Source {
+		case "", "*", "local", "upstream":
+		default:
+			return nil, fmt.Errorf("policy rule %d: source must be local, upstream or *", i)
+		}
+		if len(r.Tools) == 0 {
+			return nil, fmt.Errorf("policy rule %d: tools is required", i)
+		}
+		for _, pat := range r.Tools {
+			if _, err := path.Match(pat, ""); err != nil {
+				return nil, fmt.Errorf("policy rule %d: invalid tool pattern %q", i, pat)
+			}
+		}
+		if len(r.Args) > 0 {
+			args := make(map[string]ArgConstraint, len(r.Args))
+			for k, c := range r.Args {
+				if c.Pattern != "" {
+					re, err := compileArgPattern(c.Pattern)
+					if err != nil {
+						return nil, fmt.Errorf("policy rule %d: invalid pattern for arg %s: %w", i, k, err)
+					}
+					c.re = re
+				}
+				args[k] = c
+			}
+			r.Args = args
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (r PolicyRule) matches(source string, toolName string) bool {
+	if r.Source != "" && r.Source != "*" && r.Source != source {
+		return false
+	}
+	for _, pat := range r.Tools {
+		if ok, _ := path.Match(pat, toolName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// names reports whether the rule lists toolName literally (not via a glob).
+func (r PolicyRule) names(toolName string) bool {
+	for _, pat := range r.Tools {
+		if pat == toolName {
+			return true
+		}
+	}
+	return false
+}
+
+// checkArgs returns the first violated constraint.
+func (r PolicyRule) checkArgs(args map[string]any) error {
+	keys := make([]string, 0, len(r.Args))
+	for k := range r.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := r.Args[k].check(k, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r PolicyRule) argsMatch(args map[string]any) bool {
+	return r.checkArgs(args) == nil
+}
+
+func (c ArgConstraint) check(name string, args map[string]any) error {
+	v, ok := args[name]
+	if !ok || v == nil {
+		if c.Required {
+			return fmt.Errorf("arg %s is required", name)
+		}
+		return nil
+	}
+	if arr, ok := v.([]any); ok {
+		for _, it := range arr {
+			if err := c.checkValue(name, it); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return c.checkValue(name, v)
+}
+
+// compileArgPattern compiles an ArgConstraint pattern with full-match semantics.
+func compileArgPattern(pattern string) (*regexp.Regexp, error) {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+func (c ArgConstraint) checkValue(name string, v any) error {
+	if _, ok := v.(stepRef); ok {
+		// Step output reference, unknown until execution: it passes allow constraints (re-checked
+		// once resolved) and, conservatively, makes deny constraints match.
+		return nil
+	}
+	s := fmt.Sprint(v)
+	if f, ok := v.(float64); ok && f == float64(int64(f)) {
+		s = fmt.Sprint(int64(f))
+	}
+	re := c.re
+	if re == nil && c.Pattern != "" {
+		var err error
+		if re, err = compileArgPattern(c.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern for arg %s: %w", name, err)
+		}
+	}
+	if re != nil && !re.MatchString(s) {
+		return fmt.Errorf("arg %s=%q does not match %s", name, s, c.Pattern)
+	}
+	if len(c.Enum) > 0 {
+		for _, e := range c.Enum {
+			if e == s {
+				return nil
+			}
+		}
+		return fmt.Errorf("arg %s=%q is not one of %s", name, s, strings.Join(c.Enum, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package router
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewPolicyDefaultProfileMatchesDefaultPolicy(t *testing.T) {
+	p, err := NewPolicy(PolicyConfig{})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	if !p.IsAllowed("local", "search_tools") {
+		t.Fatalf("expected search_tools allowed")
+	}
+	if p.IsAllowed("upstream", "list_issues") {
+		t.Fatalf("expected upstream tools blocked by default profile")
+	}
+}
+
+func TestPolicyReadOnlyProfileAllowsNonMutatingUpstream(t *testing.T) {
+	p, err := NewPolicy(PolicyConfig{Profile: "read-only"})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	if !p.IsAllowed("upstream", "list_issues") {
+		t.Fatalf("expected upstream read tool allowed")
+	}
+	if p.IsAllowed("upstream", "create_pull_request") {
+		t.Fatalf("expected mutating upstream tool blocked")
+	}
+	if p.IsAllowed("local", "jira_add_comment") {
+		t.Fatalf("expected jira_add_comment blocked in read-only")
+	}
+
+	triage, err := NewPolicy(PolicyConfig{Profile: "triage"})
+	if err != nil {
+		t.Fatalf("NewPolicy triage: %v", err)
+	}
+	if !triage.IsAllowed("local", "jira_add_comment") {
+		t.Fatalf("expected jira_add_comment allowed in triage")
+	}
+}
+
+func TestPolicyRulesGlobsSourcesAndArgs(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "policy.yaml")
+	data := `
+profile: ops
+profiles:
+  ops:
+    extends: read-only
+    rules:
+      - tools: ["grafana_*"]
+        effect: deny
+rules:
+  - tools: ["get_pull_request_*"]
+    source: local
+    effect: allow
+    args:
+      repo: {pattern: "myorg/.+", required: true}
+  - tools: ["jira_search_issues"]
+    effect: allow
+    args:
+      jql: {pattern: "(?i)project\\s*=\\s*(PAY|OPS)(\\s+AND\\s+\\([^()]*\\))?"}
+  - tools: ["jira_create_issue"]
+    source: local
+    effect: allow
+`
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfg, err := LoadPolicyFile(file)
+	if err != nil {
+		t.Fatalf("LoadPolicyFile: %v", err)
+	}
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	if p.IsAllowed("local", "grafana_search") {
+		t.Fatalf("expected grafana_* denied by profile rule")
+	}
+	if err := p.Check("local", "get_pull_request_details", map[string]any{"repo": "myorg/app", "number": float64(1)}); err != nil {
+		t.Fatalf("expected myorg repo allowed: %v", err)
+	}
+	err = p.Check("local", "get_pull_request_details", map[string]any{"repo": "other/app"})
+	if err == nil || !strings.Contains(err.Error(), "repo") {
+		t.Fatalf("expected repo constraint violation, got %v", err)
+	}
+	if err := p.Check("local", "get_pull_request_details", map[string]any{}); err == nil {
+		t.Fatalf("expected missing required repo to be rejected")
+	}
+	if err := p.Check("local", "jira_search_issues", map[string]any{"jql": "project = PAY AND (status = Open OR labels = ops)"}); err != nil {
+		t.Fatalf("expected PAY jql allowed: %v", err)
+	}
+	// Patterns match the whole value, so a matching prefix does not admit other projects.
+	for _, jql := range []string{"project = HR", "project = PAY OR project = HR", "project = PAY AND (x) OR (project = HR)"} {
+		if err := p.Check("local", "jira_search_issues", map[string]any{"jql": jql}); err == nil {
+			t.Fatalf("expected %q rejected", jql)
+		}
+	}
+	// Exact-name allow overrides the mutating-name heuristic.
+	if !p.IsAllowed("local", "jira_create_issue") {
+		t.Fatalf("expected explicitly allowed jira_create_issue")
+	}
+	if p.IsAllowed("local", "jira_update_issue") {
+		t.Fatalf("expected jira_update_issue still blocked")
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	if _, err := NewPolicy(PolicyConfig{Profile: "nope"}); err == nil {
+		t.Fatalf("expected unknown profile error")
+	}
+	loop := PolicyConfig{Profile: "a", Profiles: map[string]PolicyProfile{"a": {Extends: "b"}, "b": {Extends: "a"}}}
+	if _, err := NewPolicy(loop); err == nil {
+		t.Fatalf("expected extends cycle error")
+	}
+	bad := PolicyConfig{Rules: []PolicyRule{{Tools: []string{"x"}, Effect: "maybe"}}}
+	if _, err := NewPolicy(bad); err == nil {
+		t.Fatalf("expected invalid effect error")
+	}
+}
+
+func TestValidatePlanEnforcesArgConstraints(t *testing.T) {
+	p, err := NewPolicy(PolicyConfig{Rules: []PolicyRule{{
+		Tools:  []string{"get_pull_request_details"},
+		Effect: "allow",
+		Args:   map[string]ArgConstraint{"repo": {Pattern: "myorg/.+"}},
+	}}})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	catalog := []ToolCatalogItem{{Name: "get_pull_request_details", Source: "local"}}
+	plan := ModelPlan{Steps: []PlanStep{{Name: "get_pull_request_details", Source: "local", Args: json.RawMessage(`{"repo":"evil/repo","number":1}`)}}}
+	if err := ValidatePlan(plan, p, catalog, 1); err == nil || !strings.Contains(err.Error(), "blocked by policy") {
+		t.Fatalf("expected policy error, got %v", err)
+	}
+}
+
+func TestPolicyWriteModeAllowsConfirmableWrites(t *testing.T) {
+	p, err := NewPolicy(PolicyConfig{WriteMode: true, Rules: []PolicyRule{{Tools: []string{"jira_create_issue"}, Effect: "deny"}}})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	if !p.IsAllowed("local", "jira_add_comment") {
+		t.Fatalf("expected jira_add_comment allowed in write mode")
+	}
+	if p.IsAllowed("local", "jira_create_issue") {
+		t.Fatalf("expected deny rule to override write mode")
+	}
+	if p.IsAllowed("local", "create_pull_request") {
+		t.Fatalf("expected non-confirmable mutating tools still blocked")
+	}
+}
+
+func TestPlanPayloadReflectsAllowedWrites(t *testing.T) {
+	policy := planPayload("comment on GO-1", map[string]any{}, nil, 3)["policy"].(map[string]any)
+	if policy["read_only"] != true {
+		t.Fatalf("expected read-only policy without writes, got %v", policy)
+	}
+	policy = planPayload("comment on GO-1", map[string]any{"write_mode": "confirm", "write_tools": []string{"jira_add_comment"}}, nil, 3)["policy"].(map[string]any)
+	writes, _ := policy["confirmation_required"].([]string)
+	if policy["read_only"] != false || len(writes) != 1 || writes[0] != "jira_add_comment" {
+		t.Fatalf("unexpected policy %v", policy)
+	}
+}
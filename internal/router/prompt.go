@@ -2,6 +2,7 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
@@ -10,6 +11,64 @@ func BuildPlanSystemPrompt() string {
 }
 
 func BuildPlanUserPrompt(userInput string, ctx map[string]any, catalog []ToolCatalogItem, maxSteps int) (string, error) {
+	b, err := json.MarshalIndent(planPayload(userInput, ctx, catalog, maxSteps), "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Generate a tool execution plan as JSON.\n\n%s", string(b)), nil
+}
+
+// BuildNextStepsUserPrompt asks for the next steps of an iterative query, given the results so far.
+func BuildNextStepsUserPrompt(userInput string, ctx map[string]any, catalog []ToolCatalogItem, history []ExecutedStep, remainingSteps int) (string, error) {
+	payload := planPayload(userInput, ctx, catalog, remainingSteps)
+	payload["executed_steps"] = TruncateExecutedStepsForLLM(history)
+	payload["first_step_index"] = len(history)
+	payload["iteration"] = []string{
+		"You are planning iteratively: executed_steps holds the results of the steps run so far (empty on the first round).",
+		"Plan only the next step(s) you can fill in now; use ids, urls and names found in executed_steps instead of guessing them.",
+		"The steps you plan are numbered from first_step_index: $ref steps[N] with N < first_step_index points at executed_steps[N].",
+		"If a step failed, change its args or pick another tool instead of repeating it unchanged.",
+		"When the task is answered (or cannot progress), return done=true with no steps.",
+	}
+	schema := payload["response_schema"].(map[string]any)
+	schema["properties"].(map[string]any)["done"] = map[string]any{"type": "boolean"}
+
+	b, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Plan the next tool steps as JSON.\n\n%s", string(b)), nil
+}
+
+// BuildRepairUserPrompt asks the model to fix a plan that ValidatePlan rejected.
+// history carries the results of earlier rounds in iterative mode (may be empty).
+func BuildRepairUserPrompt(userInput string, ctx map[string]any, catalog []ToolCatalogItem, maxSteps int, history []ExecutedStep, rejected ModelPlan, validationErr error) (string, error) {
+	payload := planPayload(userInput, ctx, catalog, maxSteps)
+	if len(history) > 0 {
+		payload["executed_steps"] = TruncateExecutedStepsForLLM(history)
+		payload["first_step_index"] = len(history)
+	}
+	payload["rejected_plan"] = rejected
+	payload["validation_error"] = validationErr.Error()
+	var stepErr *PlanStepError
+	if errors.As(validationErr, &stepErr) {
+		payload["offending_step"] = map[string]any{"index": stepErr.Index, "step": stepErr.Step}
+	}
+	payload["repair"] = []string{
+		"The rejected_plan failed validation with validation_error; offending_step (if present) is the step that failed.",
+		"Return a corrected full plan in the same response format. Keep valid steps; fix or replace the offending one.",
+		"Use only tools from the tools list, with args matching their inputSchema, and respect the policy.",
+	}
+
+	b, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Repair this tool execution plan and return it as JSON.\n\n%s", string(b)), nil
+}
+
+func planPayload(userInput string, ctx map[string]any, catalog []ToolCatalogItem, maxSteps int) map[string]any {
 	instructions := map[string]any{
 		"pr_review_workflow": []string{
 			"For comprehensive PR reviews, prefer fetch_complete_pr_diff (saves full diff to file) over get_pull_request_diff (returns chunks).",
@@ -47,10 +106,20 @@ func BuildPlanUserPrompt(userInput string, ctx map[string]any, catalog []ToolCat
 			"If context.grafana_client is set (from `grafana <client>` prefix), always set args.client for all grafana_* tool calls, unless args.base_url is explicitly set.",
 			"Available Grafana client aliases (if configured) are in context.grafana_clients; default alias (if set) is context.grafana_default_client.",
 		},
+		"dataflow":    "To pass a value from an earlier step's result, set the arg to {\"$ref\": \"steps[N].result.<path>\"} (N = 0-based index of an earlier step; path like head.sha or items[0].id). Steps of this plan are numbered from first_step_index (0 unless given); executed_steps[N] is steps[N]. Steps that reference each other must not share a parallel_group.",
+		"step_errors": "Transient tool errors (timeouts, 429, 502-504) are retried automatically. For optional steps set on_error=continue; to try another tool when a step fails set on_error=fallback and fallback to a step object (name, source, args; no on_error).",
+		"step_output": "To keep only what the task needs from a large result, set output.expr on the step to a jq expression, e.g. [.issues[] | {key, assignee: .fields.assignee.displayName}].",
 		"pagination":  "Auto-pagination is enabled. If a tool returns has_next=true, the system will automatically fetch the next page/chunk.",
 		"file_output": "Tools like fetch_complete_pr_diff save results to files and return file paths. The LLM client can then read these files.",
 	}
 
+	for _, t := range catalog {
+		if t.Stub {
+			instructions["tool_stubs"] = "Tools marked stub=true are listed without inputSchema. To use one, plan a describe_tool step with args {\"name\": \"<tool>\"} for it; its full schema is then provided and you can plan again."
+			break
+		}
+	}
+
 	if devModeEnabled() {
 		instructions["dev_workflow"] = []string{
 			"When the user asks to add/generate a new local tool, call dev_scaffold_tool with tool_name/tool_description/input_schema and a clear spec.",
@@ -58,14 +127,32 @@ func BuildPlanUserPrompt(userInput string, ctx map[string]any, catalog []ToolCat
 		}
 	}
 
+	policy := map[string]any{
+		"read_only": true,
+		"must_not":  []string{"create", "update", "merge", "delete", "write", "push"},
+	}
+	if mode, _ := ctx["write_mode"].(string); mode == "confirm" {
+		writes, _ := ctx["write_tools"].([]string)
+		if len(writes) == 0 {
+			writes = ConfirmableWriteTools()
+		}
+		policy = map[string]any{
+			"read_only":             false,
+			"confirmation_required": writes,
+			"must_not":              []string{"merge", "delete", "push"},
+		}
+		instructions["write_workflow"] = []string{
+			"Writes are enabled for the tools in policy.confirmation_required: if the user explicitly asks for such a Jira change, plan the corresponding tool.",
+			"Plans with write steps are not executed immediately; the whole plan is returned for confirmation and runs when the user confirms it.",
+			"Never plan write tools the user did not ask for.",
+		}
+	}
+
 	payload := map[string]any{
-		"task":      userInput,
-		"context":   ctx,
-		"max_steps": maxSteps,
-		"policy": map[string]any{
-			"read_only": true,
-			"must_not":  []string{"create", "update", "merge", "delete", "write", "push"},
-		},
+		"task":         userInput,
+		"context":      ctx,
+		"max_steps":    maxSteps,
+		"policy":       policy,
 		"instructions": instructions,
 		"tools":        catalog,
 		"response_schema": map[string]any{
@@ -81,6 +168,9 @@ func BuildPlanUserPrompt(userInput string, ctx map[string]any, catalog []ToolCat
 							"args":           map[string]any{"type": "object"},
 							"reason":         map[string]any{"type": "string"},
 							"parallel_group": map[string]any{"type": "string"},
+							"on_error":       map[string]any{"type": "string", "enum": []string{OnErrorFail, OnErrorContinue, OnErrorFallback}},
+							"fallback":       map[string]any{"type": "object"},
+							"output":         map[string]any{"type": "object", "properties": map[string]any{"expr": map[string]any{"type": "string"}}},
 						},
 						"required": []string{"name", "source", "args"},
 					},
@@ -90,17 +180,29 @@ func BuildPlanUserPrompt(userInput string, ctx map[string]any, catalog []ToolCat
 			"required": []string{"steps", "final_answer_needed"},
 		},
 	}
+	return payload
+}
+
+func BuildSummarizeSystemPrompt() string {
+	return "You summarize tool results for a human. Be concise and accurate. Return plain text (no JSON, no markdown code fences)."
+}
 
+func BuildStructuredSummarizeSystemPrompt() string {
+	return "You turn tool results into a structured answer. Return only one JSON value that is valid against the given JSON Schema (no prose, no markdown code fences). Use only facts from the tool results."
+}
+
+func BuildStructuredSummarizeUserPrompt(userInput string, res RouterResult, schema json.RawMessage) (string, error) {
+	payload := map[string]any{
+		"task":           userInput,
+		"answer_schema":  schema,
+		"executed_steps": TruncateExecutedStepsForLLM(res.ExecutedSteps),
+		"manifest":       res.Manifest,
+	}
 	b, err := json.MarshalIndent(payload, "", "  ")
 	if err != nil {
 		return "", err
 	}
-
-	return fmt.Sprintf("Generate a tool execution plan as JSON.\n\n%s", string(b)), nil
-}
-
-func BuildSummarizeSystemPrompt() string {
-	return "You summarize tool results for a human. Be concise and accurate. Return plain text (no JSON, no markdown code fences)."
+	return fmt.Sprintf("Write the final answer as JSON valid against answer_schema, based on executed tool results.\n\n%s", string(b)), nil
 }
 
 func BuildSummarizeUserPrompt(userInput string, res RouterResult) (string, error) {
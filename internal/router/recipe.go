@@ -0,0 +1,382 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParamKey marks a recipe arg that takes a parameter value as is (keeping its type): {"$param": "number"}.
+// Inside strings, "{{name}}" interpolates a parameter instead.
+const ParamKey = "$param"
+
+// Recipe is a named, parameterized plan template. Recipes run through executor mode
+// (query {recipe, params}) without calling the planner.
+//
+// Example (YAML, `recipes:` in the proxy config):
+//
+//	recipes:
+//	  - name: pr-triage
+//	    description: PR review bundle, checks and the linked Jira issue
+//	    params:
+//	      repo: {type: string, required: true}
+//	      number: {type: integer, required: true}
+//	      issue: {type: string, required: true}
+//	    steps:
+//	      - name: prepare_pull_request_review_bundle
+//	        source: local
+//	        args: {repo: {$param: repo}, number: {$param: number}}
+//	      - name: get_pull_request_checks
+//	        source: local
+//	        args: {repo: {$param: repo}, number: {$param: number}}
+//	      - name: jira_get_issue_bundle
+//	        source: local
+//	        args: {issue: "{{issue}}"}
+type Recipe struct {
+	Name        string                 `yaml:"name" json:"name"`
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Params      map[string]RecipeParam `yaml:"params,omitempty" json:"params,omitempty"`
+	Steps       []RecipeStep           `yaml:"steps" json:"steps"`
+}
+
+// RecipeParam declares a typed recipe input.
+type RecipeParam struct {
+	// Type is string (default), integer, number, boolean, array or object.
+	Type        string `yaml:"type,omitempty" json:"type,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty" json:"required,omitempty"`
+	// Default is used when the caller omits the param.
+	Default any `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// RecipeStep is a PlanStep whose args may contain parameter placeholders.
+type RecipeStep struct {
+	Name          string         `yaml:"name" json:"name"`
+	Source        string         `yaml:"source" json:"source"`
+	Args          map[string]any `yaml:"args,omitempty" json:"args,omitempty"`
+	Reason        string         `yaml:"reason,omitempty" json:"reason,omitempty"`
+	ParallelGroup string         `yaml:"parallel_group,omitempty" json:"parallel_group,omitempty"`
+	OnError       string         `yaml:"on_error,omitempty" json:"on_error,omitempty"`
+	Fallback      *RecipeStep    `yaml:"fallback,omitempty" json:"fallback,omitempty"`
+	Output        *OutputOptions `yaml:"output,omitempty" json:"output,omitempty"`
+}
+
+var (
+	recipeNameRe     = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	paramTemplateRe  = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+	recipeParamTypes = map[string]bool{"string": true, "integer": true, "number": true, "boolean": true, "array": true, "object": true}
+)
+
+// Validate checks the recipe name, the number of steps, param types and that every placeholder
+// refers to a declared param.
+func (r Recipe) Validate() error {
+	if !recipeNameRe.MatchString(r.Name) {
+		return fmt.Errorf("recipe name %q must match %s", r.Name, recipeNameRe)
+	}
+	if len(r.Steps) == 0 {
+		return fmt.Errorf("recipe %s: steps are required", r.Name)
+	}
+	if len(r.Steps) > MaxPlanSteps {
+		return fmt.Errorf("recipe %s: %d steps, at most %d are allowed", r.Name, len(r.Steps), MaxPlanSteps)
+	}
+	for name, p := range r.Params {
+		if !recipeParamTypes[p.paramType()] {
+			return fmt.Errorf("recipe %s: param %s has unknown type %q", r.Name, name, p.Type)
+		}
+		if p.Default != nil {
+			if err := p.check(name, p.Default); err != nil {
+				return fmt.Errorf("recipe %s: default: %w", r.Name, err)
+			}
+		}
+	}
+	for i, s := range r.Steps {
+		if strings.TrimSpace(s.Name) == "" {
+			return fmt.Errorf("recipe %s: steps[%d]: name is required", r.Name, i)
+		}
+		var unknown []string
+		collect := func(name string) {
+			if _, ok := r.Params[name]; !ok {
+				unknown = append(unknown, name)
+			}
+		}
+		walkParams(s.Args, collect)
+		if s.Fallback != nil {
+			walkParams(s.Fallback.Args, collect)
+		}
+		if len(unknown) > 0 {
+			return fmt.Errorf("recipe %s: steps[%d] (%s) uses undeclared param(s): %s", r.Name, i, s.Name, strings.Join(unknown, ", "))
+		}
+	}
+	return nil
+}
+
+// Expand checks params against the declared types and returns the plan steps with placeholders filled in.
+// Args whose {"$param"} refers to an omitted optional param are dropped.
+func (r Recipe) Expand(params map[string]any) ([]PlanStep, error) {
+	for name := range params {
+		if _, ok := r.Params[name]; !ok {
+			return nil, fmt.Errorf("recipe %s: unknown param %q (declared: %s)", r.Name, name, strings.Join(r.ParamNames(), ", "))
+		}
+	}
+	values := make(map[string]any, len(r.Params))
+	for name, p := range r.Params {
+		v, ok := params[name]
+		if !ok || v == nil {
+			if p.Default == nil {
+				if p.Required {
+					return nil, fmt.Errorf("recipe %s: missing required param %q", r.Name, name)
+				}
+				continue
+			}
+			v = p.Default
+		}
+		if err := p.check(name, v); err != nil {
+			return nil, fmt.Errorf("recipe %s: %w", r.Name, err)
+		}
+		values[name] = v
+	}
+
+	steps := make([]PlanStep, 0, len(r.Steps))
+	for i, s := range r.Steps {
+		step, err := s.expand(values)
+		if err != nil {
+			return nil, fmt.Errorf("recipe %s: steps[%d]: %w", r.Name, i, err)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func (s RecipeStep) expand(values map[string]any) (PlanStep, error) {
+	args := map[string]any{}
+	for k, v := range s.Args {
+		filled, keep := fillParams(v, values)
+		if keep {
+			args[k] = filled
+		}
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return PlanStep{}, err
+	}
+	step := PlanStep{Name: s.Name, Source: s.Source, Args: b, Reason: s.Reason, ParallelGroup: s.ParallelGroup, OnError: s.OnError, Output: s.Output}
+	if s.Fallback != nil {
+		fb, err := s.Fallback.expand(values)
+		if err != nil {
+			return PlanStep{}, fmt.Errorf("fallback: %w", err)
+		}
+		step.Fallback = &fb
+	}
+	return step, nil
+}
+
+// ParamNames returns the declared param names, sorted.
+func (r Recipe) ParamNames() []string {
+	names := make([]string, 0, len(r.Params))
+	for name := range r.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRecipeFromPlan turns executed plan steps into a recipe. Every arg equal to one of values
+// becomes a {"$param"} placeholder (string values of 3+ characters are also replaced inside
+// longer strings); params are required and typed after their values.
+func NewRecipeFromPlan(name string, description string, steps []PlanStep, values map[string]any) (Recipe, error) {
+	r := Recipe{Name: name, Description: description, Params: map[string]RecipeParam{}}
+	for param, v := range values {
+		r.Params[param] = RecipeParam{Type: typeOfValue(v), Required: true}
+	}
+	names := r.ParamNames()
+
+	for _, s := range steps {
+		step, err := recipeStepFromPlan(s, names, values)
+		if err != nil {
+			return Recipe{}, err
+		}
+		r.Steps = append(r.Steps, step)
+	}
+	return r, r.Validate()
+}
+
+func recipeStepFromPlan(s PlanStep, names []string, values map[string]any) (RecipeStep, error) {
+	var args map[string]any
+	if len(s.Args) > 0 {
+		if err := json.Unmarshal(s.Args, &args); err != nil {
+			return RecipeStep{}, fmt.Errorf("step %s: args must be a JSON object: %w", s.Name, err)
+		}
+	}
+	for k, v := range args {
+		args[k] = templateParams(v, names, values)
+	}
+	step := RecipeStep{Name: s.Name, Source: s.Source, Args: args, Reason: s.Reason, ParallelGroup: s.ParallelGroup, OnError: s.OnError, Output: s.Output}
+	if s.Fallback != nil {
+		fb, err := recipeStepFromPlan(*s.Fallback, names, values)
+		if err != nil {
+			return RecipeStep{}, err
+		}
+		step.Fallback = &fb
+	}
+	return step, nil
+}
+
+func (p RecipeParam) paramType() string {
+	if t := strings.ToLower(strings.TrimSpace(p.Type)); t != "" {
+		return t
+	}
+	return "string"
+}
+
+func (p RecipeParam) check(name string, v any) error {
+	want := p.paramType()
+	got := typeOfValue(v)
+	if got == want || (want == "number" && got == "integer") {
+		return nil
+	}
+	return fmt.Errorf("param %s must be %s, got %s", name, want, got)
+}
+
+// typeOfValue returns the recipe param type of a JSON or YAML value.
+func typeOfValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64:
+		return "integer"
+	case float64:
+		if x == math.Trunc(x) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// asParam reports whether v is a {"$param": "name"} placeholder.
+func asParam(v any) (string, bool) {
+	m, ok := v.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	name, ok := m[ParamKey].(string)
+	return name, ok
+}
+
+// walkParams calls fn for every placeholder in v.
+func walkParams(v any, fn func(name string)) {
+	if name, ok := asParam(v); ok {
+		fn(name)
+		return
+	}
+	switch x := v.(type) {
+	case string:
+		for _, m := range paramTemplateRe.FindAllStringSubmatch(x, -1) {
+			fn(m[1])
+		}
+	case map[string]any:
+		for _, e := range x {
+			walkParams(e, fn)
+		}
+	case []any:
+		for _, e := range x {
+			walkParams(e, fn)
+		}
+	}
+}
+
+// fillParams replaces placeholders in v. keep is false for a {"$param"} of an omitted param.
+func fillParams(v any, values map[string]any) (any, bool) {
+	if name, ok := asParam(v); ok {
+		val, ok := values[name]
+		return val, ok
+	}
+	switch x := v.(type) {
+	case string:
+		return paramTemplateRe.ReplaceAllStringFunc(x, func(m string) string {
+			name := paramTemplateRe.FindStringSubmatch(m)[1]
+			if val, ok := values[name]; ok {
+				return formatParam(val)
+			}
+			return ""
+		}), true
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			if filled, keep := fillParams(e, values); keep {
+				out[k] = filled
+			}
+		}
+		return out, true
+	case []any:
+		out := make([]any, 0, len(x))
+		for _, e := range x {
+			if filled, keep := fillParams(e, values); keep {
+				out = append(out, filled)
+			}
+		}
+		return out, true
+	default:
+		return v, true
+	}
+}
+
+// templateParams is the inverse of fillParams for NewRecipeFromPlan.
+func templateParams(v any, names []string, values map[string]any) any {
+	for _, name := range names {
+		if equalJSON(v, values[name]) {
+			return map[string]any{ParamKey: name}
+		}
+	}
+	switch x := v.(type) {
+	case string:
+		for _, name := range names {
+			// Shorter values (e.g. "1") would match by accident.
+			if s, ok := values[name].(string); ok && len(s) >= 3 {
+				x = strings.ReplaceAll(x, s, "{{"+name+"}}")
+			}
+		}
+		return x
+	case map[string]any:
+		for k, e := range x {
+			x[k] = templateParams(e, names, values)
+		}
+		return x
+	case []any:
+		for i, e := range x {
+			x[i] = templateParams(e, names, values)
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+func formatParam(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+}
+
+func equalJSON(a, b any) bool {
+	ab, err1 := json.Marshal(a)
+	bb, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(ab) == string(bb)
+}
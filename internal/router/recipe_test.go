@@ -0,0 +1,104 @@
+package router
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRecipeExpandFromYAML(t *testing.T) {
+	var r Recipe
+	err := yaml.Unmarshal([]byte(`
+name: pr-triage
+params:
+  repo: {type: string, required: true}
+  number: {type: integer, required: true}
+  jql_limit: {type: integer, default: 20}
+  label: {type: string}
+steps:
+  - name: get_pull_request_checks
+    source: local
+    args: {repo: {$param: repo}, number: {$param: number}, label: {$param: label}}
+  - name: jira_search_issues
+    source: local
+    args: {jql: "text ~ \"{{repo}}#{{number}}\"", maxResults: {$param: jql_limit}}
+`), &r)
+	if err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	steps, err := r.Expand(map[string]any{"repo": "org/api", "number": float64(42)})
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	var first, second map[string]any
+	_ = json.Unmarshal(steps[0].Args, &first)
+	_ = json.Unmarshal(steps[1].Args, &second)
+	if first["repo"] != "org/api" || first["number"] != float64(42) {
+		t.Fatalf("unexpected first args: %v", first)
+	}
+	if _, ok := first["label"]; ok {
+		t.Fatalf("omitted optional param must drop the arg: %v", first)
+	}
+	if second["jql"] != `text ~ "org/api#42"` || second["maxResults"] != float64(20) {
+		t.Fatalf("unexpected second args: %v", second)
+	}
+
+	for _, bad := range []map[string]any{
+		{"repo": "org/api"},                      // missing number
+		{"repo": "org/api", "number": "42"},      // wrong type
+		{"repo": "org/api", "number": 1.5},       // not an integer
+		{"repo": "org/api", "number": 1, "x": 1}, // unknown param
+	} {
+		if _, err := r.Expand(bad); err == nil {
+			t.Fatalf("expected error for params %v", bad)
+		}
+	}
+}
+
+func TestRecipeValidateRejectsUndeclaredParams(t *testing.T) {
+	r := Recipe{Name: "x", Steps: []RecipeStep{{Name: "search_tools", Source: "local", Args: map[string]any{"query": "{{q}}"}}}}
+	if err := r.Validate(); err == nil || !strings.Contains(err.Error(), "undeclared param(s): q") {
+		t.Fatalf("expected undeclared param error, got %v", err)
+	}
+	if err := (Recipe{Name: "../etc", Steps: r.Steps}).Validate(); err == nil {
+		t.Fatalf("expected invalid name error")
+	}
+	long := Recipe{Name: "long"}
+	for i := 0; i <= MaxPlanSteps; i++ {
+		long.Steps = append(long.Steps, RecipeStep{Name: "search_tools", Source: "local"})
+	}
+	if err := long.Validate(); err == nil || !strings.Contains(err.Error(), "at most 8") {
+		t.Fatalf("expected too many steps error, got %v", err)
+	}
+}
+
+func TestNewRecipeFromPlan(t *testing.T) {
+	steps := []PlanStep{
+		{Name: "get_pull_request_checks", Source: "local", Args: json.RawMessage(`{"repo":"org/api","number":42}`)},
+		{Name: "jira_search_issues", Source: "local", Args: json.RawMessage(`{"jql":"text ~ \"org/api\"","maxResults":5}`)},
+	}
+	r, err := NewRecipeFromPlan("triage", "", steps, map[string]any{"repo": "org/api", "number": float64(42)})
+	if err != nil {
+		t.Fatalf("NewRecipeFromPlan: %v", err)
+	}
+	if r.Params["number"].Type != "integer" || !r.Params["repo"].Required {
+		t.Fatalf("unexpected params: %+v", r.Params)
+	}
+	if r.Steps[1].Args["jql"] != `text ~ "{{repo}}"` || r.Steps[1].Args["maxResults"] != float64(5) {
+		t.Fatalf("unexpected templated args: %v", r.Steps[1].Args)
+	}
+
+	again, err := r.Expand(map[string]any{"repo": "org/web", "number": float64(7)})
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if string(again[0].Args) != `{"number":7,"repo":"org/web"}` {
+		t.Fatalf("unexpected expanded args: %s", again[0].Args)
+	}
+}
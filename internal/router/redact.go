@@ -0,0 +1,253 @@
+package router
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// RedactionConfig configures the secret/PII redaction applied to every step result
+// (config file `redaction:`; MCP_LENS_REDACTION=off disables it).
+type RedactionConfig struct {
+	Disabled bool `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	// DisableDetectors turns off built-in detectors by name (e.g. email, high_entropy).
+	DisableDetectors []string `yaml:"disable_detectors,omitempty" json:"disable_detectors,omitempty"`
+	// Patterns are additional regexes; matches are replaced with [REDACTED:<name>].
+	Patterns []RedactionPattern `yaml:"patterns,omitempty" json:"patterns,omitempty"`
+}
+
+// RedactionPattern is a named custom detector.
+type RedactionPattern struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+}
+
+type redactDetector struct {
+	name string
+	re   *regexp.Regexp
+	// group, when > 0, is the submatch replaced (the rest of the match, e.g. "password=", is kept).
+	group int
+}
+
+// builtinRedactDetectors run in order; more specific token formats come before the generic ones.
+var builtinRedactDetectors = []redactDetector{
+	{name: "private_key", re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{name: "github_token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{name: "atlassian_token", re: regexp.MustCompile(`\bATATT3[A-Za-z0-9_\-=]{20,}`)},
+	{name: "aws_access_key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "slack_token", re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{name: "bearer", re: regexp.MustCompile(`(?i)\b(?:bearer|basic)\s+([A-Za-z0-9._~+/-]{16,}=*)`), group: 1},
+	{name: "password", re: regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|client_secret|api[_-]?key|access[_-]?token|auth[_-]?token|aws_secret_access_key)\b["']?\s*[:=]\s*("[^"\s]+"|'[^'\s]+'|[^\s"',;]+)`), group: 1},
+	{name: "email", re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`)},
+}
+
+// highEntropyRe finds candidate secrets for the entropy check: long unbroken words. Slashes and
+// dashes are word breaks so URL paths and slugs are not candidates.
+var highEntropyRe = regexp.MustCompile(`[A-Za-z0-9+_=]{32,}`)
+
+// sensitiveKeys are object keys whose string values are always redacted.
+var sensitiveKeys = map[string]bool{
+	"password": true, "passwd": true, "secret": true, "client_secret": true, "token": true,
+	"access_token": true, "refresh_token": true, "api_key": true, "apikey": true,
+	"private_key": true, "authorization": true,
+}
+
+// Redactor replaces secrets and PII in step results.
+type Redactor struct {
+	detectors   []redactDetector
+	highEntropy bool
+}
+
+// RedactionConfigFromEnv returns the default config; MCP_LENS_REDACTION=off (or 0/false) disables redaction.
+func RedactionConfigFromEnv() RedactionConfig {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("MCP_LENS_REDACTION"))) {
+	case "off", "0", "false", "no":
+		return RedactionConfig{Disabled: true}
+	}
+	return RedactionConfig{}
+}
+
+// NewRedactor builds a redactor; it returns nil (no redaction) when cfg is disabled.
+func NewRedactor(cfg RedactionConfig) (*Redactor, error) {
+	if cfg.Disabled {
+		return nil, nil
+	}
+	off := map[string]bool{}
+	known := map[string]bool{"high_entropy": true}
+	for _, d := range builtinRedactDetectors {
+		known[d.name] = true
+	}
+	for _, name := range cfg.DisableDetectors {
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			return nil, fmt.Errorf("redaction: unknown detector %q", name)
+		}
+		off[name] = true
+	}
+	r := &Redactor{highEntropy: !off["high_entropy"]}
+	for _, d := range builtinRedactDetectors {
+		if !off[d.name] {
+			r.detectors = append(r.detectors, d)
+		}
+	}
+	for _, p := range cfg.Patterns {
+		if strings.TrimSpace(p.Name) == "" {
+			return nil, fmt.Errorf("redaction: pattern name is required")
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil || p.Pattern == "" {
+			if err == nil {
+				err = fmt.Errorf("empty pattern")
+			}
+			return nil, fmt.Errorf("redaction pattern %s: %w", p.Name, err)
+		}
+		r.detectors = append(r.detectors, redactDetector{name: p.Name, re: re})
+	}
+	return r, nil
+}
+
+// RedactValue returns a copy of a JSON value with its strings redacted, and the number of
+// redactions per detector. v itself is not modified.
+func (r *Redactor) RedactValue(v any) (any, map[string]int) {
+	if r == nil {
+		return v, nil
+	}
+	counts := map[string]int{}
+	out := r.redactValue(v, counts)
+	if len(counts) == 0 {
+		counts = nil
+	}
+	return out, counts
+}
+
+func (r *Redactor) redactValue(v any, counts map[string]int) any {
+	switch vv := v.(type) {
+	case string:
+		return r.redactString(vv, counts)
+	case map[string]any:
+		out := make(map[string]any, len(vv))
+		for k, val := range vv {
+			if s, ok := val.(string); ok && s != "" && sensitiveKeys[strings.ToLower(k)] && !strings.HasPrefix(s, "[REDACTED") {
+				out[k] = "[REDACTED:secret_field]"
+				counts["secret_field"]++
+				continue
+			}
+			out[k] = r.redactValue(val, counts)
+		}
+		return out
+	case []any:
+		out := make([]any, len(vv))
+		for i, it := range vv {
+			out[i] = r.redactValue(it, counts)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// RedactText redacts a text payload (e.g. logs) and returns the number of redactions per detector.
+func (r *Redactor) RedactText(s string) (string, map[string]int) {
+	if r == nil {
+		return s, nil
+	}
+	counts := map[string]int{}
+	s = r.redactString(s, counts)
+	if len(counts) == 0 {
+		counts = nil
+	}
+	return s, counts
+}
+
+func (r *Redactor) redactString(s string, counts map[string]int) string {
+	for _, d := range r.detectors {
+		repl := "[REDACTED:" + d.name + "]"
+		if d.group == 0 {
+			s = d.re.ReplaceAllStringFunc(s, func(string) string {
+				counts[d.name]++
+				return repl
+			})
+			continue
+		}
+		s = replaceSubmatch(s, d.re, d.group, func(m string) string {
+			if strings.HasPrefix(strings.Trim(m, `"'`), "[REDACTED") {
+				return m
+			}
+			counts[d.name]++
+			return repl
+		})
+	}
+	if r.highEntropy {
+		s = highEntropyRe.ReplaceAllStringFunc(s, func(m string) string {
+			if !looksLikeSecret(m) {
+				return m
+			}
+			counts["high_entropy"]++
+			return "[REDACTED:high_entropy]"
+		})
+	}
+	return s
+}
+
+// replaceSubmatch replaces submatch group of every match of re with f(submatch).
+func replaceSubmatch(s string, re *regexp.Regexp, group int, f func(string) string) string {
+	idx := re.FindAllStringSubmatchIndex(s, -1)
+	if len(idx) == 0 {
+		return s
+	}
+	var sb strings.Builder
+	last := 0
+	for _, m := range idx {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 {
+			continue
+		}
+		sb.WriteString(s[last:start])
+		sb.WriteString(f(s[start:end]))
+		last = end
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// looksLikeSecret reports whether a long word is a random token: mixed case and digits, not hex
+// (commit SHAs, digests), and a Shannon entropy of at least 4.3 bits per character.
+func looksLikeSecret(s string) bool {
+	var upper, lower, digit bool
+	hex := true
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			hex = false
+		}
+	}
+	if hex || !upper || !lower || !digit || strings.HasPrefix(s, "[REDACTED") {
+		return false
+	}
+	return shannonEntropy(s) >= 4.3
+}
+
+func shannonEntropy(s string) float64 {
+	freq := map[rune]float64{}
+	n := 0.0
+	for _, c := range s {
+		freq[c]++
+		n++
+	}
+	var h float64
+	for _, f := range freq {
+		p := f / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
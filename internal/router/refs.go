@@ -0,0 +1,165 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RefKey marks a step output reference inside PlanStep.Args:
+//
+//	{"sha": {"$ref": "steps[0].result.head.sha"}}
+//	{"sha": {"$ref": "/steps/0/result/head/sha"}}
+//
+// The object is replaced by the referenced value before the step runs.
+// Indices refer to steps of the same plan and must point at earlier steps. In iterative mode
+// executed_steps[N] of earlier rounds is steps[N], and a round's steps are numbered after them.
+const RefKey = "$ref"
+
+// stepRef is a parsed reference; ValidatePlan substitutes it for $ref objects so that
+// argument constraints on referenced values are deferred to execution time.
+type stepRef struct {
+	raw  string
+	step int
+	segs []pathSeg // path inside the step result (empty for the whole result)
+}
+
+// parseStepRef parses "steps[N].result[.path]" (or the JSON pointer form).
+func parseStepRef(s string) (stepRef, error) {
+	segs, err := parsePath(s)
+	if err != nil {
+		return stepRef{}, fmt.Errorf("invalid %s %q: %w", RefKey, s, err)
+	}
+	if len(segs) < 3 || segs[0].field != "steps" || segs[1].index == nil || segs[2].field != "result" {
+		return stepRef{}, fmt.Errorf("invalid %s %q: must point into steps[N].result", RefKey, s)
+	}
+	return stepRef{raw: s, step: *segs[1].index, segs: segs[3:]}, nil
+}
+
+// asRef reports whether v is a {"$ref": "..."} object.
+func asRef(v any) (string, bool) {
+	m, ok := v.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	s, ok := m[RefKey].(string)
+	return s, ok
+}
+
+// walkRefs replaces every $ref object in v by fn(ref).
+func walkRefs(v any, fn func(ref stepRef) (any, error)) (any, error) {
+	if s, ok := asRef(v); ok {
+		ref, err := parseStepRef(s)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ref)
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, it := range t {
+			r, err := walkRefs(it, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(t))
+		for i, it := range t {
+			r, err := walkRefs(it, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// StepRefs returns the (deduplicated) indices of plan steps referenced by args.
+func StepRefs(args json.RawMessage) ([]int, error) {
+	var v any
+	if err := json.Unmarshal(args, &v); err != nil {
+		return nil, nil
+	}
+	seen := map[int]bool{}
+	var out []int
+	_, err := walkRefs(v, func(ref stepRef) (any, error) {
+		if !seen[ref.step] {
+			seen[ref.step] = true
+			out = append(out, ref.step)
+		}
+		return nil, nil
+	})
+	return out, err
+}
+
+// ResolveStepRefs replaces $ref objects in args with values taken from results (keyed by step index).
+func ResolveStepRefs(args json.RawMessage, results map[int]any) (json.RawMessage, error) {
+	var v any
+	if err := json.Unmarshal(args, &v); err != nil {
+		return nil, err
+	}
+	resolved, err := walkRefs(v, func(ref stepRef) (any, error) {
+		res, ok := results[ref.step]
+		if !ok {
+			return nil, fmt.Errorf("%s to steps[%d]: step has no result", RefKey, ref.step)
+		}
+		val, ok := getSegs(res, ref.segs)
+		if !ok {
+			return nil, fmt.Errorf("%s %q: no value at path", RefKey, ref.raw)
+		}
+		return val, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+// deferRefs replaces $ref objects in args by stepRef values (see ArgConstraint.check).
+func deferRefs(args map[string]any) (map[string]any, error) {
+	v, err := walkRefs(args, func(ref stepRef) (any, error) { return ref, nil })
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]any), nil
+}
+
+// placeholderRefs replaces $ref objects in args by null and returns the JSON pointers of the
+// replaced values, so the rest of the args can be checked against the tool schema.
+func placeholderRefs(args map[string]any) (map[string]any, []string) {
+	var ptrs []string
+	var walk func(v any, ptr string) any
+	walk = func(v any, ptr string) any {
+		if _, ok := asRef(v); ok {
+			ptrs = append(ptrs, ptr)
+			return nil
+		}
+		switch t := v.(type) {
+		case map[string]any:
+			out := make(map[string]any, len(t))
+			for k, it := range t {
+				out[k] = walk(it, ptr+"/"+pointerEscaper.Replace(k))
+			}
+			return out
+		case []any:
+			out := make([]any, len(t))
+			for i, it := range t {
+				out[i] = walk(it, ptr+"/"+strconv.Itoa(i))
+			}
+			return out
+		default:
+			return v
+		}
+	}
+	return walk(args, "").(map[string]any), ptrs
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
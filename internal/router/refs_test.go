@@ -0,0 +1,99 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestResolveStepRefs(t *testing.T) {
+	results := map[int]any{
+		0: map[string]any{"head": map[string]any{"sha": "abc123"}, "files": []any{map[string]any{"name": "a.go"}}},
+	}
+	args := json.RawMessage(`{"sha":{"$ref":"steps[0].result.head.sha"},"file":{"$ref":"/steps/0/result/files/0/name"},"keep":{"$ref":"x","other":1},"n":2}`)
+	out, err := ResolveStepRefs(args, results)
+	if err != nil {
+		t.Fatalf("ResolveStepRefs: %v", err)
+	}
+	var got map[string]any
+	_ = json.Unmarshal(out, &got)
+	if got["sha"] != "abc123" || got["file"] != "a.go" || got["n"] != float64(2) {
+		t.Fatalf("unexpected resolution: %s", out)
+	}
+	if keep, _ := got["keep"].(map[string]any); keep["$ref"] != "x" {
+		t.Fatalf("objects with extra keys are not references: %s", out)
+	}
+
+	for _, bad := range []string{
+		`{"x":{"$ref":"steps[0].result.head.missing"}}`,
+		`{"x":{"$ref":"steps[1].result"}}`,
+		`{"x":{"$ref":"steps[0].args"}}`,
+	} {
+		if _, err := ResolveStepRefs(json.RawMessage(bad), results); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestValidatePlanChecksStepRefs(t *testing.T) {
+	policy := DefaultPolicy()
+	catalog := []ToolCatalogItem{
+		{Name: "get_pull_request_details", Source: "local"},
+		{Name: "get_file_at_ref", Source: "local", InputSchema: json.RawMessage(`{"type":"object","properties":{"ref":{"type":"string"}},"required":["ref"]}`)},
+	}
+	step0 := PlanStep{Name: "get_pull_request_details", Source: "local", Args: json.RawMessage(`{"repo":"o/r","number":1}`)}
+	step1 := PlanStep{Name: "get_file_at_ref", Source: "local", Args: json.RawMessage(`{"ref":{"$ref":"steps[0].result.head.sha"}}`)}
+
+	if err := ValidatePlan(ModelPlan{Steps: []PlanStep{step0, step1}}, policy, catalog, 5); err != nil {
+		t.Fatalf("expected backward reference to validate: %v", err)
+	}
+	if err := ValidatePlan(ModelPlan{Steps: []PlanStep{step1, step0}}, policy, catalog, 5); err == nil {
+		t.Fatalf("expected error for reference to a later step")
+	}
+	selfRef := PlanStep{Name: "get_file_at_ref", Source: "local", Args: json.RawMessage(`{"ref":{"$ref":"steps[0].result"}}`)}
+	if err := ValidatePlan(ModelPlan{Steps: []PlanStep{selfRef}}, policy, catalog, 5); err == nil {
+		t.Fatalf("expected error for self reference")
+	}
+}
+
+func TestStepRefsDefersArgConstraints(t *testing.T) {
+	policy, err := NewPolicy(PolicyConfig{Rules: []PolicyRule{{
+		Tools:  []string{"get_file_at_ref"},
+		Effect: "allow",
+		Args:   map[string]ArgConstraint{"repo": {Pattern: "myorg/.+"}},
+	}}})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	catalog := []ToolCatalogItem{{Name: "get_pull_request_details", Source: "local"}, {Name: "get_file_at_ref", Source: "local"}}
+	plan := ModelPlan{Steps: []PlanStep{
+		{Name: "get_pull_request_details", Source: "local", Args: json.RawMessage(`{"repo":"myorg/r","number":1}`)},
+		{Name: "get_file_at_ref", Source: "local", Args: json.RawMessage(`{"repo":{"$ref":"steps[0].result.base.repo"}}`)},
+	}}
+	if err := ValidatePlan(plan, policy, catalog, 5); err != nil {
+		t.Fatalf("expected referenced arg to be checked at execution time: %v", err)
+	}
+}
+
+func TestValidatePlanChecksSchemaOfStepsWithRefs(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","properties":{"repo":{"type":"string"},"ref":{"type":"string"},"path":{"type":"string"}},"required":["repo","ref","path"],"additionalProperties":false}`)
+	catalog := []ToolCatalogItem{
+		{Name: "get_pull_request_details", Source: "local"},
+		{Name: "get_file_at_ref", Source: "local", InputSchema: schema},
+	}
+	step0 := PlanStep{Name: "get_pull_request_details", Source: "local", Args: json.RawMessage(`{"repo":"o/r","number":1}`)}
+	for _, tc := range []struct {
+		args string
+		ok   bool
+	}{
+		{`{"repo":"o/r","ref":{"$ref":"steps[0].result.head.sha"},"path":"a.go"}`, true},
+		{`{"repo":"o/r","ref":{"$ref":"steps[0].result.head.sha"}}`, false},
+		{`{"repo":"o/r","ref":{"$ref":"steps[0].result.head.sha"},"path":7}`, false},
+		{`{"repo":"o/r","ref":{"$ref":"steps[0].result.head.sha"},"path":"a.go","extra":true}`, false},
+	} {
+		step1 := PlanStep{Name: "get_file_at_ref", Source: "local", Args: json.RawMessage(tc.args)}
+		err := ValidatePlan(ModelPlan{Steps: []PlanStep{step0, step1}}, DefaultPolicy(), catalog, 5)
+		if (err == nil) != tc.ok {
+			t.Fatalf("args %s: ok=%v, got err %v", tc.args, tc.ok, err)
+		}
+	}
+}
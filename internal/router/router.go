@@ -7,7 +7,7 @@ import (
 	"strings"
 )
 
-func Plan(ctx context.Context, cl *OpenRouterClient, userInput string, userCtx map[string]any, catalog []ToolCatalogItem, maxSteps int) (ModelPlan, []byte, error) {
+func Plan(ctx context.Context, cl LLMClient, userInput string, userCtx map[string]any, catalog []ToolCatalogItem, maxSteps int) (ModelPlan, []byte, error) {
 	system := BuildPlanSystemPrompt()
 	user, err := BuildPlanUserPrompt(userInput, userCtx, catalog, maxSteps)
 	if err != nil {
@@ -18,19 +18,78 @@ func Plan(ctx context.Context, cl *OpenRouterClient, userInput string, userCtx m
 	if err != nil {
 		return ModelPlan{}, nil, err
 	}
+	plan, err := parsePlan(raw, finish)
+	return plan, raw, err
+}
+
+// PlanNext asks the planner for the next steps of an iterative query, feeding back the steps executed so far.
+// It also returns an estimate of the tokens spent on the call (prompt + completion).
+func PlanNext(ctx context.Context, cl LLMClient, userInput string, userCtx map[string]any, catalog []ToolCatalogItem, history []ExecutedStep, remainingSteps int) (ModelPlan, []byte, int, error) {
+	system := BuildPlanSystemPrompt()
+	user, err := BuildNextStepsUserPrompt(userInput, userCtx, catalog, history, remainingSteps)
+	if err != nil {
+		return ModelPlan{}, nil, 0, err
+	}
+
+	raw, finish, err := cl.ChatCompletionJSONWithFinishReason(ctx, system, user)
+	tokens := EstimateTokens(system, user, string(raw))
+	if err != nil {
+		return ModelPlan{}, nil, tokens, err
+	}
+	plan, err := parsePlan(raw, finish)
+	return plan, raw, tokens, err
+}
+
+// RepairPlan sends a plan rejected by ValidatePlan back to the model together with the validation error.
+// It also returns an estimate of the tokens spent on the call.
+func RepairPlan(ctx context.Context, cl LLMClient, userInput string, userCtx map[string]any, catalog []ToolCatalogItem, maxSteps int, history []ExecutedStep, rejected ModelPlan, validationErr error) (ModelPlan, []byte, int, error) {
+	system := BuildPlanSystemPrompt()
+	user, err := BuildRepairUserPrompt(userInput, userCtx, catalog, maxSteps, history, rejected, validationErr)
+	if err != nil {
+		return ModelPlan{}, nil, 0, err
+	}
 
+	raw, finish, err := cl.ChatCompletionJSONWithFinishReason(ctx, system, user)
+	tokens := EstimateTokens(system, user, string(raw))
+	if err != nil {
+		return ModelPlan{}, nil, tokens, err
+	}
+	plan, err := parsePlan(raw, finish)
+	return plan, raw, tokens, err
+}
+
+func parsePlan(raw []byte, finish string) (ModelPlan, error) {
 	var plan ModelPlan
 	if err := json.Unmarshal(raw, &plan); err != nil {
 		// If model truncated, surface a better error.
 		if finish == "length" {
-			return ModelPlan{}, raw, fmt.Errorf("router plan truncated (finish_reason=length); try lower max_steps or shorter input/context")
+			return ModelPlan{}, fmt.Errorf("router plan truncated (finish_reason=length); try lower max_steps or shorter input/context")
 		}
-		return ModelPlan{}, raw, fmt.Errorf("failed to parse router plan JSON: %w", err)
+		return ModelPlan{}, fmt.Errorf("failed to parse router plan JSON: %w", err)
 	}
-	return plan, raw, nil
+	return plan, nil
 }
 
+// EstimateTokens approximates the token count of LLM input/output (~4 bytes per token).
+func EstimateTokens(parts ...string) int {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	return (n + 3) / 4
+}
+
+// MaxPlanSteps is the largest max_steps accepted by planner and executor mode (and so the
+// largest recipe).
+const MaxPlanSteps = 8
+
 func ValidatePlan(plan ModelPlan, policy Policy, catalog []ToolCatalogItem, maxSteps int) error {
+	return ValidatePlanFrom(plan, policy, catalog, maxSteps, 0)
+}
+
+// ValidatePlanFrom validates a plan whose first step is steps[first]: in iterative mode the steps of
+// a round are numbered after the executed steps of earlier rounds, which $ref may point at too.
+func ValidatePlanFrom(plan ModelPlan, policy Policy, catalog []ToolCatalogItem, maxSteps int, first int) error {
 	if len(plan.Steps) == 0 {
 		return fmt.Errorf("plan has no steps")
 	}
@@ -45,38 +104,111 @@ func ValidatePlan(plan ModelPlan, policy Policy, catalog []ToolCatalogItem, maxS
 		schemas[t.Name] = t.InputSchema
 	}
 
-	for _, s := range plan.Steps {
-		if s.Name == "" {
-			return fmt.Errorf("step name is required")
-		}
-		// `query`/`router` are entrypoints for MCP clients, not callable steps.
-		// If a plan contains them, it indicates recursive planning (often from chaining mcp-lens instances).
-		if s.Name == "query" || s.Name == "router" {
-			return fmt.Errorf("invalid plan: step uses reserved tool %q (entrypoint); remove it from the tool catalog/upstreams", s.Name)
+	for i, s := range plan.Steps {
+		if err := validateStep(first+i, s, policy, known, schemas); err != nil {
+			return &PlanStepError{Index: i, Step: s, Err: err}
 		}
-		if s.Source != "local" && s.Source != "upstream" {
-			return fmt.Errorf("invalid step source: %s", s.Source)
+		if err := validateOnError(first+i, s, policy, known, schemas); err != nil {
+			return &PlanStepError{Index: i, Step: s, Err: err}
 		}
-		if _, ok := known[s.Name]; !ok {
-			// In strict mode we reject unknown tools, because tool list is hidden from clients.
-			return fmt.Errorf("unknown tool: %s", s.Name)
+	}
+	return nil
+}
+
+// validateOnError checks on_error and the fallback step, which must be a read-only step without its own on_error.
+func validateOnError(i int, s PlanStep, policy Policy, known map[string]struct{}, schemas map[string]json.RawMessage) error {
+	switch s.OnError {
+	case "", OnErrorFail, OnErrorContinue:
+		if s.Fallback != nil {
+			return fmt.Errorf("step %s: fallback requires on_error=%s", s.Name, OnErrorFallback)
 		}
-		if !policy.IsAllowed(s.Source, s.Name) {
-			return fmt.Errorf("tool blocked by policy: %s", s.Name)
+		return nil
+	case OnErrorFallback:
+	default:
+		return fmt.Errorf("step %s: invalid on_error %q (want fail, continue or fallback)", s.Name, s.OnError)
+	}
+	fb := s.Fallback
+	if fb == nil {
+		return fmt.Errorf("step %s: on_error=fallback requires a fallback step", s.Name)
+	}
+	if fb.OnError != "" || fb.Fallback != nil {
+		return fmt.Errorf("step %s: fallback step cannot set on_error or fallback", s.Name)
+	}
+	if IsConfirmableWrite(fb.Source, fb.Name) {
+		return fmt.Errorf("step %s: fallback step %s must not be a write tool", s.Name, fb.Name)
+	}
+	if err := validateStep(i, *fb, policy, known, schemas); err != nil {
+		return fmt.Errorf("step %s: fallback: %w", s.Name, err)
+	}
+	return nil
+}
+
+// PlanStepError is returned by ValidatePlan when one step is invalid.
+type PlanStepError struct {
+	Index int
+	Step  PlanStep
+	Err   error
+}
+
+func (e *PlanStepError) Error() string { return e.Err.Error() }
+func (e *PlanStepError) Unwrap() error { return e.Err }
+
+func validateStep(i int, s PlanStep, policy Policy, known map[string]struct{}, schemas map[string]json.RawMessage) error {
+	if s.Name == "" {
+		return fmt.Errorf("step name is required")
+	}
+	// `query`/`router` are entrypoints for MCP clients, not callable steps.
+	// If a plan contains them, it indicates recursive planning (often from chaining mcp-lens instances).
+	if s.Name == "query" || s.Name == "router" {
+		return fmt.Errorf("invalid plan: step uses reserved tool %q (entrypoint); remove it from the tool catalog/upstreams", s.Name)
+	}
+	if s.Source != "local" && s.Source != "upstream" {
+		return fmt.Errorf("invalid step source: %s", s.Source)
+	}
+	if _, ok := known[s.Name]; !ok {
+		// In strict mode we reject unknown tools, because tool list is hidden from clients.
+		return fmt.Errorf("unknown tool: %s", s.Name)
+	}
+	if !policy.IsAllowed(s.Source, s.Name) {
+		return fmt.Errorf("tool blocked by policy: %s", s.Name)
+	}
+	if err := s.Output.Validate(); err != nil {
+		return fmt.Errorf("output for %s: %w", s.Name, err)
+	}
+	// args must be an object
+	var obj map[string]any
+	if err := json.Unmarshal(s.Args, &obj); err != nil {
+		return fmt.Errorf("args for %s must be a JSON object", s.Name)
+	}
+	refs, err := StepRefs(s.Args)
+	if err != nil {
+		return fmt.Errorf("args for %s: %w", s.Name, err)
+	}
+	for _, r := range refs {
+		if r >= i {
+			return fmt.Errorf("args for %s: %s to steps[%d] must point at an earlier step", s.Name, RefKey, r)
 		}
-		// args must be an object
-		var obj map[string]any
-		if err := json.Unmarshal(s.Args, &obj); err != nil {
-			return fmt.Errorf("args for %s must be a JSON object", s.Name)
+	}
+	if len(refs) > 0 {
+		// Referenced values are unknown until execution: constraints on them are checked after
+		// resolution (executePlan re-checks the policy), the rest of the args against the schema.
+		deferred, err := deferRefs(obj)
+		if err != nil {
+			return err
 		}
-		if err := validateArgsAgainstSchema(s.Name, schemas[s.Name], obj); err != nil {
+		if err := policy.Check(s.Source, s.Name, deferred); err != nil {
 			return err
 		}
+		placeheld, skip := placeholderRefs(obj)
+		return validateArgsSkipping(s.Name, schemas[s.Name], placeheld, skip)
 	}
-	return nil
+	if err := policy.Check(s.Source, s.Name, obj); err != nil {
+		return err
+	}
+	return validateArgsAgainstSchema(s.Name, schemas[s.Name], obj)
 }
 
-func Summarize(ctx context.Context, cl *OpenRouterClient, userInput string, res RouterResult) (string, error) {
+func Summarize(ctx context.Context, cl LLMClient, userInput string, res RouterResult) (string, error) {
 	system := BuildSummarizeSystemPrompt()
 	user, err := BuildSummarizeUserPrompt(userInput, res)
 	if err != nil {
@@ -2,6 +2,7 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 )
 
@@ -60,3 +61,21 @@ func TestValidatePlanValidatesArgsAgainstSchema(t *testing.T) {
 		t.Fatalf("expected success, got: %v", err)
 	}
 }
+
+func TestValidatePlanReportsOffendingStep(t *testing.T) {
+	policy := DefaultPolicy()
+	catalog := []ToolCatalogItem{{Name: "get_pull_request_details", Source: "local"}}
+
+	plan := ModelPlan{Steps: []PlanStep{
+		{Name: "get_pull_request_details", Source: "local", Args: json.RawMessage(`{}`)},
+		{Name: "no_such_tool", Source: "local", Args: json.RawMessage(`{}`)},
+	}}
+	err := ValidatePlan(plan, policy, catalog, 5)
+	var stepErr *PlanStepError
+	if !errors.As(err, &stepErr) || stepErr.Index != 1 || stepErr.Step.Name != "no_such_tool" {
+		t.Fatalf("expected PlanStepError for step 1, got %v", err)
+	}
+	if err.Error() != "unknown tool: no_such_tool" {
+		t.Fatalf("error message changed: %q", err.Error())
+	}
+}
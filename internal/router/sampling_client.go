@@ -0,0 +1,97 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+// CreateMessageFunc sends sampling/createMessage to the MCP client that issued the current request.
+type CreateMessageFunc func(ctx context.Context, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error)
+
+// SamplingClient delegates planning and summarizing to the MCP client's own LLM (MCP sampling).
+// Configured model names are passed as model hints; the client picks the actual model.
+type SamplingClient struct {
+	create           CreateMessageFunc
+	model            string
+	summaryModel     string
+	maxTokensPlan    int
+	maxTokensSummary int
+	price            TokenPrice
+}
+
+// NewSamplingClient creates a client that issues completions through create.
+// Only the model and max token settings of cfg are used.
+func NewSamplingClient(create CreateMessageFunc, cfg LLMConfig) *SamplingClient {
+	return &SamplingClient{
+		create:           create,
+		model:            cfg.Model,
+		summaryModel:     cfg.SummaryModel,
+		maxTokensPlan:    cfg.MaxTokensPlan,
+		maxTokensSummary: cfg.MaxTokensSummary,
+		price:            cfg.price(),
+	}
+}
+
+func (cl *SamplingClient) sample(ctx context.Context, system string, user string, kind string) (string, string, error) {
+	maxTokens := cl.maxTokensPlan
+	if kind == "summary" {
+		maxTokens = cl.maxTokensSummary
+	}
+	if maxTokens <= 0 {
+		// sampling/createMessage requires maxTokens.
+		maxTokens = anthropicDefaultMaxTokens
+	}
+	temperature := 0.0
+	params := mcp.CreateMessageParams{
+		Messages:     []mcp.SamplingMessage{{Role: "user", Content: mcp.ContentBlock{Type: "text", Text: user}}},
+		SystemPrompt: system,
+		Temperature:  &temperature,
+		MaxTokens:    maxTokens,
+	}
+	if model := modelFor(kind, cl.model, cl.summaryModel); model != "" {
+		params.ModelPreferences = &mcp.ModelPreferences{Hints: []mcp.ModelHint{{Name: model}}}
+	}
+
+	start := time.Now()
+	res, err := cl.create(ctx, params)
+	if err != nil {
+		return "", "", err
+	}
+	// sampling/createMessage reports the model but no token counts.
+	reportUsage(ctx, LLMUsage{
+		Kind:             kind,
+		Provider:         "sampling",
+		Model:            res.Model,
+		PromptTokens:     EstimateTokens(system, user),
+		CompletionTokens: EstimateTokens(res.Content.Text),
+		Estimated:        true,
+	}, cl.price, start)
+	content := ""
+	if res.Content.Type == "" || res.Content.Type == "text" {
+		content = strings.TrimSpace(res.Content.Text)
+	}
+	if content == "" {
+		return "", "", fmt.Errorf("sampling: %w", errEmptyContent)
+	}
+	finish := res.StopReason
+	if finish == "maxTokens" {
+		finish = "length"
+	}
+	return content, finish, nil
+}
+
+func (cl *SamplingClient) ChatCompletionJSONWithFinishReason(ctx context.Context, system string, user string) ([]byte, string, error) {
+	content, finish, err := cl.sample(ctx, system, user, "plan")
+	if err != nil {
+		return nil, finish, err
+	}
+	return extractJSON(content), finish, nil
+}
+
+func (cl *SamplingClient) ChatCompletionTextWithFinishReason(ctx context.Context, system string, user string) (string, string, error) {
+	return cl.sample(ctx, system, user, "summary")
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/santhosh-tekuri/jsonschema/v5"
@@ -46,6 +47,12 @@ func firstLeafValidationError(err *jsonschema.ValidationError) *jsonschema.Valid
 }
 
 func validateArgsAgainstSchema(toolName string, schema json.RawMessage, args any) error {
+	return validateArgsSkipping(toolName, schema, args, nil)
+}
+
+// validateArgsSkipping is validateArgsAgainstSchema ignoring errors on the values at the
+// skip pointers (and below them), e.g. step refs that are only resolved at execution time.
+func validateArgsSkipping(toolName string, schema json.RawMessage, args any, skip []string) error {
 	if len(schema) == 0 {
 		return nil
 	}
@@ -55,6 +62,9 @@ func validateArgsAgainstSchema(toolName string, schema json.RawMessage, args any
 	}
 	if err := s.Validate(args); err != nil {
 		if ve, ok := err.(*jsonschema.ValidationError); ok {
+			if ve = pruneSkipped(ve, skip); ve == nil {
+				return nil
+			}
 			leaf := firstLeafValidationError(ve)
 			loc := leaf.InstanceLocation
 			if loc == "" {
@@ -70,3 +80,35 @@ func validateArgsAgainstSchema(toolName string, schema json.RawMessage, args any
 	}
 	return nil
 }
+
+// pruneSkipped drops validation errors located at or below one of the skip pointers and
+// returns nil when nothing is left. A failed anyOf/oneOf is dropped as soon as one of its
+// branches failed only on skipped values, since that branch may match once they are known.
+func pruneSkipped(ve *jsonschema.ValidationError, skip []string) *jsonschema.ValidationError {
+	if len(skip) == 0 {
+		return ve
+	}
+	if len(ve.Causes) == 0 {
+		for _, p := range skip {
+			if ve.InstanceLocation == p || strings.HasPrefix(ve.InstanceLocation, p+"/") {
+				return nil
+			}
+		}
+		return ve
+	}
+	var kept []*jsonschema.ValidationError
+	for _, c := range ve.Causes {
+		if c = pruneSkipped(c, skip); c != nil {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	if len(kept) < len(ve.Causes) && (strings.HasSuffix(ve.KeywordLocation, "/anyOf") || strings.HasSuffix(ve.KeywordLocation, "/oneOf")) {
+		return nil
+	}
+	out := *ve
+	out.Causes = kept
+	return &out
+}
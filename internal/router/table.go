@@ -0,0 +1,224 @@
+package router
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tabular result formats (query format).
+const (
+	FormatMarkdownTable = "markdown_table"
+	FormatCSV           = "csv"
+	FormatTSV           = "tsv"
+)
+
+// IsTableFormat reports whether format renders step results as tables.
+func IsTableFormat(format string) bool {
+	switch format {
+	case FormatMarkdownTable, FormatCSV, FormatTSV:
+		return true
+	}
+	return false
+}
+
+// TableMime returns the MIME type and file extension of a table format.
+func TableMime(format string) (mime string, ext string) {
+	switch format {
+	case FormatCSV:
+		return "text/csv", "csv"
+	case FormatTSV:
+		return "text/tab-separated-values", "tsv"
+	default:
+		return "text/markdown", "md"
+	}
+}
+
+// defaultColumnOrder puts identifying fields first when no columns are requested.
+var defaultColumnOrder = []string{"key", "id", "number", "name", "title", "summary", "status", "state"}
+
+// TableRows finds the rows of a step result: an array of objects, or the largest array of objects
+// in a top-level field (e.g. issues, files, check_runs); a plain object is a single row.
+func TableRows(v any) ([]map[string]any, bool) {
+	switch vv := v.(type) {
+	case []any:
+		return objectRows(vv)
+	case map[string]any:
+		best := ""
+		var bestRows []map[string]any
+		for k, field := range vv {
+			arr, ok := field.([]any)
+			if !ok {
+				continue
+			}
+			rows, ok := objectRows(arr)
+			if !ok {
+				continue
+			}
+			if bestRows == nil || len(rows) > len(bestRows) || (len(rows) == len(bestRows) && k < best) {
+				best, bestRows = k, rows
+			}
+		}
+		if bestRows != nil {
+			return bestRows, true
+		}
+		return []map[string]any{vv}, true
+	}
+	return nil, false
+}
+
+func objectRows(arr []any) ([]map[string]any, bool) {
+	rows := make([]map[string]any, 0, len(arr))
+	for _, it := range arr {
+		m, ok := it.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		rows = append(rows, m)
+	}
+	return rows, true
+}
+
+// TableColumns returns the requested columns, or by default the flattened (dotted) paths
+// of all rows: identifying fields first, then alphabetical.
+func TableColumns(rows []map[string]any, requested []string) []string {
+	if len(requested) > 0 {
+		out := make([]string, 0, len(requested))
+		for _, c := range requested {
+			if c = strings.TrimSpace(c); c != "" {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+	seen := map[string]bool{}
+	for _, r := range rows {
+		flattenKeys(r, "", seen)
+	}
+	rank := func(c string) int {
+		for i, p := range defaultColumnOrder {
+			if c == p {
+				return i
+			}
+		}
+		return len(defaultColumnOrder)
+	}
+	cols := make([]string, 0, len(seen))
+	for c := range seen {
+		cols = append(cols, c)
+	}
+	sort.Slice(cols, func(i, j int) bool {
+		ri, rj := rank(cols[i]), rank(cols[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return cols[i] < cols[j]
+	})
+	return cols
+}
+
+func flattenKeys(m map[string]any, prefix string, seen map[string]bool) {
+	for k, v := range m {
+		if nested, ok := v.(map[string]any); ok && len(nested) > 0 {
+			flattenKeys(nested, prefix+k+".", seen)
+			continue
+		}
+		seen[prefix+k] = true
+	}
+}
+
+// RenderTable renders rows as a markdown table, CSV or TSV. Columns are paths into each row
+// (same syntax as include_fields); missing values are empty cells.
+func RenderTable(format string, columns []string, rows []map[string]any) (string, error) {
+	cells := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		line := make([]string, len(columns))
+		for i, c := range columns {
+			v, ok, err := getPath(r, c)
+			if err != nil {
+				return "", fmt.Errorf("column %q: %w", c, err)
+			}
+			if ok {
+				line[i] = cellText(v)
+			}
+		}
+		cells = append(cells, line)
+	}
+
+	switch format {
+	case FormatCSV, FormatTSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if format == FormatTSV {
+			w.Comma = '\t'
+		}
+		_ = w.Write(columns)
+		_ = w.WriteAll(cells)
+		if err := w.Error(); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	case FormatMarkdownTable:
+		var sb strings.Builder
+		writeRow := func(vals []string) {
+			sb.WriteString("|")
+			for _, v := range vals {
+				sb.WriteString(" " + markdownCell(v) + " |")
+			}
+			sb.WriteString("\n")
+		}
+		writeRow(columns)
+		sb.WriteString("|")
+		for range columns {
+			sb.WriteString(" --- |")
+		}
+		sb.WriteString("\n")
+		for _, line := range cells {
+			writeRow(line)
+		}
+		return sb.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported table format %q", format)
+	}
+}
+
+// cellText renders a value for one cell: arrays of scalars are joined, other structures are compact JSON.
+func cellText(v any) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(vv)
+	case []any:
+		parts := make([]string, 0, len(vv))
+		for _, it := range vv {
+			switch it.(type) {
+			case map[string]any, []any:
+				b, _ := json.Marshal(vv)
+				return string(b)
+			}
+			parts = append(parts, cellText(it))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		b, err := json.Marshal(vv)
+		if err != nil {
+			return fmt.Sprint(vv)
+		}
+		return string(b)
+	}
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
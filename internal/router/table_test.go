@@ -0,0 +1,49 @@
+package router
+
+import "testing"
+
+func TestRenderTable(t *testing.T) {
+	res := map[string]any{
+		"total": 2.0,
+		"issues": []any{
+			map[string]any{"key": "PAY-1", "fields": map[string]any{"summary": "a | b", "assignee": map[string]any{"name": "ann"}}, "labels": []any{"x", "y"}},
+			map[string]any{"key": "PAY-2", "fields": map[string]any{"summary": "line1\nline2"}},
+		},
+	}
+	rows, ok := TableRows(res)
+	if !ok || len(rows) != 2 {
+		t.Fatalf("expected issue rows, got %v", rows)
+	}
+	cols := TableColumns(rows, nil)
+	want := []string{"key", "fields.assignee.name", "fields.summary", "labels"}
+	if len(cols) != len(want) {
+		t.Fatalf("columns %v", cols)
+	}
+	for i := range want {
+		if cols[i] != want[i] {
+			t.Fatalf("columns %v, want %v", cols, want)
+		}
+	}
+
+	md, err := RenderTable(FormatMarkdownTable, []string{"key", "fields.summary", "labels"}, rows)
+	if err != nil {
+		t.Fatalf("markdown: %v", err)
+	}
+	wantMD := "| key | fields.summary | labels |\n| --- | --- | --- |\n| PAY-1 | a \\| b | x, y |\n| PAY-2 | line1<br>line2 |  |\n"
+	if md != wantMD {
+		t.Fatalf("markdown:\n%s\nwant:\n%s", md, wantMD)
+	}
+
+	csv, err := RenderTable(FormatCSV, []string{"fields.summary", "key"}, rows)
+	if err != nil || csv != "fields.summary,key\na | b,PAY-1\n\"line1\nline2\",PAY-2\n" {
+		t.Fatalf("csv %q (%v)", csv, err)
+	}
+	tsv, err := RenderTable(FormatTSV, []string{"key", "fields.assignee.name"}, rows)
+	if err != nil || tsv != "key\tfields.assignee.name\nPAY-1\tann\nPAY-2\t\n" {
+		t.Fatalf("tsv %q (%v)", tsv, err)
+	}
+
+	if _, ok := TableRows("plain text"); ok {
+		t.Fatalf("expected no rows for a string")
+	}
+}
@@ -0,0 +1,31 @@
+package router
+
+import "fmt"
+
+// TimeoutsConfig sets tool call and query deadlines from the config file (`timeouts:`).
+// Unset fields keep the values from MCP_LENS_TOOL_TIMEOUT_SECONDS, MCP_LENS_TOOL_TIMEOUTS and
+// MCP_LENS_QUERY_TIMEOUT_SECONDS (or the built-in defaults); per-tool entries are added to the env ones.
+type TimeoutsConfig struct {
+	// DefaultSeconds is the per-call timeout of a plan step.
+	DefaultSeconds int `yaml:"default_seconds,omitempty" json:"default_seconds,omitempty"`
+	// Tools overrides the per-call timeout by tool name (e.g. jira_export_tasks: 600).
+	Tools map[string]int `yaml:"tools,omitempty" json:"tools,omitempty"`
+	// QuerySeconds is the overall deadline of one query call.
+	QuerySeconds int `yaml:"query_seconds,omitempty" json:"query_seconds,omitempty"`
+}
+
+// Validate rejects negative or zero per-tool timeouts.
+func (c TimeoutsConfig) Validate() error {
+	if c.DefaultSeconds < 0 {
+		return fmt.Errorf("timeouts.default_seconds must be positive")
+	}
+	if c.QuerySeconds < 0 {
+		return fmt.Errorf("timeouts.query_seconds must be positive")
+	}
+	for name, secs := range c.Tools {
+		if secs <= 0 {
+			return fmt.Errorf("timeouts.tools.%s must be positive", name)
+		}
+	}
+	return nil
+}
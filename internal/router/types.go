@@ -12,6 +12,8 @@ type ToolCatalogItem struct {
 	Category    string          `json:"category,omitempty"`
 	Source      string          `json:"source"` // local|upstream
 	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+	// Stub marks a catalog entry sent to the planner without its schema (see describe_tool).
+	Stub bool `json:"stub,omitempty"`
 }
 
 type PlanStep struct {
@@ -20,26 +22,72 @@ type PlanStep struct {
 	Args          json.RawMessage `json:"args"`
 	Reason        string          `json:"reason,omitempty"`
 	ParallelGroup string          `json:"parallel_group,omitempty"`
+	// OnError is fail (default: stop the plan), continue or fallback (run Fallback instead).
+	OnError  string    `json:"on_error,omitempty"`
+	Fallback *PlanStep `json:"fallback,omitempty"`
+	// Output replaces the query's output options for this step.
+	Output *OutputOptions `json:"output,omitempty"`
 }
 
+// Step failure handling (PlanStep.OnError).
+const (
+	OnErrorFail     = "fail"
+	OnErrorContinue = "continue"
+	OnErrorFallback = "fallback"
+)
+
 type ModelPlan struct {
 	Steps             []PlanStep `json:"steps"`
 	FinalAnswerNeeded bool       `json:"final_answer_needed"`
+	// Done is set by the planner in iterative mode when no more steps are needed.
+	Done bool `json:"done,omitempty"`
 }
 
 type ExecutedStep struct {
-	Name   string          `json:"name"`
-	Source string          `json:"source"`
-	Args   json.RawMessage `json:"args"`
-	OK     bool            `json:"ok"`
-	Result any             `json:"result,omitempty"`
-	Error  string          `json:"error,omitempty"`
+	Name      string          `json:"name"`
+	Source    string          `json:"source"`
+	Args      json.RawMessage `json:"args"`
+	OK        bool            `json:"ok"`
+	Result    any             `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Cancelled bool            `json:"cancelled,omitempty"`
+	TimedOut  bool            `json:"timed_out,omitempty"` // per-call timeout or query deadline
+	// Attempts is set when a transient failure was retried.
+	Attempts int `json:"attempts,omitempty"`
+	// FallbackFor names the failed step this fallback step replaced.
+	FallbackFor string `json:"fallback_for,omitempty"`
+	// OutputError is set when the output expr failed; Result is then shaped without it.
+	OutputError string `json:"output_error,omitempty"`
+	// Redactions counts the secrets/PII redacted from the result, per detector.
+	Redactions map[string]int `json:"redactions,omitempty"`
 }
 
 type RouterResult struct {
 	Plan          ModelPlan           `json:"plan"`
 	ExecutedSteps []ExecutedStep      `json:"executed_steps,omitempty"`
 	Answer        string              `json:"answer,omitempty"`
+	Structured    json.RawMessage     `json:"structured_answer,omitempty"` // answer valid against the caller's answer_schema
+	AnswerError   string              `json:"answer_error,omitempty"`
 	Manifest      *artifacts.Manifest `json:"manifest,omitempty"`
+	Cancelled     bool                `json:"cancelled,omitempty"`
+	TimedOut      bool                `json:"timed_out,omitempty"`
+	Partial       bool                `json:"partial,omitempty"` // some steps failed, the plan went on (on_error)
+	Pending       *PendingActions     `json:"pending,omitempty"`
+	SavedRecipe   *SavedRecipe        `json:"saved_recipe,omitempty"`
 	Debug         any                 `json:"debug,omitempty"`
 }
+
+// SavedRecipe reports the outcome of save_recipe.
+type SavedRecipe struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// PendingActions is a plan with write steps that was held back until the caller confirms it.
+type PendingActions struct {
+	Token     string     `json:"token"`
+	ExpiresAt string     `json:"expires_at"`
+	Actions   []PlanStep `json:"actions"`
+	Message   string     `json:"message,omitempty"`
+}
@@ -0,0 +1,137 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LLMUsage is the accounting record of one planning or summarizing call.
+type LLMUsage struct {
+	Kind             string  `json:"kind"` // plan|summary
+	Provider         string  `json:"provider,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd,omitempty"`
+	LatencyMS        int64   `json:"latency_ms"`
+	// Estimated is set when the provider reported no token counts (e.g. MCP sampling).
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// UsageTotals sums LLMUsage records.
+type UsageTotals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd,omitempty"`
+}
+
+// Add accumulates u.
+func (t *UsageTotals) Add(u LLMUsage) {
+	t.Calls++
+	t.PromptTokens += u.PromptTokens
+	t.CompletionTokens += u.CompletionTokens
+	t.TotalTokens += u.TotalTokens
+	t.CostUSD += u.CostUSD
+}
+
+// UsageRecorder collects the LLM calls made while it is attached to a context (see WithUsageRecorder).
+type UsageRecorder struct {
+	mu       sync.Mutex
+	calls    []LLMUsage
+	totals   UsageTotals
+	onRecord func(LLMUsage)
+}
+
+// NewUsageRecorder creates a recorder; onRecord (optional) also receives every record, e.g. for session totals.
+func NewUsageRecorder(onRecord func(LLMUsage)) *UsageRecorder {
+	return &UsageRecorder{onRecord: onRecord}
+}
+
+// Record adds one call.
+func (r *UsageRecorder) Record(u LLMUsage) {
+	r.mu.Lock()
+	r.calls = append(r.calls, u)
+	r.totals.Add(u)
+	r.mu.Unlock()
+	if r.onRecord != nil {
+		r.onRecord(u)
+	}
+}
+
+// Calls returns the recorded calls in order.
+func (r *UsageRecorder) Calls() []LLMUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]LLMUsage(nil), r.calls...)
+}
+
+// Totals returns the sum of the recorded calls.
+func (r *UsageRecorder) Totals() UsageTotals {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.totals
+}
+
+type usageRecorderKey struct{}
+
+// WithUsageRecorder makes the LLM clients report their calls made with ctx to r.
+func WithUsageRecorder(ctx context.Context, r *UsageRecorder) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, usageRecorderKey{}, r)
+}
+
+// reportUsage fills in the derived fields of u and hands it to the recorder of ctx, if any.
+func reportUsage(ctx context.Context, u LLMUsage, price TokenPrice, start time.Time) {
+	r, _ := ctx.Value(usageRecorderKey{}).(*UsageRecorder)
+	if r == nil {
+		return
+	}
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	if u.CostUSD == 0 {
+		u.CostUSD = price.Cost(u.PromptTokens, u.CompletionTokens)
+	}
+	u.LatencyMS = time.Since(start).Milliseconds()
+	r.Record(u)
+}
+
+// TokenPrice is the price of a model in USD per million tokens, used when the provider does not report cost.
+type TokenPrice struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// Cost returns the price of a call.
+func (p TokenPrice) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.InputPerMTok + float64(completionTokens)*p.OutputPerMTok) / 1e6
+}
+
+// Budget limits the LLM usage of a session (LLMConfig.MaxTokensTotal / MaxCostUSD); zero fields are unlimited.
+type Budget struct {
+	MaxTokensTotal int
+	MaxCostUSD     float64
+}
+
+// Exceeded returns a reason when t is at or over the budget.
+func (b Budget) Exceeded(t UsageTotals) (string, bool) {
+	var reasons []string
+	if b.MaxTokensTotal > 0 && t.TotalTokens >= b.MaxTokensTotal {
+		reasons = append(reasons, fmt.Sprintf("%d/%d tokens", t.TotalTokens, b.MaxTokensTotal))
+	}
+	if b.MaxCostUSD > 0 && t.CostUSD >= b.MaxCostUSD {
+		reasons = append(reasons, fmt.Sprintf("$%.4f/$%.4f", t.CostUSD, b.MaxCostUSD))
+	}
+	if len(reasons) == 0 {
+		return "", false
+	}
+	return "LLM budget exceeded (" + strings.Join(reasons, ", ") + ")", true
+}
@@ -0,0 +1,80 @@
+package router
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ViewPresets maps a view (summary|metadata) to the paths it keeps for one tool. Paths are dotted
+// field paths; arrays on the way are projected element-wise (e.g. issues.fields.status.name).
+type ViewPresets map[string][]string
+
+// Validate checks view names and paths.
+func (p ViewPresets) Validate() error {
+	for view, paths := range p {
+		if view != "summary" && view != "metadata" {
+			return fmt.Errorf("unknown view %q (use summary or metadata)", view)
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("view %s: no paths", view)
+		}
+		for _, path := range paths {
+			if strings.TrimSpace(path) == "" || strings.ContainsAny(path, "[]/") {
+				return fmt.Errorf("view %s: invalid path %q (use dotted field names)", view, path)
+			}
+		}
+	}
+	return nil
+}
+
+// Views returns the view names, sorted.
+func (p ViewPresets) Views() []string {
+	out := make([]string, 0, len(p))
+	for v := range p {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// projectPaths keeps the given dotted paths of v; arrays are projected element-wise.
+func projectPaths(v any, paths []string) any {
+	split := make([][]string, 0, len(paths))
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			split = append(split, strings.Split(p, "."))
+		}
+	}
+	return project(v, split)
+}
+
+func project(v any, paths [][]string) any {
+	for _, p := range paths {
+		if len(p) == 0 {
+			return v
+		}
+	}
+	switch vv := v.(type) {
+	case []any:
+		out := make([]any, 0, len(vv))
+		for _, it := range vv {
+			out = append(out, project(it, paths))
+		}
+		return out
+	case map[string]any:
+		byField := map[string][][]string{}
+		for _, p := range paths {
+			byField[p[0]] = append(byField[p[0]], p[1:])
+		}
+		out := map[string]any{}
+		for field, rest := range byField {
+			if val, ok := vv[field]; ok {
+				out[field] = project(val, rest)
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
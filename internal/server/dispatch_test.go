@@ -0,0 +1,409 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/internal/proxy"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+type recordingTransport struct {
+	resps chan *mcp.Response
+	notes chan mcp.Notification
+}
+
+func (t *recordingTransport) ReadMessage() (*mcp.Request, error) { select {} }
+
+func (t *recordingTransport) WriteResponse(resp *mcp.Response) error {
+	t.resps <- resp
+	return nil
+}
+
+func (t *recordingTransport) WriteNotification(method string, params any) error {
+	b, _ := json.Marshal(params)
+	select {
+	case t.notes <- mcp.Notification{JSONRPC: "2.0", Method: method, Params: b}:
+	default:
+	}
+	return nil
+}
+
+func newTestServer(t *testing.T, workers int) (*Server, *recordingTransport) {
+	t.Helper()
+	tr := &recordingTransport{resps: make(chan *mcp.Response, 8), notes: make(chan mcp.Notification, 32)}
+	s := New(context.Background(), proxy.Config{})
+	s.SetTransport(tr)
+	s.workers = make(chan struct{}, workers)
+	return s, tr
+}
+
+func TestDispatchPingNotBlockedByBusyWorker(t *testing.T) {
+	s, tr := newTestServer(t, 2)
+
+	// Simulate a slow request occupying one worker slot.
+	s.workers <- struct{}{}
+	defer func() { <-s.workers }()
+
+	s.dispatch(&mcp.Request{JSONRPC: "2.0", ID: float64(1), Method: "ping"})
+
+	select {
+	case resp := <-tr.resps:
+		if resp.ID != float64(1) || resp.Error != nil {
+			t.Fatalf("unexpected ping response: %+v", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("ping was not answered while another request was in flight")
+	}
+	s.wg.Wait()
+}
+
+func TestDispatchRespondsToEveryRequest(t *testing.T) {
+	s, tr := newTestServer(t, 2)
+
+	for i := 0; i < 5; i++ {
+		s.dispatch(&mcp.Request{JSONRPC: "2.0", ID: float64(i), Method: "ping"})
+	}
+	s.wg.Wait()
+
+	seen := map[any]bool{}
+	for i := 0; i < 5; i++ {
+		seen[(<-tr.resps).ID] = true
+	}
+	if len(seen) != 5 {
+		t.Fatalf("expected 5 distinct responses, got %d", len(seen))
+	}
+}
+
+func TestDispatchQueuesWithoutBlockingAndDropsCancelledRequests(t *testing.T) {
+	s, tr := newTestServer(t, 1)
+
+	// Fill the pool: dispatch must still return so the read loop keeps reading.
+	s.workers <- struct{}{}
+	done := make(chan struct{})
+	go func() {
+		s.dispatch(&mcp.Request{JSONRPC: "2.0", ID: float64(1), Method: "ping"})
+		s.dispatch(&mcp.Request{JSONRPC: "2.0", ID: float64(2), Method: "ping"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("dispatch blocked the read loop while the pool was full")
+	}
+
+	// A cancellation for a queued request is read and honored before any slot frees up.
+	params, _ := json.Marshal(mcp.CancelledParams{RequestID: 1})
+	s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", Method: "notifications/cancelled", Params: params})
+	<-s.workers
+	s.wg.Wait()
+
+	select {
+	case resp := <-tr.resps:
+		if resp.ID != float64(2) {
+			t.Fatalf("expected only the uncancelled request to be answered, got %+v", resp)
+		}
+	default:
+		t.Fatalf("expected a response for request 2")
+	}
+	if len(tr.resps) != 0 {
+		t.Fatalf("cancelled request must not be answered")
+	}
+}
+
+func TestCancelledNotificationCancelsInflightRequest(t *testing.T) {
+	s, _ := newTestServer(t, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.inflight[requestKey(float64(42))] = cancel
+
+	params, _ := json.Marshal(mcp.CancelledParams{RequestID: 42, Reason: "user aborted"})
+	if resp := s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", Method: "notifications/cancelled", Params: params}); resp != nil {
+		t.Fatalf("notifications must not produce a response, got %+v", resp)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("expected request context to be cancelled")
+	}
+}
+
+func TestCallToolEmitsProgressForToken(t *testing.T) {
+	s, tr := newTestServer(t, 2)
+
+	params, _ := json.Marshal(map[string]any{
+		"name":      "query",
+		"arguments": map[string]any{"input": "search tools grafana"},
+		"_meta":     map[string]any{"progressToken": "tok-1"},
+	})
+	resp := s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(1), Method: "tools/call", Params: params})
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	select {
+	case n := <-tr.notes:
+		if n.Method != "notifications/progress" {
+			t.Fatalf("unexpected notification %q", n.Method)
+		}
+		var p mcp.ProgressParams
+		if err := json.Unmarshal(n.Params, &p); err != nil {
+			t.Fatalf("decode progress: %v", err)
+		}
+		if p.ProgressToken != "tok-1" || p.Progress <= 0 || p.Message == "" {
+			t.Fatalf("unexpected progress params: %+v", p)
+		}
+	default:
+		t.Fatalf("expected a progress notification")
+	}
+}
+
+func TestUpstreamRestartReloadsRegistryAndNotifies(t *testing.T) {
+	s, tr := newTestServer(t, 1)
+	s.registry.SetUpstreamTools("gl", []mcp.Tool{{Name: "gl.old"}, {Name: "gl.kept"}})
+
+	s.handleUpstreamRestart("gl", []mcp.Tool{{Name: "gl.kept"}, {Name: "gl.new"}})
+
+	if _, ok := s.registry.GetTool("gl.old"); ok {
+		t.Fatalf("expected tool dropped by the restarted upstream to be removed")
+	}
+	for _, name := range []string{"gl.kept", "gl.new", "query"} {
+		if _, ok := s.registry.GetTool(name); !ok {
+			t.Fatalf("expected %s in registry", name)
+		}
+	}
+	select {
+	case n := <-tr.notes:
+		if n.Method != "notifications/tools/list_changed" {
+			t.Fatalf("unexpected notification %q", n.Method)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected tools/list_changed notification")
+	}
+}
+
+// samplingTransport answers sampling/createMessage like an MCP client with its own LLM.
+type samplingTransport struct {
+	*recordingTransport
+	mu      sync.Mutex
+	related []any
+}
+
+func (t *samplingTransport) SendRequest(ctx context.Context, relatedRequestID any, method string, params any) (*mcp.Response, error) {
+	t.mu.Lock()
+	t.related = append(t.related, relatedRequestID)
+	t.mu.Unlock()
+	p := params.(mcp.CreateMessageParams)
+	text := "client summary"
+	if strings.HasPrefix(p.Messages[0].Content.Text, "Generate a tool execution plan") {
+		text = `{"steps":[{"name":"search_tools","source":"local","args":{"query":"grafana"}}],"final_answer_needed":true}`
+	}
+	return mcp.NewResponse("s-1", mcp.CreateMessageResult{Role: "assistant", Content: mcp.ContentBlock{Type: "text", Text: text}, Model: "client-model"})
+}
+
+func TestCallToolFallsBackToClientSampling(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv("MCP_LENS_LLM_PROVIDER", "")
+	s, _ := newTestServer(t, 2)
+	tr := &samplingTransport{recordingTransport: s.transport.(*recordingTransport)}
+	s.SetTransport(tr)
+
+	initParams, _ := json.Marshal(map[string]any{"protocolVersion": "2024-11-05", "capabilities": map[string]any{"sampling": map[string]any{}}})
+	if resp := s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(1), Method: "initialize", Params: initParams}); resp.Error != nil {
+		t.Fatalf("initialize: %+v", resp.Error)
+	}
+
+	params, _ := json.Marshal(map[string]any{
+		"name":      "query",
+		"arguments": map[string]any{"input": "which dashboards tooling do we have", "include_answer": true},
+	})
+	resp := s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(2), Method: "tools/call", Params: params})
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	var res mcp.CallToolResult
+	_ = json.Unmarshal(resp.Result, &res)
+	if res.IsError || !strings.Contains(res.Content[0].Text, "client summary") {
+		t.Fatalf("expected plan and answer from the client's LLM, got %+v", res)
+	}
+	if len(tr.related) != 2 || tr.related[0] != float64(2) {
+		t.Fatalf("expected plan + summary requests tied to the tools/call, got %v", tr.related)
+	}
+}
+
+func TestCallToolWithoutSamplingCapabilityNeedsProvider(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv("MCP_LENS_LLM_PROVIDER", "")
+	s, _ := newTestServer(t, 2)
+	tr := &samplingTransport{recordingTransport: s.transport.(*recordingTransport)}
+	s.SetTransport(tr)
+	s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(1), Method: "initialize", Params: json.RawMessage(`{"capabilities":{}}`)})
+
+	params, _ := json.Marshal(map[string]any{"name": "query", "arguments": map[string]any{"input": "which dashboards tooling do we have"}})
+	resp := s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(2), Method: "tools/call", Params: params})
+	var res mcp.CallToolResult
+	_ = json.Unmarshal(resp.Result, &res)
+	if !res.IsError || len(tr.related) != 0 {
+		t.Fatalf("expected a configuration error and no sampling requests, got %+v (%d requests)", res, len(tr.related))
+	}
+}
+
+// sessionSamplingTransport serves several sessions, keyed by request ID.
+type sessionSamplingTransport struct {
+	*samplingTransport
+	sessions map[any]string
+}
+
+func (t *sessionSamplingTransport) RequestSession(id any) string { return t.sessions[id] }
+
+func (t *sessionSamplingTransport) OnSessionClosed(fn func(string)) {}
+
+func TestClientSamplingIsPerSession(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv("MCP_LENS_LLM_PROVIDER", "")
+	s, _ := newTestServer(t, 2)
+	tr := &sessionSamplingTransport{
+		samplingTransport: &samplingTransport{recordingTransport: s.transport.(*recordingTransport)},
+		sessions:          map[any]string{float64(1): "a", float64(2): "b", float64(3): "b", float64(4): "a"},
+	}
+	s.SetTransport(tr)
+
+	s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(1), Method: "initialize", Params: json.RawMessage(`{"capabilities":{"sampling":{}}}`)})
+	s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(2), Method: "initialize", Params: json.RawMessage(`{"capabilities":{}}`)})
+
+	params, _ := json.Marshal(map[string]any{"name": "query", "arguments": map[string]any{"input": "which dashboards tooling do we have"}})
+	var res mcp.CallToolResult
+	resp := s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(3), Method: "tools/call", Params: params})
+	_ = json.Unmarshal(resp.Result, &res)
+	if !res.IsError || len(tr.related) != 0 {
+		t.Fatalf("session b did not declare sampling, got %+v (%d requests)", res, len(tr.related))
+	}
+	resp = s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(4), Method: "tools/call", Params: params})
+	res = mcp.CallToolResult{}
+	_ = json.Unmarshal(resp.Result, &res)
+	if res.IsError || len(tr.related) == 0 || tr.related[0] != float64(4) {
+		t.Fatalf("session a should plan with its own LLM, got %+v (%v)", res, tr.related)
+	}
+
+	s.endSession("a")
+	if s.client("a").sampling {
+		t.Fatalf("expected session a to be forgotten")
+	}
+}
+
+func TestSamplingCallsDoNotStallTheReadLoop(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv("MCP_LENS_LLM_PROVIDER", "")
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	s := New(context.Background(), proxy.Config{})
+	s.SetTransport(mcp.NewStdioTransport(serverIn, serverOut))
+	s.workers = make(chan struct{}, 1)
+	served := make(chan error, 1)
+	go func() { served <- s.serve() }()
+
+	enc := json.NewEncoder(clientOut)
+	dec := json.NewDecoder(clientIn)
+	_ = enc.Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{"capabilities": map[string]any{"sampling": map[string]any{}}}})
+
+	done := make(chan map[float64]bool, 1)
+	go func() {
+		answered := map[float64]bool{}
+		for len(answered) < 3 {
+			var m struct {
+				ID     any             `json:"id"`
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+				Result json.RawMessage `json:"result"`
+			}
+			if err := dec.Decode(&m); err != nil {
+				return
+			}
+			switch {
+			case m.Method == "sampling/createMessage":
+				// Every worker slot is now waiting on the client; the responses must still be read.
+				plan := `{"steps":[{"name":"search_tools","source":"local","args":{"query":"grafana"}}],"final_answer_needed":false}`
+				_ = enc.Encode(map[string]any{"jsonrpc": "2.0", "id": m.ID, "result": mcp.CreateMessageResult{Role: "assistant", Content: mcp.ContentBlock{Type: "text", Text: plan}, Model: "client-model"}})
+			case m.Method == "":
+				id, _ := m.ID.(float64)
+				answered[id] = true
+				if id == 1 {
+					for _, id := range []int{2, 3} {
+						_ = enc.Encode(map[string]any{"jsonrpc": "2.0", "id": id, "method": "tools/call", "params": map[string]any{"name": "query", "arguments": map[string]any{"input": "which dashboards tooling do we have"}}})
+					}
+				}
+			}
+		}
+		done <- answered
+	}()
+
+	select {
+	case answered := <-done:
+		if !answered[2] || !answered[3] {
+			t.Fatalf("expected both queries answered, got %v", answered)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("queries waiting on sampling hung the read loop")
+	}
+	clientOut.Close()
+	if err := <-served; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+}
+
+// structuredAnswerTransport answers every sampling request with a JSON answer.
+type structuredAnswerTransport struct {
+	*recordingTransport
+}
+
+func (t *structuredAnswerTransport) SendRequest(ctx context.Context, relatedRequestID any, method string, params any) (*mcp.Response, error) {
+	return mcp.NewResponse("s-1", mcp.CreateMessageResult{Role: "assistant", Content: mcp.ContentBlock{Type: "text", Text: `{"risk":"low"}`}, Model: "client-model"})
+}
+
+func TestStructuredContentFollowsNegotiatedProtocolVersion(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv("MCP_LENS_LLM_PROVIDER", "")
+	s, _ := newTestServer(t, 2)
+	s.SetTransport(&structuredAnswerTransport{recordingTransport: s.transport.(*recordingTransport)})
+
+	params, _ := json.Marshal(map[string]any{
+		"name": "query",
+		"arguments": map[string]any{
+			"input":         "what tooling do we have",
+			"steps":         []map[string]any{{"name": "search_tools", "source": "local", "args": map[string]any{"query": "grafana"}}},
+			"answer_schema": map[string]any{"type": "object", "properties": map[string]any{"risk": map[string]any{"type": "string"}}, "required": []string{"risk"}},
+		},
+	})
+	for _, tc := range []struct {
+		requested, negotiated string
+		structured            bool
+	}{
+		{"2025-06-18", "2025-06-18", true},
+		{"2024-11-05", "2024-11-05", false},
+		{"1999-01-01", mcp.LatestProtocolVersion, true},
+	} {
+		initParams, _ := json.Marshal(map[string]any{"protocolVersion": tc.requested, "capabilities": map[string]any{"sampling": map[string]any{}}})
+		resp := s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(1), Method: "initialize", Params: initParams})
+		var init mcp.InitializeResult
+		if err := json.Unmarshal(resp.Result, &init); err != nil || init.ProtocolVersion != tc.negotiated {
+			t.Fatalf("requested %s: expected %s, got %+v (%v)", tc.requested, tc.negotiated, init, err)
+		}
+
+		resp = s.handleRequest(context.Background(), &mcp.Request{JSONRPC: "2.0", ID: float64(2), Method: "tools/call", Params: params})
+		var res map[string]json.RawMessage
+		_ = json.Unmarshal(resp.Result, &res)
+		if _, ok := res["structuredContent"]; ok != tc.structured {
+			t.Fatalf("version %s: structuredContent present=%v, want %v (%s)", tc.negotiated, ok, tc.structured, resp.Result)
+		}
+		if !strings.Contains(string(res["content"]), "risk") {
+			t.Fatalf("version %s: expected the answer in the text content, got %s", tc.negotiated, res["content"])
+		}
+	}
+}
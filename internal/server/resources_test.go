@@ -30,7 +30,7 @@ func TestResourcesListRead(t *testing.T) {
 
 	// list
 	listReq := &mcp.Request{JSONRPC: "2.0", ID: 1, Method: "resources/list"}
-	listResp := s.handleRequest(listReq)
+	listResp := s.handleRequest(context.Background(), listReq)
 	if listResp == nil || listResp.Error != nil {
 		t.Fatalf("list resp error: %+v", listResp)
 	}
@@ -38,7 +38,7 @@ func TestResourcesListRead(t *testing.T) {
 	// read
 	readParams, _ := json.Marshal(map[string]any{"uri": "artifact://" + item.ID})
 	readReq := &mcp.Request{JSONRPC: "2.0", ID: 2, Method: "resources/read", Params: readParams}
-	readResp := s.handleRequest(readReq)
+	readResp := s.handleRequest(context.Background(), readReq)
 	if readResp == nil || readResp.Error != nil {
 		t.Fatalf("read resp error: %+v", readResp)
 	}
@@ -6,37 +6,77 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/golovatskygroup/mcp-lens/internal/proxy"
 	"github.com/golovatskygroup/mcp-lens/internal/registry"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
 	"github.com/golovatskygroup/mcp-lens/internal/tools"
 	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
 )
 
 // Server is the main MCP proxy server
 type Server struct {
-	transport *mcp.Transport
+	transport mcp.Transport
 	registry  *registry.Registry
-	proxy     *proxy.Proxy
+	proxy     *proxy.Multi
 	handler   *tools.Handler
 	ctx       context.Context
+
+	// workers bounds the number of requests handled concurrently.
+	workers chan struct{}
+	wg      sync.WaitGroup
+
+	// inflight maps request IDs to the cancel func of their context (for notifications/cancelled).
+	inflight   map[string]context.CancelFunc
+	inflightMu sync.Mutex
+
+	// clients records what each client declared in initialize, by session ("" for stdio).
+	clients   map[string]clientInfo
+	clientsMu sync.Mutex
 }
 
-// New creates a new MCP proxy server
+// clientInfo is what a client declared in initialize.
+type clientInfo struct {
+	sampling bool
+	// protocolVersion is the MCP version negotiated in initialize.
+	protocolVersion string
+}
+
+const defaultMaxConcurrentRequests = 8
+
+// New creates a new MCP proxy server with a single (unnamespaced) upstream.
 func New(ctx context.Context, upstreamCfg proxy.Config) *Server {
+	return newServer(ctx, proxy.NewSingle(upstreamCfg))
+}
+
+// NewWithUpstreams creates a server that aggregates several upstreams.
+// Tools of named upstreams are namespaced as "<name>.<tool>".
+func NewWithUpstreams(ctx context.Context, upstreams []proxy.NamedConfig) (*Server, error) {
+	prx, err := proxy.NewMulti(upstreams)
+	if err != nil {
+		return nil, err
+	}
+	return newServer(ctx, prx), nil
+}
+
+func newServer(ctx context.Context, prx *proxy.Multi) *Server {
 	reg := registry.NewRegistry()
-	prx := proxy.New(upstreamCfg)
 
 	s := &Server{
-		transport: mcp.NewTransport(os.Stdin, os.Stdout),
+		transport: mcp.NewStdioTransport(os.Stdin, os.Stdout),
 		registry:  reg,
 		proxy:     prx,
 		ctx:       ctx,
+		workers:   make(chan struct{}, maxConcurrentRequestsFromEnv()),
+		inflight:  make(map[string]context.CancelFunc),
+		clients:   make(map[string]clientInfo),
 	}
 
 	// Create handler with executor that calls upstream
-	s.handler = tools.NewHandler(reg, func(name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	s.handler = tools.NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
 		return prx.CallTool(ctx, name, args)
 	})
 
@@ -61,25 +101,80 @@ func New(ctx context.Context, upstreamCfg proxy.Config) *Server {
 	return s
 }
 
+// SetTransport replaces the default stdio transport (e.g. with an HTTP transport for --listen mode).
+// Must be called before Run.
+func (s *Server) SetTransport(t mcp.Transport) {
+	s.transport = t
+}
+
+// SetPolicy replaces the router policy used to validate and execute plans.
+func (s *Server) SetPolicy(p router.Policy) {
+	s.handler.SetPolicy(p)
+}
+
+// SetLLMConfig configures the router's LLM provider (planning and summarizing models).
+func (s *Server) SetLLMConfig(cfg router.LLMConfig) {
+	s.handler.SetLLMConfig(cfg)
+}
+
+// SetRecipes registers recipes (parameterized plans) from config.
+func (s *Server) SetRecipes(recipes []router.Recipe) error {
+	return s.handler.SetRecipes(recipes)
+}
+
+// SetContextExtractors registers regex context extractors (captures to context keys and tool args) from config.
+func (s *Server) SetContextExtractors(cfgs []router.ContextExtractorConfig) error {
+	return s.handler.SetContextExtractors(cfgs)
+}
+
+// SetToolViews configures output view presets for upstream tools (or overrides for local ones) from config.
+func (s *Server) SetToolViews(views map[string]router.ViewPresets) error {
+	return s.handler.SetToolViews(views)
+}
+
+// SetTimeouts configures tool call and query deadlines from config.
+func (s *Server) SetTimeouts(cfg router.TimeoutsConfig) error {
+	return s.handler.SetTimeouts(cfg)
+}
+
+// SetRedaction configures the secret/PII redaction of tool results from config.
+func (s *Server) SetRedaction(cfg router.RedactionConfig) error {
+	return s.handler.SetRedaction(cfg)
+}
+
 // Run starts the server main loop
 func (s *Server) Run() error {
+	// Crashed upstreams are restarted by the proxy; reload their tools and tell clients.
+	s.proxy.OnToolsChanged(s.handleUpstreamRestart)
+	if st, ok := s.transport.(mcp.SessionTransport); ok {
+		st.OnSessionClosed(s.endSession)
+	}
+
 	// Start upstream proxy
 	if err := s.proxy.Start(s.ctx); err != nil {
 		return fmt.Errorf("failed to start upstream: %w", err)
 	}
 	defer s.proxy.Stop()
+	// Let in-flight requests finish before the upstream goes away.
+	defer s.wg.Wait()
 
-	// Fetch tools from upstream
-	upstreamTools, err := s.proxy.ListTools(s.ctx)
-	if err != nil {
-		return fmt.Errorf("failed to list upstream tools: %w", err)
+	// Fetch tools from all upstreams (namespaced per upstream name) and load them into the registry
+	total := 0
+	for _, name := range s.proxy.Names() {
+		upstreamTools, err := s.proxy.ListUpstreamTools(s.ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to list upstream tools: %w", err)
+		}
+		s.registry.SetUpstreamTools(name, upstreamTools)
+		total += len(upstreamTools)
 	}
+	logf("Loaded %d tools from %d upstream(s)", total, len(s.proxy.Names()))
 
-	// Load tools into registry
-	s.registry.LoadTools(upstreamTools)
-	logf("Loaded %d tools from upstream", len(upstreamTools))
+	return s.serve()
+}
 
-	// Main message loop
+// serve is the main message loop; it returns at the end of input.
+func (s *Server) serve() error {
 	for {
 		req, err := s.transport.ReadMessage()
 		if err != nil {
@@ -90,26 +185,83 @@ func (s *Server) Run() error {
 			continue
 		}
 
-		resp := s.handleRequest(req)
+		// Notifications carry no ID and expect no response; handle them in arrival order.
+		if req.ID == nil {
+			s.handleRequest(s.ctx, req)
+			continue
+		}
+
+		s.dispatch(req)
+	}
+}
+
+// handleUpstreamRestart reloads the registry after an upstream restart and emits notifications/tools/list_changed.
+func (s *Server) handleUpstreamRestart(upstream string, tools []mcp.Tool) {
+	s.registry.SetUpstreamTools(upstream, tools)
+	if upstream == "" {
+		upstream = "upstream"
+	}
+	logf("Reloaded %d tools from %s after restart", len(tools), upstream)
+	if err := s.transport.WriteNotification("notifications/tools/list_changed", nil); err != nil {
+		logf("Error writing tools/list_changed: %v", err)
+	}
+}
+
+// dispatch handles a request on a worker goroutine. It never blocks: while the pool is full the
+// goroutine waits for a slot, so the read loop keeps reading notifications/cancelled and responses
+// to server-initiated requests (e.g. sampling) that running requests wait on.
+func (s *Server) dispatch(req *mcp.Request) {
+	// Register before the request starts so a notifications/cancelled that follows it is never missed.
+	ctx, cancel := context.WithCancel(s.ctx)
+	key := requestKey(req.ID)
+	s.inflightMu.Lock()
+	s.inflight[key] = cancel
+	s.inflightMu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			s.inflightMu.Lock()
+			delete(s.inflight, key)
+			s.inflightMu.Unlock()
+			cancel()
+			s.wg.Done()
+		}()
+
+		select {
+		case s.workers <- struct{}{}:
+			defer func() { <-s.workers }()
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			// Cancelled while queued: cancelled requests get no response.
+			return
+		}
+
+		resp := s.handleRequest(ctx, req)
 		if resp != nil {
+			// Transport writes are mutex-guarded, so workers can respond in any order.
 			if err := s.transport.WriteResponse(resp); err != nil {
 				logf("Error writing response: %v", err)
 			}
 		}
-	}
+	}()
 }
 
-func (s *Server) handleRequest(req *mcp.Request) *mcp.Response {
+func (s *Server) handleRequest(ctx context.Context, req *mcp.Request) *mcp.Response {
 	switch req.Method {
 	case "initialize":
 		return s.handleInitialize(req)
 	case "notifications/initialized":
 		// No response needed for notifications
 		return nil
+	case "notifications/cancelled":
+		s.handleCancelled(req)
+		return nil
 	case "tools/list":
 		return s.handleListTools(req)
 	case "tools/call":
-		return s.handleCallTool(req)
+		return s.handleCallTool(ctx, req)
 	case "resources/list":
 		return s.handleListResources(req)
 	case "resources/read":
@@ -121,9 +273,70 @@ func (s *Server) handleRequest(req *mcp.Request) *mcp.Response {
 	}
 }
 
+// handleCancelled cancels the context of an in-flight request. Unknown or finished IDs are ignored.
+func (s *Server) handleCancelled(req *mcp.Request) {
+	var params mcp.CancelledParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.RequestID == nil {
+		return
+	}
+	key := requestKey(params.RequestID)
+	s.inflightMu.Lock()
+	cancel, ok := s.inflight[key]
+	s.inflightMu.Unlock()
+	if !ok {
+		return
+	}
+	if params.Reason != "" {
+		logf("Cancelling request %s: %s", key, params.Reason)
+	} else {
+		logf("Cancelling request %s", key)
+	}
+	cancel()
+}
+
+// requestKey normalizes a JSON-RPC ID (number or string) into a map key.
+func requestKey(id any) string {
+	return fmt.Sprint(id)
+}
+
+// requestSession returns the client session of an in-flight request ("" for single-client transports).
+func (s *Server) requestSession(id any) string {
+	if st, ok := s.transport.(mcp.SessionTransport); ok {
+		return st.RequestSession(id)
+	}
+	return ""
+}
+
+func (s *Server) setClient(session string, c clientInfo) {
+	s.clientsMu.Lock()
+	s.clients[session] = c
+	s.clientsMu.Unlock()
+}
+
+func (s *Server) client(session string) clientInfo {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	return s.clients[session]
+}
+
+// endSession forgets what a client declared and its LLM usage once its session is closed.
+func (s *Server) endSession(session string) {
+	s.clientsMu.Lock()
+	delete(s.clients, session)
+	s.clientsMu.Unlock()
+	s.handler.EndSession(session)
+}
+
 func (s *Server) handleInitialize(req *mcp.Request) *mcp.Response {
+	var params mcp.InitializeParams
+	if len(req.Params) > 0 {
+		_ = json.Unmarshal(req.Params, &params)
+	}
+	version := mcp.NegotiateProtocolVersion(params.ProtocolVersion)
+	s.setClient(s.requestSession(req.ID), clientInfo{sampling: params.Capabilities.Sampling != nil, protocolVersion: version})
+
 	result := mcp.InitializeResult{
-		ProtocolVersion: "2024-11-05",
+		ProtocolVersion: version,
 		Capabilities: mcp.ServerCapabilities{
 			Tools: &mcp.ToolsCapability{
 				ListChanged: true,
@@ -174,7 +387,7 @@ func (s *Server) handleListTools(req *mcp.Request) *mcp.Response {
 	return resp
 }
 
-func (s *Server) handleCallTool(req *mcp.Request) *mcp.Response {
+func (s *Server) handleCallTool(ctx context.Context, req *mcp.Request) *mcp.Response {
 	var params mcp.CallToolParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		return mcp.NewErrorResponse(req.ID, mcp.InvalidParams, "Invalid params: "+err.Error())
@@ -183,9 +396,31 @@ func (s *Server) handleCallTool(req *mcp.Request) *mcp.Response {
 	var result *mcp.CallToolResult
 	var err error
 
+	session := s.requestSession(req.ID)
+	ctx = tools.WithSession(ctx, session)
+
+	if params.Meta != nil && params.Meta.ProgressToken != nil {
+		token := params.Meta.ProgressToken
+		ctx = tools.WithProgress(ctx, func(progress float64, message string) {
+			s.notifyRequest(req.ID, "notifications/progress", mcp.ProgressParams{
+				ProgressToken: token,
+				Progress:      progress,
+				Message:       message,
+			})
+		})
+	}
+
+	// Without its own LLM provider, the router can plan and summarize with the client's LLM.
+	if rs, ok := s.transport.(mcp.RequestSender); ok && s.client(session).sampling {
+		requestID := req.ID
+		ctx = tools.WithSampling(ctx, func(ctx context.Context, p mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+			return mcp.CreateMessage(ctx, rs, requestID, p)
+		})
+	}
+
 	// `query` is the primary entrypoint; `router` kept for backwards compatibility.
 	if params.Name == "query" || params.Name == "router" {
-		result, err = s.handler.Handle(s.ctx, params.Name, params.Arguments)
+		result, err = s.handler.Handle(ctx, params.Name, params.Arguments)
 	} else {
 		result = &mcp.CallToolResult{
 			Content: []mcp.ContentBlock{{Type: "text", Text: "Tool is not exposed directly. Use the 'query' tool to ask a free-form request, and the proxy will plan and execute appropriate tools for you."}},
@@ -198,6 +433,10 @@ func (s *Server) handleCallTool(req *mcp.Request) *mcp.Response {
 	if err != nil {
 		return mcp.NewErrorResponse(req.ID, mcp.InternalError, err.Error())
 	}
+	// Clients on versions before 2025-06-18 get the structured answer only as text.
+	if result.StructuredContent != nil && !mcp.SupportsStructuredContent(s.client(session).protocolVersion) {
+		result.StructuredContent = nil
+	}
 
 	resp, err := mcp.NewResponse(req.ID, result)
 	if err != nil {
@@ -206,6 +445,20 @@ func (s *Server) handleCallTool(req *mcp.Request) *mcp.Response {
 	return resp
 }
 
+// notifyRequest sends a notification related to an in-flight request,
+// on that request's stream when the transport supports it.
+func (s *Server) notifyRequest(requestID any, method string, params any) {
+	var err error
+	if rn, ok := s.transport.(mcp.RequestNotifier); ok {
+		err = rn.WriteRequestNotification(requestID, method, params)
+	} else {
+		err = s.transport.WriteNotification(method, params)
+	}
+	if err != nil {
+		logf("Error writing %s: %v", method, err)
+	}
+}
+
 func (s *Server) handlePing(req *mcp.Request) *mcp.Response {
 	resp, _ := mcp.NewResponse(req.ID, map[string]any{})
 	return resp
@@ -283,6 +536,16 @@ func (s *Server) buildInstructions() string {
 	return sb.String()
 }
 
+// maxConcurrentRequestsFromEnv reads MCP_LENS_MAX_CONCURRENT_REQUESTS (default: 8).
+func maxConcurrentRequestsFromEnv() int {
+	if v := strings.TrimSpace(os.Getenv("MCP_LENS_MAX_CONCURRENT_REQUESTS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxConcurrentRequests
+}
+
 func logf(format string, args ...any) {
 	fmt.Fprintf(os.Stderr, "[mcp-proxy] "+format+"\n", args...)
 }
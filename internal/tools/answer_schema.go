@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+// answer fills in the final answer: free text, or JSON valid against answer_schema in res.Structured.
+// When the session's LLM budget is used up it falls back to the executor summary and returns the reason.
+func (h *Handler) answer(ctx context.Context, cl router.LLMClient, in routerInput, res *router.RouterResult) string {
+	if reason, over := h.llmBudgetExceeded(ctx); over {
+		res.Answer = executorSummary(*res)
+		if len(in.AnswerSchema) > 0 {
+			res.AnswerError = reason
+		}
+		return reason
+	}
+	if len(in.AnswerSchema) > 0 {
+		structured, err := router.SummarizeStructured(ctx, cl, in.Input, *res, in.AnswerSchema)
+		if err != nil {
+			res.AnswerError = err.Error()
+			return ""
+		}
+		res.Structured = structured
+		return ""
+	}
+	if answer, err := router.Summarize(ctx, cl, in.Input, *res); err == nil {
+		res.Answer = answer
+	}
+	return ""
+}
+
+// structuredExecutorAnswer makes the answer_schema answer of an executor-mode query, which otherwise needs no LLM.
+func (h *Handler) structuredExecutorAnswer(ctx context.Context, in routerInput, res *router.RouterResult) {
+	cl, err := h.llmClient(ctx)
+	if err != nil {
+		res.Answer = executorSummary(*res)
+		res.AnswerError = "answer_schema needs an LLM: " + err.Error()
+		return
+	}
+	ctx, usage := h.meterLLM(ctx)
+	overBudget := h.answer(ctx, cl, in, res)
+	res.Debug = &planDebug{LLMUsage: h.usageDebug(ctx, usage, overBudget)}
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestQueryStructuredAnswerRetriesUntilValid(t *testing.T) {
+	var summaries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		content := `{"steps":[{"name":"ci.list_jobs","source":"upstream","args":{}}],"final_answer_needed":true}`
+		if strings.Contains(body.Messages[0].Content, "structured answer") {
+			summaries = append(summaries, body.Messages[1].Content)
+			content = "```json\n{\"risk\":\"extreme\"}\n```"
+			if len(summaries) > 1 {
+				content = `{"risk":"high","blocking_issues":["job 4711 failed"]}`
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"content": content}, "finish_reason": "stop"}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OPENROUTER_API_KEY", "x")
+	t.Setenv("MCP_LENS_ROUTER_MODEL", "m")
+	t.Setenv("MCP_LENS_ROUTER_BASE_URL", srv.URL)
+	h := ciHandler(t)
+
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"risk":            map[string]any{"enum": []string{"low", "medium", "high"}},
+			"blocking_issues": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"required": []string{"risk"},
+	}
+	args, _ := json.Marshal(map[string]any{"input": "review CI", "answer_schema": schema})
+	out, err := h.Handle(context.Background(), "query", args)
+	if err != nil || out.IsError {
+		t.Fatalf("query failed: %v %+v", err, out)
+	}
+	if len(summaries) != 2 || !strings.Contains(summaries[1], "/risk") {
+		t.Fatalf("expected one retry with the validation error, got %q", summaries)
+	}
+	b, _ := json.Marshal(out.StructuredContent)
+	if string(b) != `{"risk":"high","blocking_issues":["job 4711 failed"]}` {
+		t.Fatalf("unexpected structuredContent %s", b)
+	}
+
+	// Invalid schemas are rejected up front.
+	args, _ = json.Marshal(map[string]any{"input": "review CI", "answer_schema": map[string]any{"type": 42}})
+	if out, err := h.Handle(context.Background(), "query", args); err != nil || !out.IsError {
+		t.Fatalf("expected invalid schema error, got %+v", out)
+	}
+}
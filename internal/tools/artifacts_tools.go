@@ -56,7 +56,7 @@ func (h *Handler) artifactSaveText(_ context.Context, args json.RawMessage) (*mc
 			ext = strings.TrimPrefix(e, ".")
 		}
 	}
-	repl, item, err := h.artifacts.StoreBytes("artifact_save_text", args, mime, ext, []byte(in.Text))
+	repl, item, err := h.artifacts.StoreBytes("artifact_save_text", h.redactArgs(args), mime, ext, []byte(in.Text))
 	if err != nil {
 		return errorResult(err.Error()), nil
 	}
@@ -96,7 +96,7 @@ func (h *Handler) artifactAppendText(_ context.Context, args json.RawMessage) (*
 		sep = "\n"
 	}
 	newText := orig + sep + in.Text
-	repl, item, err := h.artifacts.StoreBytes("artifact_append_text", args, mime, "txt", []byte(newText))
+	repl, item, err := h.artifacts.StoreBytes("artifact_append_text", h.redactArgs(args), mime, "txt", []byte(newText))
 	if err != nil {
 		return errorResult(err.Error()), nil
 	}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golovatskygroup/mcp-lens/internal/registry"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+const (
+	defaultCatalogTopK = 25
+	stubDescriptionLen = 100
+)
+
+// pinnedCatalogTools always keep their schema: the planner needs them to reach stubbed tools.
+var pinnedCatalogTools = map[string]struct{}{
+	"search_tools":  {},
+	"describe_tool": {},
+}
+
+// catalogTopKFromEnv reads MCP_LENS_CATALOG_TOP_K (default 25; 0 sends every schema).
+func catalogTopKFromEnv() int {
+	if v := strings.TrimSpace(os.Getenv("MCP_LENS_CATALOG_TOP_K")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultCatalogTopK
+}
+
+// planningCatalog returns the catalog sent to the planner: the top-K tools ranked against the
+// input (BM25) keep their full schema, the rest become one-line stubs the planner can describe_tool.
+// Validation always uses the full catalog.
+func planningCatalog(input string, catalog []router.ToolCatalogItem) []router.ToolCatalogItem {
+	k := catalogTopKFromEnv()
+	if k <= 0 || len(catalog) <= k+len(pinnedCatalogTools) {
+		return catalog
+	}
+
+	tools := make([]mcp.Tool, len(catalog))
+	for i, it := range catalog {
+		tools[i] = mcp.Tool{Name: it.Name, Description: it.Description, InputSchema: it.InputSchema}
+	}
+	top := make(map[string]struct{}, k)
+	for _, s := range registry.RankTools(input, tools) {
+		if len(top) >= k {
+			break
+		}
+		if _, pinned := pinnedCatalogTools[s.Name]; pinned {
+			continue
+		}
+		top[s.Name] = struct{}{}
+	}
+
+	out := make([]router.ToolCatalogItem, len(catalog))
+	for i, it := range catalog {
+		_, keep := top[it.Name]
+		_, pinned := pinnedCatalogTools[it.Name]
+		if keep || pinned {
+			out[i] = it
+			continue
+		}
+		out[i] = router.ToolCatalogItem{
+			Name:        it.Name,
+			Description: stubDescription(it.Description),
+			Category:    it.Category,
+			Source:      it.Source,
+			Stub:        true,
+		}
+	}
+	return out
+}
+
+// stubDescription keeps the first line of a description, truncated.
+func stubDescription(desc string) string {
+	desc, _, _ = strings.Cut(strings.TrimSpace(desc), "\n")
+	if r := []rune(desc); len(r) > stubDescriptionLen {
+		desc = string(r[:stubDescriptionLen-3]) + "..."
+	}
+	return desc
+}
+
+// expandCatalog replaces the stubs of the named tools with their full catalog entries.
+func expandCatalog(prompt []router.ToolCatalogItem, catalog []router.ToolCatalogItem, names []string) []router.ToolCatalogItem {
+	if len(names) == 0 {
+		return prompt
+	}
+	want := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		want[n] = struct{}{}
+	}
+	full := make(map[string]router.ToolCatalogItem, len(catalog))
+	for _, it := range catalog {
+		full[it.Name] = it
+	}
+
+	out := make([]router.ToolCatalogItem, len(prompt))
+	for i, it := range prompt {
+		out[i] = it
+		if _, ok := want[it.Name]; ok && it.Stub {
+			if f, ok := full[it.Name]; ok {
+				out[i] = f
+			}
+		}
+	}
+	return out
+}
+
+// describedStubs returns the stubbed tools the plan asks describe_tool about.
+func describedStubs(plan router.ModelPlan, prompt []router.ToolCatalogItem) []string {
+	stubs := make(map[string]struct{})
+	for _, it := range prompt {
+		if it.Stub {
+			stubs[it.Name] = struct{}{}
+		}
+	}
+	var names []string
+	for _, s := range plan.Steps {
+		if s.Name != "describe_tool" {
+			continue
+		}
+		var args struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(s.Args, &args) != nil {
+			continue
+		}
+		if _, ok := stubs[args.Name]; ok {
+			names = append(names, args.Name)
+		}
+	}
+	return names
+}
+
+// planToolNames returns the tools used by a plan (to show their schemas when repairing it).
+func planToolNames(plan router.ModelPlan) []string {
+	names := make([]string, 0, len(plan.Steps))
+	for _, s := range plan.Steps {
+		names = append(names, s.Name)
+	}
+	return names
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+// promptTools decodes the tool catalog of a planner prompt.
+func promptTools(t *testing.T, prompt string) map[string]router.ToolCatalogItem {
+	t.Helper()
+	_, body, _ := strings.Cut(prompt, "\n\n")
+	var payload struct {
+		Tools []router.ToolCatalogItem `json:"tools"`
+	}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("decode prompt: %v", err)
+	}
+	out := make(map[string]router.ToolCatalogItem, len(payload.Tools))
+	for _, it := range payload.Tools {
+		out[it.Name] = it
+	}
+	return out
+}
+
+func TestPlanningCatalogSendsTopKSchemasAndStubs(t *testing.T) {
+	t.Setenv("MCP_LENS_CATALOG_TOP_K", "3")
+	h := ciHandler(t)
+	catalog := h.buildRouterCatalog()
+
+	prompt := planningCatalog("fetch the log of the failed ci job", catalog)
+	if len(prompt) != len(catalog) {
+		t.Fatalf("expected every tool listed, got %d of %d", len(prompt), len(catalog))
+	}
+	full := 0
+	for _, it := range prompt {
+		if !it.Stub {
+			full++
+			continue
+		}
+		if it.InputSchema != nil || len(it.Description) > stubDescriptionLen {
+			t.Fatalf("stub %s carries its schema or long description", it.Name)
+		}
+	}
+	if full != 3+len(pinnedCatalogTools) {
+		t.Fatalf("expected top-3 plus pinned tools with schemas, got %d", full)
+	}
+	byName := map[string]router.ToolCatalogItem{}
+	for _, it := range prompt {
+		byName[it.Name] = it
+	}
+	if byName["ci.get_log"].Stub || byName["describe_tool"].Stub {
+		t.Fatalf("expected ci.get_log (best match) and describe_tool with schemas")
+	}
+
+	t.Setenv("MCP_LENS_CATALOG_TOP_K", "0")
+	for _, it := range planningCatalog("anything", catalog) {
+		if it.Stub {
+			t.Fatalf("MCP_LENS_CATALOG_TOP_K=0 must send every schema")
+		}
+	}
+}
+
+func TestPlannerDescribesStubbedToolAndReplans(t *testing.T) {
+	t.Setenv("MCP_LENS_CATALOG_TOP_K", "1")
+	var calls int32
+	fakePlanner(t, func(prompt string) string {
+		tools := promptTools(t, prompt)
+		if atomic.AddInt32(&calls, 1) == 1 {
+			if !tools["ci.get_log"].Stub || !strings.Contains(prompt, "tool_stubs") {
+				t.Errorf("expected ci.get_log as a stub in the first prompt")
+			}
+			return `{"steps":[{"name":"describe_tool","source":"local","args":{"name":"ci.get_log"}}],"final_answer_needed":false}`
+		}
+		if tools["ci.get_log"].Stub || tools["ci.get_log"].InputSchema == nil {
+			t.Errorf("expected the described tool's schema in the second prompt")
+		}
+		return `{"steps":[{"name":"ci.get_log","source":"upstream","args":{"job":4711}}],"final_answer_needed":false}`
+	})
+	h := ciHandler(t)
+
+	res := runQuery(t, h, map[string]any{"input": "list jobs"})
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("expected a second planning call after describe_tool, got %d", n)
+	}
+	if len(res.ExecutedSteps) != 1 || res.ExecutedSteps[0].Name != "ci.get_log" || !res.ExecutedSteps[0].OK {
+		t.Fatalf("unexpected steps: %+v", res.ExecutedSteps)
+	}
+}
@@ -238,7 +238,7 @@ func (h *Handler) confluenceDownloadAttachment(ctx context.Context, args json.Ra
 		name = path.Base(u.Path)
 	}
 	ext := path.Ext(name)
-	repl, item, err := h.artifacts.StoreBytes("confluence_download_attachment", args, ct, strings.TrimPrefix(ext, "."), b)
+	repl, item, _, err := h.storeArtifactBytes("confluence_download_attachment", args, ct, strings.TrimPrefix(ext, "."), b)
 	if err != nil {
 		return errorResult(err.Error()), nil
 	}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"strings"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+// SetContextExtractors registers regex context extractors from config; they run after the built-ins.
+func (h *Handler) SetContextExtractors(cfgs []router.ContextExtractorConfig) error {
+	exs, err := router.NewRegexContextExtractors(cfgs)
+	if err != nil {
+		return err
+	}
+	h.extractors = exs
+	return nil
+}
+
+func (h *Handler) extractContext(input string) map[string]any {
+	extra := make([]router.ContextExtractor, 0, len(h.extractors))
+	for _, ex := range h.extractors {
+		extra = append(extra, ex)
+	}
+	return router.ExtractStructuredContext(input, extra...)
+}
+
+// applyConfiguredContextArgs fills empty args of a step from context per the configured extractors' args rules.
+func (h *Handler) applyConfiguredContextArgs(tool string, args map[string]any, ctx map[string]any) {
+	for _, ex := range h.extractors {
+		for arg, key := range ex.ArgsFor(tool) {
+			v, ok := ctx[key]
+			if !ok || isEmptyArg(v) || !isEmptyArg(args[arg]) {
+				continue
+			}
+			args[arg] = v
+		}
+	}
+}
+
+// isEmptyArg reports whether an arg counts as unset (missing, blank string or zero number).
+func isEmptyArg(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(x) == ""
+	case float64:
+		return x == 0
+	case int:
+		return x == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+func TestConfiguredContextExtractorFillsPlanArgs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"content": `{"steps":[{"name":"ci.get_log","source":"upstream","args":{}}]}`}, "finish_reason": "stop"}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OPENROUTER_API_KEY", "x")
+	t.Setenv("MCP_LENS_ROUTER_MODEL", "m")
+	t.Setenv("MCP_LENS_ROUTER_BASE_URL", srv.URL)
+	h := ciHandler(t)
+	err := h.SetContextExtractors([]router.ContextExtractorConfig{{
+		Name:     "ci_run",
+		Pattern:  `https://ci\.acme\.corp/runs/(?P<id>\d+)`,
+		Context:  map[string]string{"id": "ci_job_id"},
+		Integers: []string{"id"},
+		Args:     []router.ContextArgRule{{Tools: []string{"ci.get_*"}, Set: map[string]string{"job": "ci_job_id"}}},
+	}})
+	if err != nil {
+		t.Fatalf("SetContextExtractors: %v", err)
+	}
+
+	res := runQuery(t, h, map[string]any{"input": "why did https://ci.acme.corp/runs/4711 fail?"})
+	if len(res.ExecutedSteps) != 1 || !res.ExecutedSteps[0].OK {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if b, _ := json.Marshal(res.ExecutedSteps[0].Result); !strings.Contains(string(b), `"job":4711`) {
+		t.Fatalf("expected job arg from context, got %s", b)
+	}
+}
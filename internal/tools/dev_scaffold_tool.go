@@ -12,7 +12,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golovatskygroup/mcp-lens/internal/router"
 	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
 )
 
@@ -141,7 +140,7 @@ func (h *Handler) devScaffoldTool(ctx context.Context, args json.RawMessage) (*m
 	toolFileRel := filepath.ToSlash(filepath.Join("internal", "tools", in.ToolName+".go"))
 	inputType := handlerMethod + "Input"
 
-	toolFileContent, warn, err := generateToolFileBestEffort(ctx, in, handlerMethod, inputType)
+	toolFileContent, warn, err := h.generateToolFileBestEffort(ctx, in, handlerMethod, inputType)
 	if err != nil {
 		return errorResult("failed to generate tool implementation: " + err.Error()), nil
 	}
@@ -236,11 +235,11 @@ func (h *Handler) devScaffoldTool(ctx context.Context, args json.RawMessage) (*m
 	return jsonResult(out), nil
 }
 
-func generateToolFileBestEffort(ctx context.Context, in devScaffoldToolInput, handlerMethod string, inputType string) (content string, warning string, err error) {
-	// If OpenRouter isn't configured, fall back to a deterministic skeleton.
-	cl, clErr := router.NewOpenRouterClientFromEnv()
+func (h *Handler) generateToolFileBestEffort(ctx context.Context, in devScaffoldToolInput, handlerMethod string, inputType string) (content string, warning string, err error) {
+	// If no LLM is configured, fall back to a deterministic skeleton.
+	cl, clErr := h.llmClient(ctx)
 	if clErr != nil {
-		return generateToolFileSkeleton(in, handlerMethod, inputType), "OpenRouter not configured; generated skeleton only", nil
+		return generateToolFileSkeleton(in, handlerMethod, inputType), "LLM not configured; generated skeleton only", nil
 	}
 
 	schemaBytes, _ := json.MarshalIndent(in.InputSchema, "", "  ")
@@ -264,7 +263,7 @@ func generateToolFileBestEffort(ctx context.Context, in devScaffoldToolInput, ha
 	user, _ := json.MarshalIndent(payload, "", "  ")
 
 	system := "You are a senior Go engineer working on mcp-lens. Return ONLY valid Go source code (no markdown, no backticks)."
-	resp, err := cl.ChatCompletionText(ctx, system, string(user))
+	resp, _, err := cl.ChatCompletionTextWithFinishReason(ctx, system, string(user))
 	if err != nil {
 		return "", "", err
 	}
@@ -171,7 +171,7 @@ func (h *Handler) %s(ctx context.Context, args json.RawMessage) (*mcp.CallToolRe
 	t.Setenv("MCP_LENS_ROUTER_BASE_URL", srv.URL)
 
 	reg := registry.NewRegistry()
-	h := NewHandler(reg, func(name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
 		return &mcp.CallToolResult{
 			Content: []mcp.ContentBlock{{Type: "text", Text: `{"error":"upstream not configured"}`}},
 			IsError: true,
@@ -260,7 +260,8 @@ func (h *Handler) githubDownloadJobLogs(ctx context.Context, args json.RawMessag
 	if h.artifacts == nil {
 		return errorResult("artifact store is not configured"), nil
 	}
-	repl, item, err := h.artifacts.StoreBytes("github_download_job_logs", args, mime, "log", logBytes)
+	// Logs often echo credentials; redact them before they are stored (the preview reaches the LLM).
+	repl, item, redactions, err := h.storeArtifactBytes("github_download_job_logs", args, mime, "log", logBytes)
 	if err != nil {
 		return errorResult(err.Error()), nil
 	}
@@ -271,6 +272,9 @@ func (h *Handler) githubDownloadJobLogs(ctx context.Context, args json.RawMessag
 		"mime":     item.Mime,
 		"sha256":   item.SHA256,
 	}
+	if len(redactions) > 0 {
+		out["redactions"] = redactions
+	}
 	return jsonResult(out), nil
 }
 
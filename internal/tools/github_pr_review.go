@@ -1142,7 +1142,7 @@ func (h *Handler) fetchCompletePRDiff(ctx context.Context, args json.RawMessage)
 	filePath := filepath.Join(outputDir, filename)
 
 	// Write to file
-	if err := os.WriteFile(filePath, []byte(diffContent), 0644); err != nil {
+	if err := os.WriteFile(filePath, []byte(diffContent), 0o644); err != nil {
 		return errorResult(fmt.Sprintf("Failed to write diff file: %s", err.Error())), nil
 	}
 
@@ -1257,7 +1257,7 @@ func (h *Handler) fetchCompletePRFiles(ctx context.Context, args json.RawMessage
 
 	// Write to file as JSON
 	fileContent, _ := json.MarshalIndent(allFiles, "", "  ")
-	if err := os.WriteFile(filePath, fileContent, 0644); err != nil {
+	if err := os.WriteFile(filePath, fileContent, 0o644); err != nil {
 		return errorResult(fmt.Sprintf("Failed to write files list: %s", err.Error())), nil
 	}
 
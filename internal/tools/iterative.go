@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/golovatskygroup/mcp-lens/internal/artifacts"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+const (
+	// maxIterativeSteps caps the total number of planned steps across all rounds of an iterative query.
+	maxIterativeSteps     = 20
+	defaultIterativeSteps = 10
+	// defaultTokenBudget bounds the (estimated) planner tokens an iterative query may spend.
+	defaultTokenBudget = 100000
+)
+
+// iterativeDebug is reported in RouterResult.Debug for mode=iterative.
+type iterativeDebug struct {
+	Rounds          int          `json:"rounds"`
+	EstimatedTokens int          `json:"estimated_tokens"`
+	TokenBudget     int          `json:"token_budget"`
+	StopReason      string       `json:"stop_reason"`
+	LastError       string       `json:"last_error,omitempty"`
+	PlanRepairs     []planRepair `json:"plan_repairs,omitempty"`
+	LLMUsage        *usageDebug  `json:"llm_usage,omitempty"`
+}
+
+// runIterative plans and executes in rounds: each round the planner sees the results so far
+// and either adds steps or stops, until the step or token budget is spent.
+// prompt is the planning catalog; stubs described in a round keep their full schema afterwards.
+func (h *Handler) runIterative(ctx context.Context, cl router.LLMClient, usage *router.UsageRecorder, in routerInput, policy router.Policy, catalog []router.ToolCatalogItem, prompt []router.ToolCatalogItem) *mcp.CallToolResult {
+	budget := in.TokenBudget
+	if budget <= 0 {
+		budget = defaultTokenBudget
+	}
+	dbg := &iterativeDebug{TokenBudget: budget}
+	res := router.RouterResult{Debug: dbg}
+	manifest := &artifacts.Manifest{Artifacts: []artifacts.Item{}}
+	var history []router.ExecutedStep
+
+	for {
+		remaining := in.MaxSteps - len(res.Plan.Steps)
+		if remaining <= 0 {
+			dbg.StopReason = "max_steps"
+			break
+		}
+		if dbg.EstimatedTokens >= budget {
+			dbg.StopReason = "token_budget"
+			break
+		}
+		if ctx.Err() != nil {
+			dbg.StopReason = "interrupted"
+			break
+		}
+		if reason, over := h.llmBudgetExceeded(ctx); over {
+			dbg.StopReason = "llm_budget"
+			dbg.LastError = reason
+			break
+		}
+
+		next, rawPlan, tokens, err := router.PlanNext(ctx, cl, in.Input, in.Context, prompt, history, remaining)
+		dbg.Rounds++
+		dbg.EstimatedTokens += tokens
+		if err != nil {
+			if dbg.Rounds == 1 {
+				return errorResult(err.Error())
+			}
+			dbg.StopReason = "planner_error"
+			dbg.LastError = err.Error()
+			break
+		}
+		if next.Done || len(next.Steps) == 0 {
+			dbg.StopReason = "done"
+			break
+		}
+
+		next, rawPlan, repairs, repairTokens, err := h.validateWithRepair(ctx, cl, in, policy, catalog, prompt, remaining, history, next, rawPlan)
+		dbg.EstimatedTokens += repairTokens
+		for _, r := range repairs {
+			r.Round = dbg.Rounds
+			dbg.PlanRepairs = append(dbg.PlanRepairs, r)
+		}
+		if err != nil {
+			if dbg.Rounds == 1 {
+				return errorResult(err.Error() + "\nplan=" + string(rawPlan) + planRepairsText(repairs))
+			}
+			dbg.StopReason = "invalid_plan"
+			dbg.LastError = err.Error()
+			break
+		}
+		res.Plan.Steps = append(res.Plan.Steps, next.Steps...)
+		prompt = expandCatalog(prompt, catalog, append(describedStubs(next, prompt), planToolNames(next)...))
+		res.Plan.FinalAnswerNeeded = res.Plan.FinalAnswerNeeded || next.FinalAnswerNeeded
+
+		if in.DryRun {
+			dbg.StopReason = "dry_run"
+			break
+		}
+		if hasConfirmableWrites(next) {
+			// Writes end the loop: the round is held until the caller confirms it.
+			res.Pending = h.holdPendingPlan(ctx, in, next, history)
+			dbg.StopReason = "pending_confirmation"
+			break
+		}
+
+		// Step errors are fed back to the planner on the next round instead of ending the query.
+		execSteps, m, _ := h.executePlanAfter(ctx, next, history, policy, in.Output, in.Parallelism)
+		history = append(history, execSteps...)
+		if m != nil {
+			manifest.Artifacts = append(manifest.Artifacts, m.Artifacts...)
+		}
+	}
+
+	res.ExecutedSteps = history
+	if len(manifest.Artifacts) > 0 {
+		res.Manifest = manifest
+	}
+	markInterrupted(ctx, &res)
+	if !res.Cancelled && !res.TimedOut {
+		markPartial(&res)
+		h.saveRecipe(in.SaveRecipe, &res)
+	}
+
+	var overBudget string
+	if in.IncludeAnswer && len(history) > 0 && ctx.Err() == nil {
+		overBudget = h.answer(ctx, cl, in, &res)
+	}
+	dbg.LLMUsage = h.usageDebug(ctx, usage, overBudget)
+	return h.formatRouterResult(res, in.Format, in.Columns)
+}
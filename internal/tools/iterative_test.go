@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/internal/registry"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+// fakePlanner serves OpenRouter chat completions; next receives the user prompt and returns the assistant content.
+func fakePlanner(t *testing.T, next func(prompt string) string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		content := next(body.Messages[len(body.Messages)-1].Content)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"content": content}, "finish_reason": "stop"}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OPENROUTER_API_KEY", "x")
+	t.Setenv("MCP_LENS_ROUTER_MODEL", "m")
+	t.Setenv("MCP_LENS_ROUTER_BASE_URL", srv.URL)
+}
+
+func ciHandler(t *testing.T) *Handler {
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		switch name {
+		case "ci.list_jobs":
+			return &mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: `{"jobs":[{"id":4711,"status":"failed"}]}`}}}, nil
+		case "ci.get_log":
+			return &mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: `{"log":"panic: boom","args":` + string(args) + `}`}}}, nil
+		}
+		return nil, nil
+	})
+	reg.LoadTools(h.BuiltinTools())
+	reg.LoadTools([]mcp.Tool{
+		{Name: "ci.list_jobs", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		{Name: "ci.get_log", InputSchema: json.RawMessage(`{"type":"object","properties":{"job":{"type":"integer"}},"required":["job"]}`)},
+	})
+	p := router.DefaultPolicy()
+	p.AllowUpstream = map[string]struct{}{"ci.list_jobs": {}, "ci.get_log": {}}
+	h.SetPolicy(p)
+	return h
+}
+
+func runQuery(t *testing.T, h *Handler, in map[string]any) router.RouterResult {
+	t.Helper()
+	args, _ := json.Marshal(in)
+	res, err := h.Handle(context.Background(), "query", args)
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	var out router.RouterResult
+	if err := json.Unmarshal([]byte(res.Content[0].Text), &out); err != nil {
+		t.Fatalf("decode result: %v\n%s", err, res.Content[0].Text)
+	}
+	return out
+}
+
+func TestIterativeModeFeedsResultsBackToPlanner(t *testing.T) {
+	var rounds int32
+	fakePlanner(t, func(prompt string) string {
+		switch atomic.AddInt32(&rounds, 1) {
+		case 1:
+			return `{"steps":[{"name":"ci.list_jobs","source":"upstream","args":{}}],"final_answer_needed":false}`
+		case 2:
+			if !strings.Contains(prompt, "4711") {
+				t.Errorf("second round prompt lacks first round results")
+			}
+			return `{"steps":[{"name":"ci.get_log","source":"upstream","args":{"job":4711}}],"final_answer_needed":false}`
+		default:
+			return `{"steps":[],"done":true,"final_answer_needed":false}`
+		}
+	})
+	h := ciHandler(t)
+
+	res := runQuery(t, h, map[string]any{"input": "show the log of the failing job", "mode": "iterative"})
+	if len(res.ExecutedSteps) != 2 || !res.ExecutedSteps[1].OK || res.ExecutedSteps[1].Name != "ci.get_log" {
+		t.Fatalf("unexpected steps: %+v", res.ExecutedSteps)
+	}
+	if len(res.Plan.Steps) != 2 {
+		t.Fatalf("expected accumulated plan, got %+v", res.Plan)
+	}
+	dbg, _ := res.Debug.(map[string]any)
+	if dbg["rounds"] != float64(3) || dbg["stop_reason"] != "done" {
+		t.Fatalf("unexpected debug: %+v", res.Debug)
+	}
+}
+
+func TestIterativeModeStopsAtBudgets(t *testing.T) {
+	fakePlanner(t, func(string) string {
+		return `{"steps":[{"name":"ci.list_jobs","source":"upstream","args":{}}],"final_answer_needed":false}`
+	})
+	h := ciHandler(t)
+
+	res := runQuery(t, h, map[string]any{"input": "loop", "mode": "iterative", "max_steps": 3})
+	if len(res.ExecutedSteps) != 3 || res.Debug.(map[string]any)["stop_reason"] != "max_steps" {
+		t.Fatalf("expected stop at max_steps, got %d steps, debug %+v", len(res.ExecutedSteps), res.Debug)
+	}
+
+	res = runQuery(t, h, map[string]any{"input": "loop", "mode": "iterative", "token_budget": 1})
+	if len(res.ExecutedSteps) != 1 || res.Debug.(map[string]any)["stop_reason"] != "token_budget" {
+		t.Fatalf("expected stop at token budget, got %d steps, debug %+v", len(res.ExecutedSteps), res.Debug)
+	}
+}
+
+func TestIterativeModeRejectsSteps(t *testing.T) {
+	h := ciHandler(t)
+	args, _ := json.Marshal(map[string]any{"input": "x", "mode": "iterative", "steps": []map[string]any{{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}}}})
+	res, _ := h.Handle(context.Background(), "query", args)
+	if !res.IsError {
+		t.Fatalf("expected error for steps in iterative mode")
+	}
+	args, _ = json.Marshal(map[string]any{"input": "x", "mode": "iterative", "max_steps": 21})
+	res, _ = h.Handle(context.Background(), "query", args)
+	if !res.IsError {
+		t.Fatalf("expected error for max_steps above the iterative cap")
+	}
+}
+
+func TestIterativeModeRefsPointAtEarlierRounds(t *testing.T) {
+	var rounds int32
+	fakePlanner(t, func(prompt string) string {
+		switch atomic.AddInt32(&rounds, 1) {
+		case 1:
+			return `{"steps":[{"name":"ci.list_jobs","source":"upstream","args":{}}],"final_answer_needed":false}`
+		case 2:
+			if !strings.Contains(prompt, `"first_step_index": 1`) {
+				t.Errorf("second round prompt lacks first_step_index: %s", prompt)
+			}
+			// steps[0] is the first round's list_jobs; this round's steps are steps[1] and steps[2].
+			return `{"steps":[
+				{"name":"ci.get_log","source":"upstream","args":{"job":1}},
+				{"name":"ci.get_log","source":"upstream","args":{"job":{"$ref":"steps[0].result.jobs[0].id"}}}
+			],"final_answer_needed":false}`
+		default:
+			return `{"steps":[],"done":true,"final_answer_needed":false}`
+		}
+	})
+	h := ciHandler(t)
+
+	res := runQuery(t, h, map[string]any{"input": "show the log of the failing job", "mode": "iterative"})
+	if len(res.ExecutedSteps) != 3 || !res.ExecutedSteps[2].OK {
+		t.Fatalf("unexpected steps: %+v", res.ExecutedSteps)
+	}
+	var args struct{ Job int }
+	if err := json.Unmarshal(res.ExecutedSteps[2].Args, &args); err != nil || args.Job != 4711 {
+		t.Fatalf("expected the ref to resolve against round 1, got %s", res.ExecutedSteps[2].Args)
+	}
+}
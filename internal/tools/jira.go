@@ -284,7 +284,7 @@ func (j *jiraClient) do(ctx context.Context, method string, apiPath string, quer
 	return resp.StatusCode, resp.Header, b, nil
 }
 
-func jiraAuthHint(status int, body []byte) string {
+func jiraAuthHint(status int, hdr http.Header, body []byte) string {
 	switch status {
 	case http.StatusUnauthorized:
 		return "Jira API returned 401. Check auth env vars: (Cloud) JIRA_EMAIL + JIRA_API_TOKEN, (DC/Server) JIRA_PAT, (3LO) JIRA_OAUTH_ACCESS_TOKEN (+ JIRA_CLOUD_ID)."
@@ -294,7 +294,12 @@ func jiraAuthHint(status int, body []byte) string {
 		return "Jira API returned 404. Issue/project may not exist or your user/app lacks access (some Jira instances mask permission issues as 404)."
 	case http.StatusTooManyRequests:
 		// Cloud: points-based rate limiting.
-		return "Jira API returned 429 (rate limited). Respect Retry-After and retry with backoff."
+		hint := "Jira API returned 429 (rate limited). Respect Retry-After and retry with backoff."
+		if d, ok := parseRetryAfterSeconds(hdr); ok {
+			// executePlan's step retry reads this back (retryAfterFromError).
+			hint += fmt.Sprintf(" Retry-After: %d", int(d.Seconds()))
+		}
+		return hint
 	default:
 		// Best-effort hint for common auth denial responses.
 		if bytes.Contains(bytes.ToLower(body), []byte("captcha")) {
@@ -402,12 +407,12 @@ func (h *Handler) jiraGetMyself(ctx context.Context, args json.RawMessage) (*mcp
 	status, hdr, body, err := cl.do(ctx, http.MethodGet, "/myself", nil, nil, nil)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, body))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, body))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, body))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, hdr, body))), nil
 	}
 	return jsonResult(map[string]any{
 		"base_url":    cl.baseURL,
@@ -446,12 +451,12 @@ func (h *Handler) jiraGetIssue(ctx context.Context, args json.RawMessage) (*mcp.
 	status, hdr, body, err := cl.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(in.Issue), q, nil, nil)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, body))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, body))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, body))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, hdr, body))), nil
 	}
 	return jsonResult(mustUnmarshalAny(body)), nil
 }
@@ -496,12 +501,12 @@ func (h *Handler) jiraSearchIssues(ctx context.Context, args json.RawMessage) (*
 	status, hdr, body, err := cl.do(ctx, http.MethodGet, "/search", q, nil, nil)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, body))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, body))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, body))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, hdr, body))), nil
 	}
 	return jsonResult(mustUnmarshalAny(body)), nil
 }
@@ -542,12 +547,12 @@ func (h *Handler) jiraGetIssueComments(ctx context.Context, args json.RawMessage
 	status, hdr, body, err := cl.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(in.Issue)+"/comment", q, nil, nil)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, body))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, body))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, body))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, hdr, body))), nil
 	}
 	return jsonResult(mustUnmarshalAny(body)), nil
 }
@@ -574,12 +579,12 @@ func (h *Handler) jiraGetIssueTransitions(ctx context.Context, args json.RawMess
 	status, hdr, body, err := cl.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(in.Issue)+"/transitions", q, nil, nil)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, body))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, body))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, body))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, hdr, body))), nil
 	}
 	return jsonResult(mustUnmarshalAny(body)), nil
 }
@@ -632,12 +637,12 @@ func (h *Handler) jiraAddComment(ctx context.Context, args json.RawMessage) (*mc
 	status, hdr, respBody, err := cl.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(in.Issue)+"/comment", nil, nil, b)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, respBody))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, respBody))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(respBody)), jiraAuthHint(status, respBody))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(respBody)), jiraAuthHint(status, hdr, respBody))), nil
 	}
 	return jsonResult(mustUnmarshalAny(respBody)), nil
 }
@@ -684,7 +689,7 @@ func (h *Handler) jiraTransitionIssue(ctx context.Context, args json.RawMessage)
 	status, hdr, respBody, err := cl.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(in.Issue)+"/transitions", nil, nil, b)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, respBody))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, respBody))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
@@ -693,7 +698,7 @@ func (h *Handler) jiraTransitionIssue(ctx context.Context, args json.RawMessage)
 		return jsonResult(map[string]any{"ok": true, "status": status}), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(respBody)), jiraAuthHint(status, respBody))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(respBody)), jiraAuthHint(status, hdr, respBody))), nil
 	}
 	return jsonResult(mustUnmarshalAny(respBody)), nil
 }
@@ -732,12 +737,12 @@ func (h *Handler) jiraCreateIssue(ctx context.Context, args json.RawMessage) (*m
 	status, hdr, respBody, err := cl.do(ctx, http.MethodPost, "/issue", nil, nil, b)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, respBody))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, respBody))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(respBody)), jiraAuthHint(status, respBody))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(respBody)), jiraAuthHint(status, hdr, respBody))), nil
 	}
 	return jsonResult(mustUnmarshalAny(respBody)), nil
 }
@@ -769,7 +774,7 @@ func (h *Handler) jiraUpdateIssue(ctx context.Context, args json.RawMessage) (*m
 	status, hdr, respBody, err := cl.do(ctx, http.MethodPut, "/issue/"+url.PathEscape(in.Issue), nil, nil, b)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, respBody))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, respBody))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
@@ -777,7 +782,7 @@ func (h *Handler) jiraUpdateIssue(ctx context.Context, args json.RawMessage) (*m
 		return jsonResult(map[string]any{"ok": true, "status": status}), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(respBody)), jiraAuthHint(status, respBody))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(respBody)), jiraAuthHint(status, hdr, respBody))), nil
 	}
 	return jsonResult(mustUnmarshalAny(respBody)), nil
 }
@@ -841,7 +846,7 @@ func (h *Handler) jiraAddAttachment(ctx context.Context, args json.RawMessage) (
 		return errorResult(err.Error()), nil
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", resp.StatusCode, strings.TrimSpace(string(respBody)), jiraAuthHint(resp.StatusCode, respBody))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", resp.StatusCode, strings.TrimSpace(string(respBody)), jiraAuthHint(resp.StatusCode, resp.Header, respBody))), nil
 	}
 	return jsonResult(mustUnmarshalAny(respBody)), nil
 }
@@ -880,12 +885,12 @@ func (h *Handler) jiraListProjects(ctx context.Context, args json.RawMessage) (*
 		status, hdr, body, err := cl.do(ctx, http.MethodGet, "/project/search", q, nil, nil)
 		if err != nil {
 			if errors.Is(err, errJiraHTMLOrRedirect) {
-				return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, body))), nil
+				return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, body))), nil
 			}
 			return errorResult(err.Error()), nil
 		}
 		if status < 200 || status >= 300 {
-			return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, body))), nil
+			return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, hdr, body))), nil
 		}
 		return jsonResult(mustUnmarshalAny(body)), nil
 	}
@@ -893,12 +898,12 @@ func (h *Handler) jiraListProjects(ctx context.Context, args json.RawMessage) (*
 	status, hdr, body, err := cl.do(ctx, http.MethodGet, "/project", nil, nil, nil)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, body))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, body))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, body))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, hdr, body))), nil
 	}
 	return jsonResult(mustUnmarshalAny(body)), nil
 }
@@ -911,7 +916,7 @@ func mustUnmarshalAny(b []byte) any {
 	return v
 }
 
-// Optional helper for callers that want to implement backoff on 429.
+// parseRetryAfterSeconds reads a delay-seconds Retry-After header (HTTP dates are not supported).
 func parseRetryAfterSeconds(h http.Header) (time.Duration, bool) {
 	ra := strings.TrimSpace(h.Get("Retry-After"))
 	if ra == "" {
@@ -69,12 +69,12 @@ func (h *Handler) jiraGetIssueBundle(ctx context.Context, args json.RawMessage)
 	status, hdr, body, err := cl.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(in.Issue), q, nil, nil)
 	if err != nil {
 		if errors.Is(err, errJiraHTMLOrRedirect) {
-			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, body))), nil
+			return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, body))), nil
 		}
 		return errorResult(err.Error()), nil
 	}
 	if status < 200 || status >= 300 {
-		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, body))), nil
+		return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, hdr, body))), nil
 	}
 
 	out := map[string]any{
@@ -95,12 +95,12 @@ func (h *Handler) jiraGetIssueBundle(ctx context.Context, args json.RawMessage)
 		status, hdr, body, err := cl.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(in.Issue)+"/comment", qc, nil, nil)
 		if err != nil {
 			if errors.Is(err, errJiraHTMLOrRedirect) {
-				return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, body))), nil
+				return errorResult(fmt.Sprintf("Jira API returned HTML/redirect (likely login). status=%d location=%s\n%s", status, hdr.Get("Location"), jiraAuthHint(status, hdr, body))), nil
 			}
 			return errorResult(err.Error()), nil
 		}
 		if status < 200 || status >= 300 {
-			return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, body))), nil
+			return errorResult(fmt.Sprintf("Jira API error (%d): %s\n%s", status, strings.TrimSpace(string(body)), jiraAuthHint(status, hdr, body))), nil
 		}
 		commentsAny := mustUnmarshalAny(body)
 		out["comments"] = commentsAny
@@ -220,7 +220,7 @@ func (h *Handler) jiraExportTasks(ctx context.Context, args json.RawMessage) (*m
 			md.WriteString("\n```\n")
 		}
 
-		if err := os.WriteFile(issueFile, []byte(md.String()), 0o644); err != nil {
+		if err := h.writeRedactedFile(issueFile, md.String()); err != nil {
 			results = append(results, jiraExportIssueResult{Key: key, Summary: summary, Status: status, File: "", Warnings: []string{"failed to write file: " + err.Error()}})
 			continue
 		}
@@ -248,7 +248,7 @@ func (h *Handler) jiraExportTasks(ctx context.Context, args json.RawMessage) (*m
 		}
 		idx.WriteString(line + "\n")
 	}
-	_ = os.WriteFile(indexFile, []byte(idx.String()), 0o644)
+	_ = h.writeRedactedFile(indexFile, idx.String())
 
 	out := map[string]any{
 		"output_dir": in.OutputDir,
@@ -444,7 +444,7 @@ func (h *Handler) exportConfluencePage(ctx context.Context, outputDir string, pa
 		htmlOut.WriteString(bodyStorage)
 	}
 
-	if err := os.WriteFile(htmlPath, []byte(htmlOut.String()), 0o644); err != nil {
+	if err := h.writeRedactedFile(htmlPath, htmlOut.String()); err != nil {
 		return "", "", err
 	}
 
@@ -452,7 +452,7 @@ func (h *Handler) exportConfluencePage(ctx context.Context, outputDir string, pa
 	if strings.TrimSpace(text) == "" {
 		text = htmlToText(bodyView)
 	}
-	if err := os.WriteFile(textPath, []byte(text), 0o644); err != nil {
+	if err := h.writeRedactedFile(textPath, text); err != nil {
 		return "", "", err
 	}
 
@@ -61,7 +61,7 @@ func TestJiraExportTasksExpandsConfluenceLinks(t *testing.T) {
 	t.Cleanup(jiraSrv.Close)
 
 	reg := registry.NewRegistry()
-	h := NewHandler(reg, func(name string, args json.RawMessage) (*mcp.CallToolResult, error) { return nil, nil })
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) { return nil, nil })
 	reg.LoadTools(h.BuiltinTools())
 
 	outDir := t.TempDir()
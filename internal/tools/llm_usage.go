@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+type sessionKey struct{}
+
+// WithSession tags a tool call with the MCP session it came from ("" for stdio), so LLM usage
+// and budgets are kept per client of a shared --listen instance.
+func WithSession(ctx context.Context, session string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+func sessionFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(sessionKey{}).(string)
+	return s
+}
+
+// sessionUsage is the running LLM usage of one MCP session.
+type sessionUsage struct {
+	mu     sync.Mutex
+	totals router.UsageTotals
+}
+
+func (s *sessionUsage) add(u router.LLMUsage) {
+	s.mu.Lock()
+	s.totals.Add(u)
+	s.mu.Unlock()
+}
+
+func (s *sessionUsage) get() router.UsageTotals {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totals
+}
+
+// usageDebug is reported as llm_usage in RouterResult.Debug.
+type usageDebug struct {
+	Calls   []router.LLMUsage  `json:"calls,omitempty"`
+	Query   router.UsageTotals `json:"query"`
+	Session router.UsageTotals `json:"session"`
+	// BudgetExceeded is set when the session budget stopped planning or replaced the LLM summary.
+	BudgetExceeded string `json:"budget_exceeded,omitempty"`
+}
+
+// sessionUsage returns the usage of the session the call in ctx belongs to.
+func (h *Handler) sessionUsage(ctx context.Context) *sessionUsage {
+	session := sessionFromContext(ctx)
+	h.usageMu.Lock()
+	defer h.usageMu.Unlock()
+	u := h.usage[session]
+	if u == nil {
+		if h.usage == nil {
+			h.usage = map[string]*sessionUsage{}
+		}
+		u = &sessionUsage{}
+		h.usage[session] = u
+	}
+	return u
+}
+
+// EndSession drops the LLM usage of a closed MCP session.
+func (h *Handler) EndSession(session string) {
+	h.usageMu.Lock()
+	delete(h.usage, session)
+	h.usageMu.Unlock()
+}
+
+// meterLLM attaches a recorder for the LLM calls of one query; calls also count towards the session total.
+func (h *Handler) meterLLM(ctx context.Context) (context.Context, *router.UsageRecorder) {
+	rec := router.NewUsageRecorder(h.sessionUsage(ctx).add)
+	return router.WithUsageRecorder(ctx, rec), rec
+}
+
+// llmBudgetExceeded reports whether the session has used up max_tokens_total or max_cost_usd.
+func (h *Handler) llmBudgetExceeded(ctx context.Context) (string, bool) {
+	return h.llmConfig().Budget().Exceeded(h.sessionUsage(ctx).get())
+}
+
+func (h *Handler) usageDebug(ctx context.Context, rec *router.UsageRecorder, budgetExceeded string) *usageDebug {
+	return &usageDebug{
+		Calls:          rec.Calls(),
+		Query:          rec.Totals(),
+		Session:        h.sessionUsage(ctx).get(),
+		BudgetExceeded: budgetExceeded,
+	}
+}
+
+// executorSummary is the deterministic answer used when no LLM summary is made.
+func executorSummary(res router.RouterResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Executed %d step(s).\n", len(res.ExecutedSteps)))
+	for i, st := range res.ExecutedSteps {
+		if st.OK {
+			sb.WriteString(fmt.Sprintf("%d) %s: ok\n", i+1, st.Name))
+		} else {
+			sb.WriteString(fmt.Sprintf("%d) %s: error: %s\n", i+1, st.Name, st.Error))
+		}
+	}
+	if res.Manifest != nil && len(res.Manifest.Artifacts) > 0 {
+		sb.WriteString("\nArtifacts:\n")
+		for _, a := range res.Manifest.Artifacts {
+			sb.WriteString(fmt.Sprintf("- %s (%d bytes)\n", a.Path, a.Bytes))
+		}
+	}
+	return sb.String()
+}
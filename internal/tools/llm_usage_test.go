@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+func TestQueryReportsLLMUsageAndEnforcesBudget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   "vendor/planner-1",
+			"choices": []any{map[string]any{"message": map[string]any{"content": `{"steps":[{"name":"ci.list_jobs","source":"upstream","args":{}}],"final_answer_needed":true}`}, "finish_reason": "stop"}},
+			"usage":   map[string]any{"prompt_tokens": 80, "completion_tokens": 20, "total_tokens": 100},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OPENROUTER_API_KEY", "x")
+	t.Setenv("MCP_LENS_ROUTER_MODEL", "m")
+	t.Setenv("MCP_LENS_ROUTER_BASE_URL", srv.URL)
+	t.Setenv("MCP_LENS_LLM_INPUT_COST_PER_MTOK", "10")
+	t.Setenv("MCP_LENS_LLM_MAX_TOKENS_TOTAL", "100")
+	h := ciHandler(t)
+
+	// The plan call uses up the budget: the answer degrades to the executor summary.
+	res := runQuery(t, h, map[string]any{"input": "list jobs", "include_answer": true})
+	if len(res.ExecutedSteps) != 1 || !strings.HasPrefix(res.Answer, "Executed 1 step(s).") {
+		t.Fatalf("expected executed plan with executor summary, got %+v", res)
+	}
+	var dbg struct {
+		LLMUsage usageDebug `json:"llm_usage"`
+	}
+	b, _ := json.Marshal(res.Debug)
+	if err := json.Unmarshal(b, &dbg); err != nil {
+		t.Fatalf("decode debug: %v", err)
+	}
+	u := dbg.LLMUsage
+	if len(u.Calls) != 1 || u.Calls[0].Kind != "plan" || u.Calls[0].Model != "vendor/planner-1" || u.Calls[0].PromptTokens != 80 {
+		t.Fatalf("unexpected calls %+v", u.Calls)
+	}
+	if u.Query.TotalTokens != 100 || u.Session.TotalTokens != 100 || u.Query.CostUSD != 0.0008 {
+		t.Fatalf("unexpected totals query=%+v session=%+v", u.Query, u.Session)
+	}
+	if !strings.Contains(u.BudgetExceeded, "100/100 tokens") {
+		t.Fatalf("expected budget note, got %q", u.BudgetExceeded)
+	}
+
+	// Later planner queries are refused; executor mode still works.
+	args, _ := json.Marshal(map[string]any{"input": "list jobs again"})
+	out, err := h.Handle(context.Background(), "query", args)
+	if err != nil || !out.IsError || !strings.Contains(out.Content[0].Text, "LLM budget exceeded") {
+		t.Fatalf("expected refusal, got %+v", out)
+	}
+	res = runQuery(t, h, map[string]any{
+		"input": "list jobs",
+		"steps": []map[string]any{{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}}},
+	})
+	if len(res.ExecutedSteps) != 1 || !res.ExecutedSteps[0].OK {
+		t.Fatalf("expected executor mode to run, got %+v", res)
+	}
+}
+
+func TestLLMUsageIsPerSession(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"content": `{"steps":[{"name":"ci.list_jobs","source":"upstream","args":{}}],"final_answer_needed":false}`}, "finish_reason": "stop"}},
+			"usage":   map[string]any{"prompt_tokens": 80, "completion_tokens": 20, "total_tokens": 100},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OPENROUTER_API_KEY", "x")
+	t.Setenv("MCP_LENS_ROUTER_MODEL", "m")
+	t.Setenv("MCP_LENS_ROUTER_BASE_URL", srv.URL)
+	t.Setenv("MCP_LENS_LLM_MAX_TOKENS_TOTAL", "100")
+	h := ciHandler(t)
+
+	args, _ := json.Marshal(map[string]any{"input": "list jobs"})
+	query := func(session string) *mcp.CallToolResult {
+		out, err := h.Handle(WithSession(context.Background(), session), "query", args)
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		return out
+	}
+	if out := query("a"); out.IsError {
+		t.Fatalf("session a: unexpected error %+v", out)
+	}
+	if out := query("a"); !out.IsError || !strings.Contains(out.Content[0].Text, "LLM budget exceeded") {
+		t.Fatalf("session a: expected refusal, got %+v", out)
+	}
+	if out := query("b"); out.IsError {
+		t.Fatalf("session b must not share session a's budget, got %+v", out)
+	}
+	h.EndSession("a")
+	if out := query("a"); out.IsError {
+		t.Fatalf("expected a new session a to start with a fresh budget, got %+v", out)
+	}
+}
@@ -5,30 +5,98 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/golovatskygroup/mcp-lens/internal/artifacts"
 	"github.com/golovatskygroup/mcp-lens/internal/registry"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
 	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
 )
 
 // Handler processes local tool calls (meta-tools + proxy-provided tools).
 type Handler struct {
 	registry  *registry.Registry
-	executor  func(name string, args json.RawMessage) (*mcp.CallToolResult, error)
+	executor  func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error)
 	artifacts *artifacts.Store
 	execDeps  *ExecutionDependencies
+	policy    *router.Policy
+	pending   *pendingStore
+	audit     *auditLog
+	timeouts  callTimeouts
+	retry     stepRetry
+	llm       *router.LLMConfig
+	// usage is the LLM usage per MCP session (see WithSession).
+	usage   map[string]*sessionUsage
+	usageMu sync.Mutex
+	recipes *recipeStore
+	// extractors are the context extractors from config (see SetContextExtractors).
+	extractors []*router.RegexContextExtractor
+	// redactor removes secrets/PII from step results (nil: disabled).
+	redactor *router.Redactor
 }
 
 // NewHandler creates a new tool handler.
-func NewHandler(reg *registry.Registry, executor func(string, json.RawMessage) (*mcp.CallToolResult, error)) *Handler {
+func NewHandler(reg *registry.Registry, executor func(context.Context, string, json.RawMessage) (*mcp.CallToolResult, error)) *Handler {
 	st, _ := artifacts.NewFromEnv()
-	return &Handler{registry: reg, executor: executor, artifacts: st}
+	return &Handler{
+		registry:  reg,
+		executor:  executor,
+		artifacts: st,
+		pending:   newPendingStore(pendingTTLFromEnv()),
+		audit:     &auditLog{path: auditLogPathFromEnv()},
+		timeouts:  timeoutsFromEnv(),
+		retry:     stepRetryFromEnv(),
+		recipes:   newRecipeStore(recipesDirFromEnv()),
+		redactor:  redactorFromEnv(),
+	}
 }
 
 func (h *Handler) ArtifactStore() *artifacts.Store {
 	return h.artifacts
 }
 
+// SetPolicy replaces the router policy (default: router.DefaultPolicy()).
+func (h *Handler) SetPolicy(p router.Policy) {
+	h.policy = &p
+}
+
+// SetLLMConfig configures the router's LLM provider; empty fields fall back to environment variables.
+func (h *Handler) SetLLMConfig(cfg router.LLMConfig) {
+	h.llm = &cfg
+}
+
+// llmClient returns the configured LLM provider. Without provider config (default OpenRouter
+// but no key or model), it falls back to the MCP client's LLM when the client supports sampling.
+func (h *Handler) llmClient(ctx context.Context) (router.LLMClient, error) {
+	cfg := h.llmConfig()
+	cl, err := router.NewLLMClient(cfg)
+	if err != nil {
+		if create := samplingFromContext(ctx); create != nil && strings.TrimSpace(cfg.Provider) == "" {
+			return router.NewSamplingClient(create, cfg), nil
+		}
+		return nil, err
+	}
+	return cl, nil
+}
+
+// llmConfig returns the LLM config set by SetLLMConfig merged with environment variables.
+func (h *Handler) llmConfig() router.LLMConfig {
+	cfg := router.LLMConfigFromEnv()
+	if h.llm != nil {
+		cfg = h.llm.Merge(cfg)
+	}
+	return cfg
+}
+
+func (h *Handler) routerPolicy() router.Policy {
+	p := router.DefaultPolicy()
+	if h.policy != nil {
+		p = *h.policy
+	}
+	p.WriteMode = p.WriteMode || writeModeEnabled()
+	return p
+}
+
 // SetExecutionDependencies configures the code execution dependencies (Docker, native executor, tool registry, discovery).
 func (h *Handler) SetExecutionDependencies(deps *ExecutionDependencies) {
 	h.execDeps = deps
@@ -44,7 +112,7 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				"type": "object",
 				"properties": {
 					"query": {"type": "string", "description": "Search query (e.g., 'pull request', 'files changed', 'diff', 'review')"},
-					"category": {"type": "string", "description": "Filter by category", "enum": ["repository", "issues", "pull_requests", "reviews", "code_search", "branches", "releases", "users", "copilot", "sub_issues", "local"]},
+					"category": {"type": "string", "description": "Filter by category: repository, issues, pull_requests, reviews, code_search, branches, releases, users, copilot, sub_issues, local, or the name of a namespaced upstream (e.g. gitlab)"},
 					"limit": {"type": "integer", "description": "Max results (default: 10)", "default": 10},
 					"format": {"type": "string", "description": "Output format: text (default) or json", "enum": ["text", "json"], "default": "text"},
 					"include_schemas": {"type": "boolean", "description": "Include inputSchema for each tool (json format only)", "default": false}
@@ -159,6 +227,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["repo", "number"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"repo", "number", "title", "state", "draft", "html_url", "user.login", "base.ref", "head.ref", "head.sha", "merged", "mergeable", "updated_at"},
+				"metadata": {"repo", "number", "title", "state", "html_url", "head.sha"},
+			},
 		},
 		{
 			Name:        "list_pull_request_files",
@@ -173,6 +245,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["repo", "number"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"repo", "number", "page", "has_next", "next_page", "file_count", "files.filename", "files.status", "files.additions", "files.deletions", "files.changes"},
+				"metadata": {"repo", "number", "file_count", "files.filename", "files.status"},
+			},
 		},
 		{
 			Name:        "get_pull_request_diff",
@@ -200,6 +276,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["repo", "number"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"repo", "number", "title", "state", "author", "statistics", "files_by_status", "directories", "file_types"},
+				"metadata": {"repo", "number", "title", "state", "author", "statistics"},
+			},
 		},
 		{
 			Name:        "get_pull_request_file_diff",
@@ -227,6 +307,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["repo", "number"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"repo", "number", "page", "has_next", "next_page", "commit_count", "commits.sha", "commits.message", "commits.author.name", "commits.author.date", "commits.html_url"},
+				"metadata": {"repo", "number", "commit_count", "commits.sha", "commits.author.date"},
+			},
 		},
 		{
 			Name:        "get_pull_request_checks",
@@ -239,6 +323,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["repo", "number"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"repo", "number", "head_sha", "checks.total_count", "checks.check_runs.name", "checks.check_runs.status", "checks.check_runs.conclusion", "checks.check_runs.html_url"},
+				"metadata": {"repo", "number", "head_sha", "checks.check_runs.name", "checks.check_runs.conclusion"},
+			},
 		},
 		{
 			Name:        "get_file_at_ref",
@@ -269,6 +357,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["repo"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"total_count", "has_next", "next_page", "workflow_runs.id", "workflow_runs.name", "workflow_runs.event", "workflow_runs.status", "workflow_runs.conclusion", "workflow_runs.head_branch", "workflow_runs.head_sha", "workflow_runs.updated_at", "workflow_runs.html_url"},
+				"metadata": {"total_count", "workflow_runs.id", "workflow_runs.name", "workflow_runs.status", "workflow_runs.conclusion"},
+			},
 		},
 		{
 			Name:        "github_list_workflow_jobs",
@@ -283,6 +375,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["repo", "run_id"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"total_count", "has_next", "next_page", "jobs.id", "jobs.name", "jobs.status", "jobs.conclusion", "jobs.started_at", "jobs.completed_at", "jobs.html_url"},
+				"metadata": {"total_count", "jobs.id", "jobs.name", "jobs.conclusion"},
+			},
 		},
 		{
 			Name:        "github_download_job_logs",
@@ -355,6 +451,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"api_version": {"type": "integer", "description": "REST API version (2 or 3). Default: 2", "enum": [2,3], "default": 2}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"accountId", "name", "key", "displayName", "emailAddress", "active", "timeZone"},
+				"metadata": {"accountId", "name", "displayName"},
+			},
 		},
 		{
 			Name:        "jira_get_issue",
@@ -371,6 +471,13 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["issue"]
 			}`),
+			Views: map[string][]string{
+				"summary": {
+					"key", "fields.summary", "fields.status.name", "fields.issuetype.name", "fields.priority.name",
+					"fields.assignee.displayName", "fields.reporter.displayName", "fields.labels", "fields.created", "fields.updated",
+				},
+				"metadata": {"key", "fields.summary", "fields.status.name", "fields.assignee.displayName"},
+			},
 		},
 		{
 			Name:        "jira_get_issue_bundle",
@@ -392,6 +499,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["issue"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"issue.key", "issue.fields.summary", "issue.fields.status.name", "issue.fields.issuetype.name", "issue.fields.priority.name", "issue.fields.assignee.displayName", "issue.fields.updated", "comments.total", "comments.comments.id", "comments.comments.author.displayName", "comments.comments.created", "comments.comments.body", "comments_has_next", "comments_next_startAt"},
+				"metadata": {"issue.key", "issue.fields.summary", "issue.fields.status.name", "comments.total"},
+			},
 		},
 		{
 			Name:        "jira_search_issues",
@@ -411,6 +522,13 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["jql"]
 			}`),
+			Views: map[string][]string{
+				"summary": {
+					"startAt", "maxResults", "total", "issues.key", "issues.fields.summary", "issues.fields.status.name",
+					"issues.fields.issuetype.name", "issues.fields.priority.name", "issues.fields.assignee.displayName", "issues.fields.updated",
+				},
+				"metadata": {"total", "issues.key", "issues.fields.summary", "issues.fields.status.name"},
+			},
 		},
 		{
 			Name:        "jira_get_issue_comments",
@@ -429,6 +547,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["issue"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"startAt", "maxResults", "total", "comments.id", "comments.author.displayName", "comments.created", "comments.updated", "comments.body"},
+				"metadata": {"total", "comments.id", "comments.author.displayName", "comments.created"},
+			},
 		},
 		{
 			Name:        "jira_get_issue_transitions",
@@ -444,6 +566,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["issue"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"transitions.id", "transitions.name", "transitions.to.name", "transitions.to.statusCategory.name"},
+				"metadata": {"transitions.id", "transitions.name"},
+			},
 		},
 		{
 			Name:        "jira_list_projects",
@@ -460,6 +586,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"api_version": {"type": "integer", "description": "REST API version (2 or 3). Default: 2", "enum": [2,3], "default": 2}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"startAt", "maxResults", "total", "isLast", "values.id", "values.key", "values.name", "values.projectTypeKey", "id", "key", "name", "projectTypeKey"},
+				"metadata": {"total", "values.key", "values.name", "key", "name"},
+			},
 		},
 		{
 			Name:        "jira_export_tasks",
@@ -575,6 +705,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"start": {"type": "integer", "description": "Pagination start offset (v1).", "default": 0}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"limit", "has_next", "next_cursor", "next_start", "data.results.id", "data.results.key", "data.results.name", "data.results.type", "data.results.status"},
+				"metadata": {"has_next", "data.results.key", "data.results.name"},
+			},
 		},
 		{
 			Name:        "confluence_get_page",
@@ -591,6 +725,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["id"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"id", "title", "status", "type", "spaceId", "parentId", "space.key", "version.number", "version.createdAt", "version.when", "_links.webui"},
+				"metadata": {"id", "title", "spaceId", "space.key", "version.number"},
+			},
 		},
 		{
 			Name:        "confluence_get_page_by_title",
@@ -608,6 +746,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["space_key","title"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"space_key", "title", "count", "page.id", "page.title", "page.type", "page.status", "page.version.number", "page.version.when", "page._links.webui"},
+				"metadata": {"space_key", "title", "page.id", "page.version.number"},
+			},
 		},
 		{
 			Name:        "confluence_search_cql",
@@ -625,6 +767,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["cql"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"cql", "has_next", "next_cursor", "next_start", "data.totalSize", "data.results.content.id", "data.results.content.type", "data.results.title", "data.results.excerpt", "data.results.url", "data.results.lastModified", "data.results.resultGlobalContainer.title"},
+				"metadata": {"cql", "data.totalSize", "data.results.content.id", "data.results.title"},
+			},
 		},
 		{
 			Name:        "confluence_get_page_children",
@@ -641,6 +787,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["page_id"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"has_next", "next_cursor", "next_start", "result.page.results.id", "result.page.results.title", "result.page.results.type", "result.page.results._links.webui"},
+				"metadata": {"has_next", "result.page.results.id", "result.page.results.title"},
+			},
 		},
 		{
 			Name:        "confluence_list_page_attachments",
@@ -657,6 +807,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["page_id"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"has_next", "next_start", "result.results.id", "result.results.title", "result.results.metadata.mediaType", "result.results.extensions.fileSize", "result.results._links.download"},
+				"metadata": {"has_next", "result.results.id", "result.results.title"},
+			},
 		},
 		{
 			Name:        "confluence_download_attachment",
@@ -700,6 +854,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"status", "health.database", "health.version", "health.commit"},
+				"metadata": {"status", "health.version"},
+			},
 		},
 		{
 			Name:        "grafana_get_current_user",
@@ -716,6 +874,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"id", "login", "email", "name", "orgId", "isGrafanaAdmin"},
+				"metadata": {"id", "login", "name"},
+			},
 		},
 		{
 			Name:        "grafana_search",
@@ -740,6 +902,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"page", "count", "has_next", "next_page", "items.uid", "items.title", "items.type", "items.url", "items.folderTitle", "items.tags"},
+				"metadata": {"count", "items.uid", "items.title", "items.type"},
+			},
 		},
 		{
 			Name:        "grafana_get_dashboard",
@@ -758,6 +924,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["uid"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"meta.slug", "dashboard.uid", "dashboard.title", "dashboard.tags", "dashboard.time", "dashboard.templating.list"},
+				"metadata": {"dashboard.uid", "dashboard.title", "meta.slug"},
+			},
 		},
 		{
 			Name:        "grafana_get_dashboard_summary",
@@ -778,6 +948,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"uid", "title", "tags", "dashboard_url", "folder_title", "time", "variables", "panel_count", "panels.title", "panels.type"},
+				"metadata": {"uid", "title", "dashboard_url", "folder_title", "panel_count"},
+			},
 		},
 		{
 			Name:        "grafana_list_folders",
@@ -797,6 +971,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"page", "count", "has_next", "next_page", "items.uid", "items.title", "items.url"},
+				"metadata": {"count", "items.uid", "items.title"},
+			},
 		},
 		{
 			Name:        "grafana_get_folder",
@@ -815,6 +993,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["uid"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"uid", "title", "url", "parentUid", "created", "updated"},
+				"metadata": {"uid", "title"},
+			},
 		},
 		{
 			Name:        "grafana_list_datasources",
@@ -831,6 +1013,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"uid", "name", "type", "url", "access", "isDefault"},
+				"metadata": {"uid", "name", "type"},
+			},
 		},
 		{
 			Name:        "grafana_get_datasource",
@@ -849,6 +1035,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"uid", "name", "type", "url", "access", "isDefault", "database"},
+				"metadata": {"uid", "name", "type"},
+			},
 		},
 		{
 			Name:        "grafana_query_annotations",
@@ -876,6 +1066,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"count", "items.id", "items.time", "items.timeEnd", "items.text", "items.tags", "items.dashboardUID", "items.panelId"},
+				"metadata": {"count", "items.id", "items.time", "items.text"},
+			},
 		},
 		{
 			Name:        "grafana_list_annotation_tags",
@@ -894,6 +1088,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"result.tags.tag", "result.tags.count"},
+				"metadata": {"result.tags.tag"},
+			},
 		},
 		{
 			Name:        "grafana_list_alerts",
@@ -915,6 +1113,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"alerts.id", "alerts.name", "alerts.state", "alerts.newStateDate", "alerts.dashboardUid", "alerts.panelId", "alerts.url"},
+				"metadata": {"alerts.id", "alerts.name", "alerts.state"},
+			},
 		},
 		{
 			Name:        "grafana_get_alert",
@@ -933,6 +1135,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["id"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"Id", "Name", "State", "Message", "NewStateDate", "DashboardUid", "PanelId", "ExecutionError"},
+				"metadata": {"Id", "Name", "State"},
+			},
 		},
 		{
 			Name:        "grafana_list_alert_rules",
@@ -949,6 +1155,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 					"user_agent": {"type": "string", "description": "Override User-Agent header."}
 				}
 			}`),
+			Views: map[string][]string{
+				"summary":  {"api", "rules.uid", "rules.title", "rules.folderUID", "rules.ruleGroup", "rules.for", "rules.labels", "rules.isPaused", "rules.updated"},
+				"metadata": {"api", "rules.uid", "rules.title", "rules.ruleGroup"},
+			},
 		},
 		{
 			Name:        "grafana_get_alert_rule",
@@ -967,6 +1177,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				},
 				"required": ["uid"]
 			}`),
+			Views: map[string][]string{
+				"summary":  {"uid", "title", "folderUID", "ruleGroup", "condition", "for", "labels", "annotations", "isPaused", "updated"},
+				"metadata": {"uid", "title", "ruleGroup"},
+			},
 		},
 		{
 			Name:        "router",
@@ -976,7 +1190,7 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				"properties": {
 					"input": {"type": "string", "description": "User request / task (free-form). Use this when you don't know which tool to call."},
 					"context": {"type": "object", "description": "Optional structured context"},
-					"mode": {"type": "string", "description": "Router mode. auto=plan+execute (default), planner=plan only, executor=execute provided steps only", "enum": ["auto", "planner", "executor"], "default": "auto"},
+					"mode": {"type": "string", "description": "Router mode. auto=plan+execute (default), planner=plan only, executor=execute provided steps only, iterative=plan/execute in rounds, feeding results back to the planner until done or out of budget", "enum": ["auto", "planner", "executor", "iterative"], "default": "auto"},
 					"steps": {
 						"type": "array",
 						"description": "Optional explicit execution plan (executor mode). If provided, the router will validate + execute these steps without calling the planner model.",
@@ -987,7 +1201,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 								"source": {"type": "string", "enum": ["local", "upstream"]},
 								"args": {"type": "object"},
 								"reason": {"type": "string"},
-								"parallel_group": {"type": "string", "description": "Optional group id for parallel execution (requires parallelism > 1)."}
+								"parallel_group": {"type": "string", "description": "Optional group id for parallel execution (requires parallelism > 1)."},
+								"on_error": {"type": "string", "description": "What to do when the step still fails after retries: fail (default) stops the plan, continue goes on (result is marked partial), fallback runs the fallback step instead.", "enum": ["fail", "continue", "fallback"]},
+								"fallback": {"type": "object", "description": "Read-only step (name, source, args) run when on_error=fallback and this step fails."},
+								"output": {"type": "object", "description": "Output shaping for this step only (same fields as output); replaces the query-level output."}
 							},
 							"required": ["name", "source", "args"]
 						}
@@ -1001,14 +1218,32 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 							"exclude_fields": {"type": "array", "items": {"type": "string"}, "description": "Blacklist of fields/paths to remove"},
 							"max_items": {"type": "integer", "description": "Max items for arrays (applied recursively)"},
 							"max_depth": {"type": "integer", "description": "Max nesting depth (objects/arrays deeper than this are replaced with '<truncated>')"},
-							"redact": {"type": "array", "items": {"type": "string"}, "description": "Paths to redact (replaced with '[REDACTED]')"}
+							"redact": {"type": "array", "items": {"type": "string"}, "description": "Paths to redact (replaced with '[REDACTED]')"},
+							"expr": {"type": "string", "description": "jq expression applied to each result first, e.g. '[.check_runs[] | select(.conclusion == \"failure\") | {name, conclusion}]'. Several outputs become an array; errors are reported per step as output_error."}
 						}
 					},
-					"max_steps": {"type": "integer", "description": "Max steps (default: 5, max: 8)", "default": 5},
+					"max_steps": {"type": "integer", "description": "Max steps (default: 5, max: 8; iterative: total across rounds, default: 10, max: 20)", "default": 5},
 					"parallelism": {"type": "integer", "description": "Max parallelism for steps with the same parallel_group (default: 1).", "default": 1, "minimum": 1, "maximum": 8},
 					"include_answer": {"type": "boolean", "description": "Also produce a final human-readable answer", "default": false},
 					"dry_run": {"type": "boolean", "description": "Return plan only; do not execute tools", "default": false},
-					"format": {"type": "string", "description": "Output format", "enum": ["json", "text"], "default": "json"}
+					"format": {"type": "string", "description": "Output format. markdown_table/csv/tsv render each step result (an array of objects, or the largest array of objects in it) as a table; large tables are stored as artifacts.", "enum": ["json", "text", "markdown_table", "csv", "tsv"], "default": "json"},
+					"columns": {"type": "array", "items": {"type": "string"}, "description": "Table columns in order, as paths into each row (e.g. 'key', 'fields.assignee.displayName'). Default: all fields, nested objects flattened."},
+					"confirm": {"type": "string", "description": "Write mode: confirmation token from a previous result's pending.token. Executes the held plan; other fields except format are ignored."},
+					"token_budget": {"type": "integer", "description": "Iterative mode: max estimated planner tokens across rounds (default: 100000)", "minimum": 1},
+					"timeout_seconds": {"type": "integer", "description": "Overall deadline for this call in seconds; can only shorten the server limit (MCP_LENS_QUERY_TIMEOUT_SECONDS, default 600).", "minimum": 1},
+					"recipe": {"type": "string", "description": "Run a saved recipe (parameterized plan template from config or save_recipe) in executor mode, without the planner. input is optional."},
+					"params": {"type": "object", "description": "Recipe parameters (typed as declared by the recipe)."},
+					"answer_schema": {"type": "object", "description": "JSON Schema for a structured final answer (implies include_answer). The answer is returned as structured_answer and MCP structuredContent; answer_error explains a failure."},
+					"save_recipe": {
+						"type": "object",
+						"description": "Save this query's plan as a recipe if every step succeeds. Args equal to a params value become that parameter.",
+						"properties": {
+							"name": {"type": "string"},
+							"description": {"type": "string"},
+							"params": {"type": "object", "description": "Parameter name -> value used in this query"}
+						},
+						"required": ["name"]
+					}
 				},
 				"required": ["input"]
 			}`),
@@ -1021,7 +1256,7 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 				"properties": {
 					"input": {"type": "string", "description": "User request / task (free-form)"},
 					"context": {"type": "object", "description": "Optional structured context"},
-					"mode": {"type": "string", "description": "Router mode. auto=plan+execute (default), planner=plan only, executor=execute provided steps only", "enum": ["auto", "planner", "executor"], "default": "auto"},
+					"mode": {"type": "string", "description": "Router mode. auto=plan+execute (default), planner=plan only, executor=execute provided steps only, iterative=plan/execute in rounds, feeding results back to the planner until done or out of budget", "enum": ["auto", "planner", "executor", "iterative"], "default": "auto"},
 					"steps": {
 						"type": "array",
 						"description": "Optional explicit execution plan (executor mode). If provided, the router will validate + execute these steps without calling the planner model.",
@@ -1032,7 +1267,10 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 								"source": {"type": "string", "enum": ["local", "upstream"]},
 								"args": {"type": "object"},
 								"reason": {"type": "string"},
-								"parallel_group": {"type": "string", "description": "Optional group id for parallel execution (requires parallelism > 1)."}
+								"parallel_group": {"type": "string", "description": "Optional group id for parallel execution (requires parallelism > 1)."},
+								"on_error": {"type": "string", "description": "What to do when the step still fails after retries: fail (default) stops the plan, continue goes on (result is marked partial), fallback runs the fallback step instead.", "enum": ["fail", "continue", "fallback"]},
+								"fallback": {"type": "object", "description": "Read-only step (name, source, args) run when on_error=fallback and this step fails."},
+								"output": {"type": "object", "description": "Output shaping for this step only (same fields as output); replaces the query-level output."}
 							},
 							"required": ["name", "source", "args"]
 						}
@@ -1046,14 +1284,32 @@ func (h *Handler) BuiltinTools() []mcp.Tool {
 							"exclude_fields": {"type": "array", "items": {"type": "string"}, "description": "Blacklist of fields/paths to remove"},
 							"max_items": {"type": "integer", "description": "Max items for arrays (applied recursively)"},
 							"max_depth": {"type": "integer", "description": "Max nesting depth (objects/arrays deeper than this are replaced with '<truncated>')"},
-							"redact": {"type": "array", "items": {"type": "string"}, "description": "Paths to redact (replaced with '[REDACTED]')"}
+							"redact": {"type": "array", "items": {"type": "string"}, "description": "Paths to redact (replaced with '[REDACTED]')"},
+							"expr": {"type": "string", "description": "jq expression applied to each result first, e.g. '[.check_runs[] | select(.conclusion == \"failure\") | {name, conclusion}]'. Several outputs become an array; errors are reported per step as output_error."}
 						}
 					},
-					"max_steps": {"type": "integer", "description": "Max steps (default: 5, max: 8)", "default": 5},
+					"max_steps": {"type": "integer", "description": "Max steps (default: 5, max: 8; iterative: total across rounds, default: 10, max: 20)", "default": 5},
 					"parallelism": {"type": "integer", "description": "Max parallelism for steps with the same parallel_group (default: 1).", "default": 1, "minimum": 1, "maximum": 8},
 					"include_answer": {"type": "boolean", "description": "Also produce a final human-readable answer", "default": false},
 					"dry_run": {"type": "boolean", "description": "Return plan only; do not execute tools", "default": false},
-					"format": {"type": "string", "description": "Output format", "enum": ["json", "text"], "default": "json"}
+					"format": {"type": "string", "description": "Output format. markdown_table/csv/tsv render each step result (an array of objects, or the largest array of objects in it) as a table; large tables are stored as artifacts.", "enum": ["json", "text", "markdown_table", "csv", "tsv"], "default": "json"},
+					"columns": {"type": "array", "items": {"type": "string"}, "description": "Table columns in order, as paths into each row (e.g. 'key', 'fields.assignee.displayName'). Default: all fields, nested objects flattened."},
+					"confirm": {"type": "string", "description": "Write mode: confirmation token from a previous result's pending.token. Executes the held plan; other fields except format are ignored."},
+					"token_budget": {"type": "integer", "description": "Iterative mode: max estimated planner tokens across rounds (default: 100000)", "minimum": 1},
+					"timeout_seconds": {"type": "integer", "description": "Overall deadline for this call in seconds; can only shorten the server limit (MCP_LENS_QUERY_TIMEOUT_SECONDS, default 600).", "minimum": 1},
+					"recipe": {"type": "string", "description": "Run a saved recipe (parameterized plan template from config or save_recipe) in executor mode, without the planner. input is optional."},
+					"params": {"type": "object", "description": "Recipe parameters (typed as declared by the recipe)."},
+					"answer_schema": {"type": "object", "description": "JSON Schema for a structured final answer (implies include_answer). The answer is returned as structured_answer and MCP structuredContent; answer_error explains a failure."},
+					"save_recipe": {
+						"type": "object",
+						"description": "Save this query's plan as a recipe if every step succeeds. Args equal to a params value become that parameter.",
+						"properties": {
+							"name": {"type": "string"},
+							"description": {"type": "string"},
+							"params": {"type": "object", "description": "Parameter name -> value used in this query"}
+						},
+						"required": ["name"]
+					}
 				},
 				"required": ["input"]
 			}`),
@@ -1091,7 +1347,7 @@ func (h *Handler) Handle(ctx context.Context, name string, args json.RawMessage)
 	case "describe_tool":
 		return h.handleDescribe(args)
 	case "execute_tool":
-		return h.handleExecute(args)
+		return h.handleExecute(ctx, args)
 	case "dev_scaffold_tool":
 		return h.devScaffoldTool(ctx, args)
 	case "artifact_save_text":
@@ -1500,6 +1756,9 @@ func (h *Handler) handleDescribe(args json.RawMessage) (*mcp.CallToolResult, err
 	// Local tools first.
 	for _, t := range h.BuiltinTools() {
 		if t.Name == input.Name {
+			if views := h.registry.Views(t.Name); len(views) > 0 {
+				t.Views = views
+			}
 			return formatTool(t), nil
 		}
 	}
@@ -1508,9 +1767,24 @@ func (h *Handler) handleDescribe(args json.RawMessage) (*mcp.CallToolResult, err
 	if !ok {
 		return errorResult(fmt.Sprintf("Tool '%s' not found. Use search_tools to find available tools.", input.Name)), nil
 	}
+	tool.Views = h.registry.Views(input.Name)
 	return formatTool(tool), nil
 }
 
+// SetToolViews configures output view presets (tool name -> view -> paths) for upstream tools
+// or to override those declared by local tools.
+func (h *Handler) SetToolViews(views map[string]router.ViewPresets) error {
+	m := make(map[string]map[string][]string, len(views))
+	for name, v := range views {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("tool_views %s: %w", name, err)
+		}
+		m[name] = v
+	}
+	h.registry.SetViews(m)
+	return nil
+}
+
 func formatTool(tool mcp.Tool) *mcp.CallToolResult {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("## %s\n\n", tool.Name))
@@ -1523,10 +1797,17 @@ func formatTool(tool mcp.Tool) *mcp.CallToolResult {
 	sb.Write(schemaBytes)
 	sb.WriteString("\n```\n")
 
+	if views := router.ViewPresets(tool.Views); len(views) > 0 {
+		sb.WriteString("\n**Output views** (output.view):\n")
+		for _, v := range views.Views() {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", v, strings.Join(views[v], ", ")))
+		}
+	}
+
 	return textResult(sb.String())
 }
 
-func (h *Handler) handleExecute(args json.RawMessage) (*mcp.CallToolResult, error) {
+func (h *Handler) handleExecute(ctx context.Context, args json.RawMessage) (*mcp.CallToolResult, error) {
 	var input ExecuteToolInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return errorResult("Invalid input: " + err.Error()), nil
@@ -1543,7 +1824,7 @@ func (h *Handler) handleExecute(args json.RawMessage) (*mcp.CallToolResult, erro
 	// Activate tool so it appears in tools/list for the session.
 	h.registry.Activate(input.Name)
 
-	return h.executor(input.Name, input.Params)
+	return h.executor(ctx, input.Name, input.Params)
 }
 
 func textResult(text string) *mcp.CallToolResult {
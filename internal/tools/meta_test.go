@@ -60,7 +60,7 @@ func TestHandleQueryWithoutEnv(t *testing.T) {
 	os.Unsetenv("MCP_LENS_ROUTER_MODEL")
 
 	reg := registry.NewRegistry()
-	h := NewHandler(reg, func(name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
 		return &mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: "ok"}}}, nil
 	})
 
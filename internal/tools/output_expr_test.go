@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestQueryOutputExprPerStep(t *testing.T) {
+	h := ciHandler(t)
+	res := runQuery(t, h, map[string]any{
+		"input":  "failed jobs",
+		"output": map[string]any{"expr": `[.jobs[] | select(.status == "failed") | .id]`},
+		"steps": []map[string]any{
+			{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}},
+			{"name": "ci.get_log", "source": "upstream", "args": map[string]any{"job": 4711}, "output": map[string]any{"expr": ".log"}},
+			{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}, "output": map[string]any{"expr": ".jobs.missing"}},
+		},
+	})
+	if len(res.ExecutedSteps) != 3 {
+		t.Fatalf("expected 3 steps, got %+v", res.ExecutedSteps)
+	}
+	if b, _ := json.Marshal(res.ExecutedSteps[0].Result); string(b) != "[4711]" {
+		t.Fatalf("query-level expr: got %s", b)
+	}
+	if res.ExecutedSteps[1].Result != "panic: boom" {
+		t.Fatalf("step override: got %v", res.ExecutedSteps[1].Result)
+	}
+	// A failing expression is reported on the step; the step keeps its unfiltered result.
+	st := res.ExecutedSteps[2]
+	if !st.OK || st.OutputError == "" || st.Result == nil {
+		t.Fatalf("expected output_error with result kept, got %+v", st)
+	}
+
+	// Syntax errors are rejected before anything runs.
+	args, _ := json.Marshal(map[string]any{
+		"input": "x",
+		"steps": []map[string]any{{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}, "output": map[string]any{"expr": ".jobs |"}}},
+	})
+	if out, err := h.Handle(t.Context(), "query", args); err != nil || !out.IsError {
+		t.Fatalf("expected validation error, got %+v", out)
+	}
+}
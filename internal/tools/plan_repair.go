@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+const defaultPlanRepairAttempts = 2
+
+// planRepairAttemptsFromEnv reads MCP_LENS_PLAN_REPAIR_ATTEMPTS (default 2; 0 disables repair).
+func planRepairAttemptsFromEnv() int {
+	if v := strings.TrimSpace(os.Getenv("MCP_LENS_PLAN_REPAIR_ATTEMPTS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultPlanRepairAttempts
+}
+
+// planRepair records a model plan rejected by ValidatePlan.
+type planRepair struct {
+	Round   int             `json:"round,omitempty"` // iterative mode
+	Attempt int             `json:"attempt"`         // 0 = the original plan
+	Error   string          `json:"error"`
+	Step    *int            `json:"step,omitempty"` // index of the offending step
+	Plan    json.RawMessage `json:"plan"`
+}
+
+// planDebug is reported in RouterResult.Debug by the planner path.
+type planDebug struct {
+	PlanRepairs []planRepair `json:"plan_repairs,omitempty"`
+	LLMUsage    *usageDebug  `json:"llm_usage,omitempty"`
+}
+
+// applyContextToPlan threads client selection and extracted URL/ID context into the plan
+// deterministically (do not rely on the model to do it).
+func (h *Handler) applyContextToPlan(plan *router.ModelPlan, ctx map[string]any) {
+	h.applyJiraClientToPlan(plan, ctx)
+	h.applyConfluenceClientToPlan(plan, ctx)
+	h.applyGrafanaClientToPlan(plan, ctx)
+	h.applyExtractedContextToPlan(plan, ctx)
+}
+
+// validateWithRepair validates a model plan against the full catalog; while it is rejected, the validation
+// error and the offending step are sent back to the model for a corrected plan, up to
+// MCP_LENS_PLAN_REPAIR_ATTEMPTS times and while the session's LLM budget lasts. The repair prompt uses the planning catalog with the schemas
+// of the plan's tools filled in.
+// It returns the last plan and its raw JSON, the rejected attempts, the estimated repair tokens and the
+// final validation error (nil when the plan is valid).
+func (h *Handler) validateWithRepair(ctx context.Context, cl router.LLMClient, in routerInput, policy router.Policy, catalog []router.ToolCatalogItem, prompt []router.ToolCatalogItem, maxSteps int, history []router.ExecutedStep, plan router.ModelPlan, raw []byte) (router.ModelPlan, []byte, []planRepair, int, error) {
+	maxAttempts := planRepairAttemptsFromEnv()
+	var repairs []planRepair
+	tokens := 0
+	for attempt := 0; ; attempt++ {
+		h.applyContextToPlan(&plan, in.Context)
+		verr := router.ValidatePlanFrom(plan, policy, catalog, maxSteps, len(history))
+		if verr == nil {
+			return plan, raw, repairs, tokens, nil
+		}
+
+		rec := planRepair{Attempt: attempt, Error: verr.Error(), Plan: raw}
+		var stepErr *router.PlanStepError
+		if errors.As(verr, &stepErr) {
+			idx := stepErr.Index
+			rec.Step = &idx
+		}
+		if !json.Valid(rec.Plan) {
+			rec.Plan, _ = json.Marshal(string(raw))
+		}
+		repairs = append(repairs, rec)
+		if attempt >= maxAttempts || ctx.Err() != nil {
+			return plan, raw, repairs, tokens, verr
+		}
+		if reason, over := h.llmBudgetExceeded(ctx); over {
+			repairs = append(repairs, planRepair{Attempt: attempt + 1, Error: "repair skipped: " + reason, Plan: json.RawMessage("null")})
+			return plan, raw, repairs, tokens, verr
+		}
+
+		repaired, repairedRaw, used, err := router.RepairPlan(ctx, cl, in.Input, in.Context, expandCatalog(prompt, catalog, planToolNames(plan)), maxSteps, history, plan, verr)
+		tokens += used
+		if err != nil {
+			// Keep reporting the validation error; the failed repair call is visible in the attempts.
+			repairs = append(repairs, planRepair{Attempt: attempt + 1, Error: "repair failed: " + err.Error(), Plan: json.RawMessage("null")})
+			return plan, raw, repairs, tokens, verr
+		}
+		plan, raw = repaired, repairedRaw
+	}
+}
+
+// planRepairsText renders rejected attempts for error results.
+func planRepairsText(repairs []planRepair) string {
+	if len(repairs) <= 1 {
+		return ""
+	}
+	b, _ := json.Marshal(repairs)
+	return "\nplan_repairs=" + string(b)
+}
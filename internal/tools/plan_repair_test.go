@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPlannerRepairsInvalidPlan(t *testing.T) {
+	var calls int32
+	fakePlanner(t, func(prompt string) string {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// Missing required arg "job".
+			return `{"steps":[{"name":"ci.list_jobs","source":"upstream","args":{}},{"name":"ci.get_log","source":"upstream","args":{}}],"final_answer_needed":false}`
+		}
+		if !strings.Contains(prompt, "validation_error") || !strings.Contains(prompt, `"offending_step"`) {
+			t.Errorf("repair prompt lacks the validation error or offending step")
+		}
+		return `{"steps":[{"name":"ci.list_jobs","source":"upstream","args":{}},{"name":"ci.get_log","source":"upstream","args":{"job":1}}],"final_answer_needed":false}`
+	})
+	h := ciHandler(t)
+
+	res := runQuery(t, h, map[string]any{"input": "show the failing log"})
+	if len(res.ExecutedSteps) != 2 || !res.ExecutedSteps[1].OK {
+		t.Fatalf("expected repaired plan to run, got %+v", res.ExecutedSteps)
+	}
+	dbg, _ := res.Debug.(map[string]any)
+	repairs, _ := dbg["plan_repairs"].([]any)
+	if len(repairs) != 1 {
+		t.Fatalf("expected one recorded rejection, got %+v", res.Debug)
+	}
+	first, _ := repairs[0].(map[string]any)
+	if first["attempt"] != float64(0) || first["step"] != float64(1) || first["error"] == "" || first["plan"] == nil {
+		t.Fatalf("unexpected attempt record: %+v", first)
+	}
+}
+
+func TestPlannerRepairGivesUpAfterMaxAttempts(t *testing.T) {
+	t.Setenv("MCP_LENS_PLAN_REPAIR_ATTEMPTS", "1")
+	var calls int32
+	fakePlanner(t, func(string) string {
+		atomic.AddInt32(&calls, 1)
+		return `{"steps":[{"name":"no_such_tool","source":"upstream","args":{}}],"final_answer_needed":false}`
+	})
+	h := ciHandler(t)
+
+	args, _ := json.Marshal(map[string]any{"input": "x"})
+	res, err := h.Handle(context.Background(), "query", args)
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if !res.IsError || !strings.Contains(res.Content[0].Text, "unknown tool") || !strings.Contains(res.Content[0].Text, "plan_repairs=") {
+		t.Fatalf("expected validation error with attempts, got %+v", res.Content)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("expected plan + 1 repair call, got %d", n)
+	}
+}
+
+func TestPlannerRepairStopsAtLLMBudget(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"content": `{"steps":[{"name":"no_such_tool","source":"upstream","args":{}}],"final_answer_needed":false}`}, "finish_reason": "stop"}},
+			"usage":   map[string]any{"prompt_tokens": 80, "completion_tokens": 20, "total_tokens": 100},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OPENROUTER_API_KEY", "x")
+	t.Setenv("MCP_LENS_ROUTER_MODEL", "m")
+	t.Setenv("MCP_LENS_ROUTER_BASE_URL", srv.URL)
+	t.Setenv("MCP_LENS_LLM_MAX_TOKENS_TOTAL", "100")
+	h := ciHandler(t)
+
+	args, _ := json.Marshal(map[string]any{"input": "x"})
+	res, err := h.Handle(context.Background(), "query", args)
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if !res.IsError || !strings.Contains(res.Content[0].Text, "repair skipped: LLM budget exceeded") {
+		t.Fatalf("expected validation error with the skipped repair, got %+v", res.Content)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected no repair call once the budget is used up, got %d calls", n)
+	}
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgressFunc receives progress updates while a query executes.
+// progress increases with every call; message describes the current step.
+type ProgressFunc func(progress float64, message string)
+
+type progressKey struct{}
+
+// WithProgress attaches a progress reporter to ctx (typically bound to the client's progressToken).
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// planProgress reports executePlan milestones. Safe to use when no reporter is attached.
+// The total number of steps is not known up front (auto-continuation appends steps),
+// so progress is a monotonically increasing event counter and the message carries step/total.
+type planProgress struct {
+	fn    ProgressFunc
+	mu    sync.Mutex
+	count float64
+}
+
+func newPlanProgress(ctx context.Context) *planProgress {
+	return &planProgress{fn: progressFromContext(ctx)}
+}
+
+func (p *planProgress) emit(msg string) {
+	if p.fn == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.count++
+	p.fn(p.count, msg)
+}
+
+// stepStarted reports that step idx (0-based) of total is about to run.
+// page is the continuation page number (1 for the planned step itself).
+func (p *planProgress) stepStarted(idx int, total int, tool string, page int) {
+	msg := fmt.Sprintf("step %d/%d: %s", idx+1, total, tool)
+	if page > 1 {
+		msg += fmt.Sprintf(" (continuation page %d)", page)
+	}
+	p.emit(msg)
+}
+
+// artifactCreated reports that a step result was stored as an artifact.
+func (p *planProgress) artifactCreated(idx int, tool string, uri string) {
+	p.emit(fmt.Sprintf("step %d: %s stored result as artifact %s", idx+1, tool, uri))
+}
+
+// stepRetrying reports that step idx failed transiently and runs again as attempt after wait.
+func (p *planProgress) stepRetrying(idx int, tool string, attempt int, wait time.Duration, reason string) {
+	p.emit(fmt.Sprintf("step %d: %s failed (%s); retry %d in %s", idx+1, tool, truncateProgress(reason), attempt-1, wait))
+}
+
+// fallbackStarted reports that step idx failed and its fallback step runs instead.
+func (p *planProgress) fallbackStarted(idx int, tool string, fallback string) {
+	p.emit(fmt.Sprintf("step %d: %s failed; running fallback %s", idx+1, tool, fallback))
+}
+
+func truncateProgress(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if len(s) > 120 {
+		s = s[:120] + "..."
+	}
+	return s
+}
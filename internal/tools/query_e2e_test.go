@@ -45,7 +45,7 @@ func TestQueryE2E_RealPR(t *testing.T) {
 	reg := registry.NewRegistry()
 
 	// Handler with no upstream executor (we only use local tools)
-	h := NewHandler(reg, func(name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
 		return &mcp.CallToolResult{
 			Content: []mcp.ContentBlock{{Type: "text", Text: `{"error": "upstream not configured"}`}},
 			IsError: true,
@@ -188,7 +188,7 @@ func TestQueryE2E_PRSummary(t *testing.T) {
 	}
 
 	reg := registry.NewRegistry()
-	h := NewHandler(reg, func(name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
 		return &mcp.CallToolResult{
 			Content: []mcp.ContentBlock{{Type: "text", Text: `{"error": "upstream not configured"}`}},
 			IsError: true,
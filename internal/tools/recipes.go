@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golovatskygroup/mcp-lens/internal/artifacts"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+// saveRecipeInput saves the plan of a successful query as a recipe.
+type saveRecipeInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Params maps param names to the values used by this query; matching args become placeholders.
+	Params map[string]any `json:"params,omitempty"`
+}
+
+func recipesDirFromEnv() string {
+	if p := strings.TrimSpace(os.Getenv("MCP_LENS_RECIPES_DIR")); p != "" {
+		return os.ExpandEnv(p)
+	}
+	return filepath.Join(artifacts.ConfigFromEnv().Dir, "mcp-lens-recipes")
+}
+
+// recipeStore holds recipes from config (read-only) and recipes saved from queries (one JSON file each).
+type recipeStore struct {
+	mu     sync.RWMutex
+	config map[string]router.Recipe
+	dir    string
+}
+
+func newRecipeStore(dir string) *recipeStore {
+	return &recipeStore{config: map[string]router.Recipe{}, dir: dir}
+}
+
+func (s *recipeStore) setConfig(recipes []router.Recipe) error {
+	m := make(map[string]router.Recipe, len(recipes))
+	for _, r := range recipes {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		if _, dup := m[r.Name]; dup {
+			return fmt.Errorf("duplicate recipe %q", r.Name)
+		}
+		m[r.Name] = r
+	}
+	s.mu.Lock()
+	s.config = m
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *recipeStore) get(name string) (router.Recipe, error) {
+	s.mu.RLock()
+	r, ok := s.config[name]
+	s.mu.RUnlock()
+	if ok {
+		return r, nil
+	}
+
+	b, err := os.ReadFile(s.path(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return router.Recipe{}, fmt.Errorf("unknown recipe %q (available: %s)", name, strings.Join(s.names(), ", "))
+		}
+		return router.Recipe{}, err
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return router.Recipe{}, fmt.Errorf("recipe %s: %w", name, err)
+	}
+	// Saved files may have been edited by hand.
+	if err := r.Validate(); err != nil {
+		return router.Recipe{}, err
+	}
+	return r, nil
+}
+
+func (s *recipeStore) save(r router.Recipe) (string, error) {
+	if err := r.Validate(); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.config[r.Name]; ok {
+		return "", fmt.Errorf("recipe %q is defined in config and cannot be overwritten", r.Name)
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", err
+	}
+	p := s.path(r.Name)
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// names lists config and saved recipe names, sorted.
+func (s *recipeStore) names() []string {
+	s.mu.RLock()
+	seen := make(map[string]struct{}, len(s.config))
+	for name := range s.config {
+		seen[name] = struct{}{}
+	}
+	s.mu.RUnlock()
+	entries, _ := os.ReadDir(s.dir)
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			seen[name] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *recipeStore) path(name string) string {
+	return filepath.Join(s.dir, filepath.Base(name)+".json")
+}
+
+// SetRecipes registers recipes from config. Saved recipes cannot overwrite them.
+func (h *Handler) SetRecipes(recipes []router.Recipe) error {
+	return h.recipes.setConfig(recipes)
+}
+
+// applyRecipe expands in.Recipe into executor-mode steps.
+func (h *Handler) applyRecipe(in *routerInput) error {
+	if len(in.Steps) > 0 {
+		return errors.New("recipe and steps cannot be combined")
+	}
+	switch strings.ToLower(strings.TrimSpace(in.Mode)) {
+	case "", "auto", "executor":
+	default:
+		return errors.New("recipes run in executor mode")
+	}
+	name := strings.TrimSpace(in.Recipe)
+	r, err := h.recipes.get(name)
+	if err != nil {
+		return err
+	}
+	steps, err := r.Expand(in.Params)
+	if err != nil {
+		return err
+	}
+	in.Steps = steps
+	in.Mode = "executor"
+	if strings.TrimSpace(in.Input) == "" {
+		in.Input = "recipe " + name
+	}
+	return nil
+}
+
+// saveRecipe stores the executed plan as a recipe when every step succeeded.
+func (h *Handler) saveRecipe(in *saveRecipeInput, res *router.RouterResult) {
+	if in == nil || len(res.ExecutedSteps) == 0 || res.Pending != nil {
+		return
+	}
+	for _, st := range res.ExecutedSteps {
+		if !st.OK {
+			res.SavedRecipe = &router.SavedRecipe{Name: in.Name, Error: "not saved: step " + st.Name + " failed"}
+			return
+		}
+	}
+	r, err := router.NewRecipeFromPlan(strings.TrimSpace(in.Name), in.Description, res.Plan.Steps, in.Params)
+	if err == nil {
+		_, err = h.recipes.save(r)
+	}
+	if err != nil {
+		res.SavedRecipe = &router.SavedRecipe{Name: in.Name, Error: "not saved: " + err.Error()}
+		return
+	}
+	res.SavedRecipe = &router.SavedRecipe{Name: r.Name, Params: r.ParamNames()}
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+func TestQueryRunsConfigRecipeWithoutPlanner(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv("MCP_LENS_RECIPES_DIR", t.TempDir())
+	h := ciHandler(t)
+	err := h.SetRecipes([]router.Recipe{{
+		Name:   "ci-log",
+		Params: map[string]router.RecipeParam{"job": {Type: "integer", Required: true}},
+		Steps: []router.RecipeStep{
+			{Name: "ci.list_jobs", Source: "upstream", Args: map[string]any{}},
+			{Name: "ci.get_log", Source: "upstream", Args: map[string]any{"job": map[string]any{router.ParamKey: "job"}}},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("SetRecipes: %v", err)
+	}
+
+	res := runQuery(t, h, map[string]any{"recipe": "ci-log", "params": map[string]any{"job": 4711}})
+	if len(res.ExecutedSteps) != 2 || !res.ExecutedSteps[1].OK {
+		t.Fatalf("expected both recipe steps to run, got %+v", res.ExecutedSteps)
+	}
+	if !strings.Contains(string(res.ExecutedSteps[1].Args), "4711") {
+		t.Fatalf("expected param in args, got %s", res.ExecutedSteps[1].Args)
+	}
+
+	for _, in := range []map[string]any{
+		{"recipe": "ci-log", "params": map[string]any{"job": "x"}},
+		{"recipe": "nope"},
+		{"recipe": "ci-log", "mode": "planner", "params": map[string]any{"job": 1}},
+	} {
+		args, _ := json.Marshal(in)
+		out, err := h.Handle(context.Background(), "query", args)
+		if err != nil || !out.IsError {
+			t.Fatalf("expected error result for %v, got %+v", in, out)
+		}
+	}
+}
+
+func TestSaveRecipeFromQueryAndRerun(t *testing.T) {
+	t.Setenv("MCP_LENS_RECIPES_DIR", t.TempDir())
+	h := ciHandler(t)
+
+	res := runQuery(t, h, map[string]any{
+		"input": "get the log",
+		"steps": []map[string]any{
+			{"name": "ci.get_log", "source": "upstream", "args": map[string]any{"job": 4711}},
+		},
+		"save_recipe": map[string]any{"name": "job-log", "params": map[string]any{"job": 4711}},
+	})
+	if res.SavedRecipe == nil || res.SavedRecipe.Error != "" || res.SavedRecipe.Name != "job-log" {
+		t.Fatalf("expected recipe saved, got %+v", res.SavedRecipe)
+	}
+
+	again := runQuery(t, h, map[string]any{"recipe": "job-log", "params": map[string]any{"job": 99}})
+	if len(again.ExecutedSteps) != 1 || !strings.Contains(string(again.ExecutedSteps[0].Args), "99") {
+		t.Fatalf("expected saved recipe to run with the new param, got %+v", again.ExecutedSteps)
+	}
+
+	// Config recipes cannot be overwritten by saves.
+	if err := h.SetRecipes([]router.Recipe{{Name: "job-log", Steps: []router.RecipeStep{{Name: "ci.list_jobs", Source: "upstream"}}}}); err != nil {
+		t.Fatalf("SetRecipes: %v", err)
+	}
+	res = runQuery(t, h, map[string]any{
+		"input":       "x",
+		"steps":       []map[string]any{{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}}},
+		"save_recipe": map[string]any{"name": "job-log"},
+	})
+	if res.SavedRecipe == nil || !strings.Contains(res.SavedRecipe.Error, "config") {
+		t.Fatalf("expected save to be refused, got %+v", res.SavedRecipe)
+	}
+}
+
+func TestSavedRecipesAreValidatedOnLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MCP_LENS_RECIPES_DIR", dir)
+	h := ciHandler(t)
+
+	r := router.Recipe{Name: "too-long"}
+	for i := 0; i <= router.MaxPlanSteps; i++ {
+		r.Steps = append(r.Steps, router.RecipeStep{Name: "ci.get_log", Source: "upstream"})
+	}
+	b, _ := json.Marshal(r)
+	if err := os.WriteFile(filepath.Join(dir, "too-long.json"), b, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := h.recipes.get("too-long"); err == nil || !strings.Contains(err.Error(), "at most") {
+		t.Fatalf("expected hand-edited recipe to be rejected, got %v", err)
+	}
+}
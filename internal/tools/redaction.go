@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golovatskygroup/mcp-lens/internal/artifacts"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+func redactorFromEnv() *router.Redactor {
+	r, _ := router.NewRedactor(router.RedactionConfigFromEnv())
+	return r
+}
+
+// SetRedaction configures the secret/PII redaction of step results; MCP_LENS_REDACTION=off still disables it.
+func (h *Handler) SetRedaction(cfg router.RedactionConfig) error {
+	if router.RedactionConfigFromEnv().Disabled {
+		cfg.Disabled = true
+	}
+	r, err := router.NewRedactor(cfg)
+	if err != nil {
+		return err
+	}
+	h.redactor = r
+	return nil
+}
+
+// redactArgs returns step args with secrets and PII removed, for callers, summaries, the audit log
+// and artifact metadata; tools themselves get the original args.
+func (h *Handler) redactArgs(args json.RawMessage) json.RawMessage {
+	if h.redactor == nil || len(args) == 0 {
+		return args
+	}
+	var v any
+	if json.Unmarshal(args, &v) != nil {
+		s, _ := h.redactor.RedactText(string(args))
+		return json.RawMessage(s)
+	}
+	v, counts := h.redactor.RedactValue(v)
+	if len(counts) == 0 {
+		return args
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return args
+	}
+	return b
+}
+
+// setStepError sets a step error with secrets and PII removed.
+func (h *Handler) setStepError(st *router.ExecutedStep, msg string) {
+	var counts map[string]int
+	st.Error, counts = h.redactor.RedactText(msg)
+	st.Redactions = addRedactions(st.Redactions, counts)
+}
+
+func addRedactions(total, counts map[string]int) map[string]int {
+	if len(counts) == 0 {
+		return total
+	}
+	if total == nil {
+		total = map[string]int{}
+	}
+	for k, n := range counts {
+		total[k] += n
+	}
+	return total
+}
+
+// storeArtifactBytes stores a tool's payload as an artifact with secrets and PII redacted from text
+// payloads and from the args kept as metadata. Binary payloads (see isTextArtifact) are stored as is.
+func (h *Handler) storeArtifactBytes(tool string, args json.RawMessage, mime, ext string, b []byte) (any, *artifacts.Item, map[string]int, error) {
+	args = h.redactArgs(args)
+	var counts map[string]int
+	if isTextArtifact(mime, ext, b) {
+		var text string
+		text, counts = h.redactor.RedactText(string(b))
+		b = []byte(text)
+	}
+	repl, item, err := h.artifacts.StoreBytes(tool, args, mime, ext, b)
+	return repl, item, counts, err
+}
+
+// writeRedactedFile writes a text export to disk with secrets and PII redacted.
+func (h *Handler) writeRedactedFile(path, text string) error {
+	text, _ = h.redactor.RedactText(text)
+	return os.WriteFile(path, []byte(text), 0o644)
+}
+
+// isTextArtifact reports whether a payload is text that can be redacted without corrupting it:
+// a known text MIME type or extension (office formats such as
+// application/vnd.openxmlformats-... are binary) and valid UTF-8.
+func isTextArtifact(mime, ext string, b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")) {
+	case "txt", "log", "md", "json", "csv", "html", "htm", "xml", "yaml", "yml":
+		return true
+	}
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = strings.TrimSpace(mime[:i])
+	}
+	if strings.HasPrefix(mime, "text/") || strings.HasSuffix(mime, "+json") || strings.HasSuffix(mime, "+xml") {
+		return true
+	}
+	switch mime {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml",
+		"application/javascript", "application/x-sh", "application/csv":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/internal/registry"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+func secretsHandler(t *testing.T) *Handler {
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: `{"log":"token=ghp_` + strings.Repeat("a1B2", 9) + ` mailed to ann.lee@example.com","api_key":"k-123"}`}}}, nil
+	})
+	reg.LoadTools(h.BuiltinTools())
+	reg.LoadTools([]mcp.Tool{{Name: "ci.get_log", InputSchema: json.RawMessage(`{"type":"object"}`)}})
+	p := router.DefaultPolicy()
+	p.AllowUpstream = map[string]struct{}{"ci.get_log": {}}
+	h.SetPolicy(p)
+	return h
+}
+
+func TestQueryRedactsStepResultsAndArtifacts(t *testing.T) {
+	t.Setenv("MCP_LENS_ARTIFACT_DIR", t.TempDir())
+	t.Setenv("MCP_LENS_ARTIFACT_INLINE_MAX_BYTES", "10")
+	h := secretsHandler(t)
+	steps := []map[string]any{{"name": "ci.get_log", "source": "upstream", "args": map[string]any{}}}
+
+	res := runQuery(t, h, map[string]any{"input": "log", "steps": steps})
+	st := res.ExecutedSteps[0]
+	if st.Redactions["github_token"] != 1 || st.Redactions["email"] != 1 || st.Redactions["secret_field"] != 1 {
+		t.Fatalf("unexpected redactions %v", st.Redactions)
+	}
+	items := h.artifacts.List()
+	if len(items) != 1 {
+		t.Fatalf("expected the step result as artifact, got %+v", items)
+	}
+	b, err := os.ReadFile(items[0].Path)
+	if err != nil || strings.Contains(string(b), "ghp_") || strings.Contains(string(b), "k-123") || !strings.Contains(string(b), "[REDACTED:email]") {
+		t.Fatalf("artifact not redacted: %s (%v)", b, err)
+	}
+
+	// Redaction can be switched off.
+	if err := h.SetRedaction(router.RedactionConfig{Disabled: true}); err != nil {
+		t.Fatalf("SetRedaction: %v", err)
+	}
+	res = runQuery(t, h, map[string]any{"input": "log", "steps": steps, "output": map[string]any{"view": "full"}})
+	if res.ExecutedSteps[0].Redactions != nil {
+		t.Fatalf("expected no redactions, got %v", res.ExecutedSteps[0].Redactions)
+	}
+}
+
+func TestQueryRedactsResolvedArgsAndErrors(t *testing.T) {
+	token := "ghp_" + strings.Repeat("a1B2", 9)
+	var gotArgs string
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		if name == "ci.get_log" {
+			return &mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: `{"token":"` + token + `"}`}}}, nil
+		}
+		gotArgs = string(args)
+		return nil, errors.New("upstream rejected " + token)
+	})
+	reg.LoadTools(h.BuiltinTools())
+	reg.LoadTools([]mcp.Tool{
+		{Name: "ci.get_log", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		{Name: "ci.check_token", InputSchema: json.RawMessage(`{"type":"object"}`)},
+	})
+	p := router.DefaultPolicy()
+	p.AllowUpstream = map[string]struct{}{"ci.get_log": {}, "ci.check_token": {}}
+	h.SetPolicy(p)
+
+	res := runQuery(t, h, map[string]any{"input": "check", "steps": []map[string]any{
+		{"name": "ci.get_log", "source": "upstream", "args": map[string]any{}},
+		{"name": "ci.check_token", "source": "upstream", "args": map[string]any{"token": map[string]any{"$ref": "steps[0].result.token"}}},
+	}})
+	if !strings.Contains(gotArgs, token) {
+		t.Fatalf("expected the tool to get the resolved secret, got %s", gotArgs)
+	}
+	if len(res.ExecutedSteps) != 2 {
+		t.Fatalf("expected two steps, got %+v", res.ExecutedSteps)
+	}
+	st := res.ExecutedSteps[1]
+	if strings.Contains(string(st.Args), "ghp_") || strings.Contains(st.Error, "ghp_") || st.Redactions["github_token"] != 1 {
+		t.Fatalf("expected redacted args and error, got args=%s error=%q redactions=%v", st.Args, st.Error, st.Redactions)
+	}
+}
+
+func TestArtifactWritesAreRedacted(t *testing.T) {
+	t.Setenv("MCP_LENS_ARTIFACT_DIR", t.TempDir())
+	h := secretsHandler(t)
+	secret := "ghp_" + strings.Repeat("a1B2", 9)
+
+	// Tool payloads (CI logs, attachments) stored as text artifacts are redacted.
+	_, item, counts, err := h.storeArtifactBytes("github_download_job_logs", nil, "application/octet-stream", "log", []byte("token="+secret))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if b, _ := os.ReadFile(item.Path); strings.Contains(string(b), "ghp_") || counts["github_token"] != 1 {
+		t.Fatalf("log artifact not redacted: %s %v", b, counts)
+	}
+
+	// Binary payloads are stored as they are: by MIME type (office formats are not XML text),
+	// extension or invalid UTF-8.
+	for _, tc := range []struct {
+		mime, ext string
+		b         []byte
+	}{
+		{"image/png", "png", []byte("\x89PNG " + secret)},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "docx", []byte("PK word/document.xml " + secret)},
+		{"text/plain", "bin", []byte("\xff\xfe " + secret)},
+	} {
+		_, item, _, err := h.storeArtifactBytes("confluence_download_attachment", nil, tc.mime, tc.ext, tc.b)
+		if err != nil {
+			t.Fatalf("store: %v", err)
+		}
+		if b, _ := os.ReadFile(item.Path); string(b) != string(tc.b) {
+			t.Fatalf("binary artifact %s changed: %q", tc.mime, b)
+		}
+	}
+
+	path := t.TempDir() + "/PAY-1.md"
+	if err := h.writeRedactedFile(path, "contact ann.lee@example.com"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if b, _ := os.ReadFile(path); strings.Contains(string(b), "ann.lee@") {
+		t.Fatalf("export not redacted: %s", b)
+	}
+}
+
+func TestSavedTextArtifactsAreKeptAsIs(t *testing.T) {
+	t.Setenv("MCP_LENS_ARTIFACT_DIR", t.TempDir())
+	h := secretsHandler(t)
+	text := "deploy key ghp_" + strings.Repeat("a1B2", 9)
+	args, _ := json.Marshal(map[string]any{"text": text, "mime": "text/plain"})
+	out, err := h.Handle(context.Background(), "artifact_save_text", args)
+	if err != nil || out.IsError {
+		t.Fatalf("artifact_save_text: %v %+v", err, out)
+	}
+	items := h.artifacts.List()
+	if len(items) != 1 {
+		t.Fatalf("expected one artifact, got %+v", items)
+	}
+	if b, _ := os.ReadFile(items[0].Path); string(b) != text {
+		t.Fatalf("saved text changed: %q", b)
+	}
+}
+
+func TestCancelledStepsRedactArgs(t *testing.T) {
+	h := secretsHandler(t)
+	steps := []router.PlanStep{{Name: "ci.get_log", Source: "upstream", Args: json.RawMessage(`{"token":"ghp_` + strings.Repeat("a1B2", 9) + `"}`)}}
+	out := h.cancelledSteps(steps, context.Canceled)
+	if len(out) != 1 || strings.Contains(string(out[0].Args), "ghp_") || !out[0].Cancelled {
+		t.Fatalf("expected a cancelled step with redacted args, got %+v", out)
+	}
+}
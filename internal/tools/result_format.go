@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golovatskygroup/mcp-lens/internal/artifacts"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+// validateFormat checks the query format (json|text|markdown_table|csv|tsv).
+func validateFormat(format string) error {
+	switch format {
+	case "", "json", "text":
+		return nil
+	}
+	if router.IsTableFormat(format) {
+		return nil
+	}
+	return fmt.Errorf("unsupported format %q (use json, text, %s, %s or %s)", format, router.FormatMarkdownTable, router.FormatCSV, router.FormatTSV)
+}
+
+// formatRouterResult renders a query result as JSON (json, text) or as one table per step result.
+func (h *Handler) formatRouterResult(res router.RouterResult, format string, columns []string) *mcp.CallToolResult {
+	format = strings.ToLower(strings.TrimSpace(format))
+	var out *mcp.CallToolResult
+	if router.IsTableFormat(format) {
+		out = h.tableResult(res, format, columns)
+	}
+	if out == nil {
+		if format == "text" || router.IsTableFormat(format) {
+			b, _ := json.MarshalIndent(res, "", "  ")
+			out = textResult(fmt.Sprintf("%s\n", string(b)))
+		} else {
+			out = jsonResult(res)
+		}
+	}
+	if len(res.Structured) > 0 {
+		out.StructuredContent = res.Structured
+	}
+	return out
+}
+
+// tableResult renders each step result that holds rows as a table block; renderings over the artifact
+// inline limit are stored as artifacts. A last block lists the answer and the steps without a table.
+// It returns nil when no step has rows (e.g. dry runs and pending plans), which are then rendered as text.
+func (h *Handler) tableResult(res router.RouterResult, format string, columns []string) *mcp.CallToolResult {
+	var blocks []mcp.ContentBlock
+	var notes []string
+	stored := 0
+	mime, ext := router.TableMime(format)
+	for i, st := range res.ExecutedSteps {
+		label := fmt.Sprintf("%d) %s", i+1, st.Name)
+		if !st.OK {
+			notes = append(notes, fmt.Sprintf("- %s: error: %s", label, st.Error))
+			continue
+		}
+		if st.OutputError != "" {
+			notes = append(notes, fmt.Sprintf("- %s: output_error: %s", label, st.OutputError))
+		}
+		rows, ok := router.TableRows(h.artifactValue(st.Result))
+		if !ok {
+			notes = append(notes, fmt.Sprintf("- %s: no tabular result", label))
+			continue
+		}
+		table, err := router.RenderTable(format, router.TableColumns(rows, columns), rows)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("- %s: %v", label, err))
+			continue
+		}
+		if h.artifacts != nil && h.artifacts.InlineMaxBytes() > 0 && len(table) > h.artifacts.InlineMaxBytes() {
+			_, item, err := h.artifacts.StoreBytes(st.Name, st.Args, mime, ext, []byte(table))
+			if err != nil {
+				notes = append(notes, fmt.Sprintf("- %s: artifact store error: %v", label, err))
+				continue
+			}
+			notes = append(notes, fmt.Sprintf("- %s: %d row(s) stored as %s (%s, %s, %d bytes)", label, len(rows), item.Mime, item.Path, artifacts.ArtifactURI(item.ID), item.Bytes))
+			stored++
+			continue
+		}
+		if format == router.FormatMarkdownTable {
+			table = fmt.Sprintf("### %s\n\n%s", label, table)
+		}
+		blocks = append(blocks, mcp.ContentBlock{Type: "text", Text: table})
+	}
+	if len(blocks) == 0 && stored == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	if res.Answer != "" {
+		sb.WriteString(res.Answer)
+		sb.WriteString("\n\n")
+	}
+	if res.AnswerError != "" {
+		sb.WriteString("answer_error: " + res.AnswerError + "\n\n")
+	}
+	switch {
+	case res.TimedOut:
+		notes = append(notes, "- timed out: results are incomplete")
+	case res.Cancelled:
+		notes = append(notes, "- cancelled: results are incomplete")
+	case res.Partial:
+		notes = append(notes, "- partial: some steps failed")
+	}
+	if len(notes) > 0 {
+		sb.WriteString(strings.Join(notes, "\n"))
+		sb.WriteString("\n")
+	}
+	if sb.Len() > 0 {
+		blocks = append(blocks, mcp.ContentBlock{Type: "text", Text: sb.String()})
+	}
+	return &mcp.CallToolResult{Content: blocks}
+}
+
+// artifactValue reads a JSON step result back from the artifact store when it was replaced by an
+// artifact reference, so large results can still be rendered as tables.
+func (h *Handler) artifactValue(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok || h.artifacts == nil {
+		return v
+	}
+	id, _ := m["artifact_id"].(string)
+	if id == "" {
+		return v
+	}
+	b, mime, ok := h.artifacts.Read(id)
+	if !ok || mime != "application/json" {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return v
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestQueryTableFormats(t *testing.T) {
+	h := ciHandler(t)
+	steps := []map[string]any{
+		{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}},
+		{"name": "ci.get_log", "source": "upstream", "args": map[string]any{"job": 4711}, "output": map[string]any{"expr": ".log"}},
+	}
+	run := func(in map[string]any) []string {
+		t.Helper()
+		args, _ := json.Marshal(in)
+		out, err := h.Handle(context.Background(), "query", args)
+		if err != nil || out.IsError {
+			t.Fatalf("query failed: %v %+v", err, out)
+		}
+		var texts []string
+		for _, c := range out.Content {
+			texts = append(texts, c.Text)
+		}
+		return texts
+	}
+
+	got := run(map[string]any{"input": "jobs", "steps": steps, "format": "csv", "columns": []string{"status", "id"}})
+	if len(got) != 2 || got[0] != "status,id\nfailed,4711\n" || !strings.Contains(got[1], "2) ci.get_log: no tabular result") {
+		t.Fatalf("unexpected csv result %q", got)
+	}
+	got = run(map[string]any{"input": "jobs", "steps": steps[:1], "format": "markdown_table", "include_answer": true})
+	if len(got) != 2 || !strings.Contains(got[0], "### 1) ci.list_jobs\n\n| id | status |\n| --- | --- |\n| 4711 | failed |") || !strings.HasPrefix(got[1], "Executed 1 step(s).") {
+		t.Fatalf("unexpected markdown result %q", got)
+	}
+
+	args, _ := json.Marshal(map[string]any{"input": "jobs", "steps": steps, "format": "xml"})
+	if out, err := h.Handle(context.Background(), "query", args); err != nil || !out.IsError {
+		t.Fatalf("expected unsupported format error, got %+v", out)
+	}
+}
+
+func TestQueryTableFormatStoresLargeTables(t *testing.T) {
+	t.Setenv("MCP_LENS_ARTIFACT_DIR", t.TempDir())
+	t.Setenv("MCP_LENS_ARTIFACT_INLINE_MAX_BYTES", "10")
+	h := ciHandler(t)
+
+	args, _ := json.Marshal(map[string]any{
+		"input":  "jobs",
+		"steps":  []map[string]any{{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}}},
+		"format": "tsv",
+	})
+	out, err := h.Handle(context.Background(), "query", args)
+	if err != nil || out.IsError || len(out.Content) != 1 {
+		t.Fatalf("query failed: %v %+v", err, out)
+	}
+	// The JSON step result went to an artifact; the table is rendered from it and stored as TSV.
+	text := out.Content[0].Text
+	if !strings.Contains(text, "1 row(s) stored as text/tab-separated-values") {
+		t.Fatalf("expected stored table, got %q", text)
+	}
+	items := h.artifacts.List()
+	last := items[len(items)-1]
+	b, err := os.ReadFile(last.Path)
+	if err != nil || last.Mime != "text/tab-separated-values" || !strings.Contains(text, last.Path) || string(b) != "id\tstatus\n4711\tfailed\n" {
+		t.Fatalf("unexpected artifact %+v %q (%v)", last, b, err)
+	}
+}
@@ -3,13 +3,16 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golovatskygroup/mcp-lens/internal/artifacts"
+	"github.com/golovatskygroup/mcp-lens/internal/proxy"
 	"github.com/golovatskygroup/mcp-lens/internal/router"
 	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
 	"golang.org/x/sync/errgroup"
@@ -19,13 +22,26 @@ type routerInput struct {
 	Input         string                `json:"input"`
 	Context       map[string]any        `json:"context,omitempty"`
 	Output        *router.OutputOptions `json:"output,omitempty"`
-	Mode          string                `json:"mode,omitempty"` // auto|planner|executor
+	Mode          string                `json:"mode,omitempty"` // auto|planner|executor|iterative
 	Steps         []router.PlanStep     `json:"steps,omitempty"`
 	MaxSteps      int                   `json:"max_steps,omitempty"`
 	Parallelism   int                   `json:"parallelism,omitempty"`
 	IncludeAnswer bool                  `json:"include_answer,omitempty"`
 	DryRun        bool                  `json:"dry_run,omitempty"`
-	Format        string                `json:"format,omitempty"` // json|text
+	Format        string                `json:"format,omitempty"` // json|text|markdown_table|csv|tsv
+	Confirm       string                `json:"confirm,omitempty"` // token of a pending write plan
+	// TimeoutSeconds shortens the overall query deadline (MCP_LENS_QUERY_TIMEOUT_SECONDS).
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// TokenBudget bounds the estimated planner tokens of an iterative query.
+	TokenBudget int `json:"token_budget,omitempty"`
+	// Recipe runs a saved plan template with Params instead of planning.
+	Recipe     string           `json:"recipe,omitempty"`
+	Params     map[string]any   `json:"params,omitempty"`
+	SaveRecipe *saveRecipeInput `json:"save_recipe,omitempty"`
+	// AnswerSchema makes the final answer JSON valid against this schema (implies include_answer).
+	AnswerSchema json.RawMessage `json:"answer_schema,omitempty"`
+	// Columns selects and orders the table columns (paths into each row) of the table formats.
+	Columns []string `json:"columns,omitempty"`
 }
 
 func (h *Handler) runRouter(ctx context.Context, args json.RawMessage) (*mcp.CallToolResult, error) {
@@ -33,15 +49,38 @@ func (h *Handler) runRouter(ctx context.Context, args json.RawMessage) (*mcp.Cal
 	if err := json.Unmarshal(args, &in); err != nil {
 		return errorResult("Invalid input: " + err.Error()), nil
 	}
+	in.Format = strings.ToLower(strings.TrimSpace(in.Format))
+	if err := validateFormat(in.Format); err != nil {
+		return errorResult(err.Error()), nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeouts.queryDeadline(in.TimeoutSeconds))
+	defer cancel()
+	if tok := strings.TrimSpace(in.Confirm); tok != "" {
+		return h.runConfirmed(ctx, tok, in.Format, in.Columns)
+	}
+	if strings.TrimSpace(in.Recipe) != "" {
+		if err := h.applyRecipe(&in); err != nil {
+			return errorResult(err.Error()), nil
+		}
+	}
 	if strings.TrimSpace(in.Input) == "" {
 		return errorResult("input is required"), nil
 	}
+	if err := in.Output.Validate(); err != nil {
+		return errorResult(err.Error()), nil
+	}
+	if len(in.AnswerSchema) > 0 {
+		if err := router.CompileAnswerSchema(in.AnswerSchema); err != nil {
+			return errorResult(err.Error()), nil
+		}
+		in.IncludeAnswer = true
+	}
 	if in.Context == nil {
 		in.Context = map[string]any{}
 	}
 
 	// Deterministic context extraction (URLs/IDs) before any planning.
-	if extracted := router.ExtractStructuredContext(in.Input); len(extracted) > 0 {
+	if extracted := h.extractContext(in.Input); len(extracted) > 0 {
 		for k, v := range extracted {
 			if _, exists := in.Context[k]; !exists {
 				in.Context[k] = v
@@ -97,15 +136,31 @@ func (h *Handler) runRouter(ctx context.Context, args json.RawMessage) (*mcp.Cal
 		in.Context["grafana_default_client"] = def
 	}
 
+	mode := strings.ToLower(strings.TrimSpace(in.Mode))
+	if mode == "" {
+		mode = "auto"
+	}
+	switch mode {
+	case "auto", "planner", "executor", "iterative":
+	default:
+		return errorResult("mode must be one of: auto, planner, executor, iterative"), nil
+	}
+
 	if in.MaxSteps <= 0 {
 		if len(in.Steps) > 0 {
 			in.MaxSteps = len(in.Steps)
+		} else if mode == "iterative" {
+			in.MaxSteps = defaultIterativeSteps
 		} else {
 			in.MaxSteps = 5
 		}
 	}
-	if in.MaxSteps > 8 {
-		return errorResult("max_steps must be <= 8"), nil
+	if mode == "iterative" {
+		if in.MaxSteps > maxIterativeSteps {
+			return errorResult(fmt.Sprintf("max_steps must be <= %d in iterative mode", maxIterativeSteps)), nil
+		}
+	} else if in.MaxSteps > router.MaxPlanSteps {
+		return errorResult(fmt.Sprintf("max_steps must be <= %d", router.MaxPlanSteps)), nil
 	}
 	if in.Format == "" {
 		in.Format = "json"
@@ -117,26 +172,16 @@ func (h *Handler) runRouter(ctx context.Context, args json.RawMessage) (*mcp.Cal
 		return errorResult("parallelism must be <= 8"), nil
 	}
 
-	mode := strings.ToLower(strings.TrimSpace(in.Mode))
-	if mode == "" {
-		mode = "auto"
-	}
-	switch mode {
-	case "auto", "planner", "executor":
-	default:
-		return errorResult("mode must be one of: auto, planner, executor"), nil
-	}
-
 	if mode == "executor" && len(in.Steps) == 0 {
 		return errorResult("executor mode requires non-empty steps"), nil
 	}
-	if mode == "planner" && len(in.Steps) > 0 {
-		return errorResult("planner mode does not allow steps"), nil
+	if (mode == "planner" || mode == "iterative") && len(in.Steps) > 0 {
+		return errorResult(mode + " mode does not allow steps"), nil
 	}
 
-	// Fast-path: safe discovery/help without OpenRouter.
-	if fp := parseDiscoveryFastPath(in.Input); fp != nil {
-		policy := router.DefaultPolicy()
+	// Fast-path: safe discovery/help without an LLM.
+	if fp := parseDiscoveryFastPath(in.Input); fp != nil && in.Recipe == "" {
+		policy := h.routerPolicy()
 		plan := router.ModelPlan{Steps: []router.PlanStep{fp.step}, FinalAnswerNeeded: false}
 		if err := router.ValidatePlan(plan, policy, h.buildRouterCatalog(), 1); err != nil {
 			return errorResult(err.Error()), nil
@@ -146,6 +191,7 @@ func (h *Handler) runRouter(ctx context.Context, args json.RawMessage) (*mcp.Cal
 			execSteps, manifest, err := h.executePlan(ctx, plan, policy, in.Output, in.Parallelism)
 			res.ExecutedSteps = execSteps
 			res.Manifest = manifest
+			markInterrupted(ctx, &res)
 			if err != nil {
 				return jsonResult(res), nil
 			}
@@ -162,17 +208,22 @@ func (h *Handler) runRouter(ctx context.Context, args json.RawMessage) (*mcp.Cal
 
 	// Executor mode: validate + execute provided steps without calling the planner model.
 	if len(in.Steps) > 0 && mode != "planner" {
-		policy := router.DefaultPolicy()
+		policy := h.routerPolicy()
 		plan := router.ModelPlan{Steps: in.Steps, FinalAnswerNeeded: in.IncludeAnswer}
 		if err := router.ValidatePlan(plan, policy, h.buildRouterCatalog(), in.MaxSteps); err != nil {
 			return errorResult(err.Error()), nil
 		}
 
 		res := router.RouterResult{Plan: plan}
+		if !in.DryRun && hasConfirmableWrites(plan) {
+			res.Pending = h.holdPendingPlan(ctx, in, plan, nil)
+			return h.formatRouterResult(res, in.Format, in.Columns), nil
+		}
 		if !in.DryRun {
 			execSteps, manifest, err := h.executePlan(ctx, plan, policy, in.Output, in.Parallelism)
 			res.ExecutedSteps = execSteps
 			res.Manifest = manifest
+			markInterrupted(ctx, &res)
 			if err != nil {
 				if strings.EqualFold(in.Format, "text") {
 					b, _ := json.MarshalIndent(res, "", "  ")
@@ -180,88 +231,115 @@ func (h *Handler) runRouter(ctx context.Context, args json.RawMessage) (*mcp.Cal
 				}
 				return jsonResult(res), nil
 			}
+			markPartial(&res)
+			h.saveRecipe(in.SaveRecipe, &res)
 		}
 
-		if in.IncludeAnswer {
-			// Executor mode is designed to work even without OpenRouter; provide a deterministic summary.
-			var sb strings.Builder
-			sb.WriteString(fmt.Sprintf("Executed %d step(s).\n", len(res.ExecutedSteps)))
-			for i, st := range res.ExecutedSteps {
-				if st.OK {
-					sb.WriteString(fmt.Sprintf("%d) %s: ok\n", i+1, st.Name))
-				} else {
-					sb.WriteString(fmt.Sprintf("%d) %s: error: %s\n", i+1, st.Name, st.Error))
-				}
-			}
-			if res.Manifest != nil && len(res.Manifest.Artifacts) > 0 {
-				sb.WriteString("\nArtifacts:\n")
-				for _, a := range res.Manifest.Artifacts {
-					sb.WriteString(fmt.Sprintf("- %s (%d bytes)\n", a.Path, a.Bytes))
-				}
-			}
-			res.Answer = sb.String()
-		}
-
-		if strings.EqualFold(in.Format, "text") {
-			b, _ := json.MarshalIndent(res, "", "  ")
-			return textResult(fmt.Sprintf("%s\n", string(b))), nil
+		if len(in.AnswerSchema) > 0 && !in.DryRun {
+			h.structuredExecutorAnswer(ctx, in, &res)
+		} else if in.IncludeAnswer {
+			// Executor mode is designed to work even without an LLM; provide a deterministic summary.
+			res.Answer = executorSummary(res)
 		}
-		return jsonResult(res), nil
+		return h.formatRouterResult(res, in.Format, in.Columns), nil
 	}
 
-	cl, err := router.NewOpenRouterClientFromEnv()
+	if reason, over := h.llmBudgetExceeded(ctx); over {
+		return errorResult(reason + ": planning is disabled for this session; run explicit steps (mode=executor) or a recipe instead"), nil
+	}
+	cl, err := h.llmClient(ctx)
 	if err != nil {
 		return errorResult(err.Error()), nil
 	}
+	ctx, usage := h.meterLLM(ctx)
 
-	policy := router.DefaultPolicy()
+	policy := h.routerPolicy()
+	if writes := policy.AllowedWrites(); len(writes) > 0 {
+		in.Context["write_mode"] = "confirm"
+		in.Context["write_tools"] = writes
+	}
 
 	catalog := h.buildRouterCatalog()
+	prompt := planningCatalog(in.Input, catalog)
+
+	if mode == "iterative" {
+		return h.runIterative(ctx, cl, usage, in, policy, catalog, prompt), nil
+	}
 
-	plan, rawPlan, err := router.Plan(ctx, cl, in.Input, in.Context, catalog, in.MaxSteps)
+	plan, rawPlan, err := router.Plan(ctx, cl, in.Input, in.Context, prompt, in.MaxSteps)
 	if err != nil {
 		return errorResult(err.Error()), nil
 	}
+	// The planner asked for schemas of stubbed tools: provide them and plan again.
+	if names := describedStubs(plan, prompt); len(names) > 0 {
+		prompt = expandCatalog(prompt, catalog, names)
+		plan, rawPlan, err = router.Plan(ctx, cl, in.Input, in.Context, prompt, in.MaxSteps)
+		if err != nil {
+			return errorResult(err.Error()), nil
+		}
+	}
 
-	// Make Jira instance selection deterministic (do not rely on the model to thread it through).
-	h.applyJiraClientToPlan(&plan, in.Context)
-	// Make Confluence instance selection deterministic (do not rely on the model to thread it through).
-	h.applyConfluenceClientToPlan(&plan, in.Context)
-	// Make Grafana instance selection deterministic (do not rely on the model to thread it through).
-	h.applyGrafanaClientToPlan(&plan, in.Context)
-	// Make URL/ID context injection deterministic (do not rely on the model).
-	h.applyExtractedContextToPlan(&plan, in.Context)
-
-	if err := router.ValidatePlan(plan, policy, catalog, in.MaxSteps); err != nil {
-		return errorResult(err.Error() + "\nplan=" + string(rawPlan)), nil
+	// Client selection and URL/ID context are applied deterministically; rejected plans go back to the model for repair.
+	plan, rawPlan, repairs, _, err := h.validateWithRepair(ctx, cl, in, policy, catalog, prompt, in.MaxSteps, nil, plan, rawPlan)
+	if err != nil {
+		return errorResult(err.Error() + "\nplan=" + string(rawPlan) + planRepairsText(repairs)), nil
 	}
 
 	res := router.RouterResult{Plan: plan}
+	dbg := planDebug{PlanRepairs: repairs}
+	res.Debug = &dbg
+
+	if !in.DryRun && hasConfirmableWrites(plan) {
+		res.Pending = h.holdPendingPlan(ctx, in, plan, nil)
+		dbg.LLMUsage = h.usageDebug(ctx, usage, "")
+		return h.formatRouterResult(res, in.Format, in.Columns), nil
+	}
 
 	if !in.DryRun {
 		execSteps, manifest, err := h.executePlan(ctx, plan, policy, in.Output, in.Parallelism)
 		if err != nil {
 			res.ExecutedSteps = execSteps
 			res.Manifest = manifest
+			markInterrupted(ctx, &res)
+			dbg.LLMUsage = h.usageDebug(ctx, usage, "")
 			return jsonResult(res), nil
 		}
 		res.ExecutedSteps = execSteps
 		res.Manifest = manifest
+		markPartial(&res)
+		h.saveRecipe(in.SaveRecipe, &res)
 	}
 
+	var overBudget string
 	if in.IncludeAnswer && mode != "planner" {
-		answer, err := router.Summarize(ctx, cl, in.Input, res)
-		if err == nil {
-			res.Answer = answer
-		}
+		overBudget = h.answer(ctx, cl, in, &res)
+	}
+	dbg.LLMUsage = h.usageDebug(ctx, usage, overBudget)
+	return h.formatRouterResult(res, in.Format, in.Columns), nil
+}
+
+// runConfirmed executes a pending write plan held by an earlier query call.
+func (h *Handler) runConfirmed(ctx context.Context, token string, format string, columns []string) (*mcp.CallToolResult, error) {
+	pp, ok := h.pending.take(token, sessionFromContext(ctx))
+	if !ok {
+		return errorResult("unknown or expired confirmation token"), nil
+	}
+	// The policy may have changed since the plan was held; validate again.
+	policy := h.routerPolicy()
+	if err := router.ValidatePlanFrom(pp.plan, policy, h.buildRouterCatalog(), len(pp.plan.Steps), len(pp.prior)); err != nil {
+		return errorResult(err.Error()), nil
 	}
 
-	if strings.EqualFold(in.Format, "text") {
-		// Very small text wrapper around JSON.
-		b, _ := json.MarshalIndent(res, "", "  ")
-		return textResult(fmt.Sprintf("%s\n", string(b))), nil
+	ctx = withConfirmedWrites(ctx, token, pp.input)
+	res := router.RouterResult{Plan: pp.plan}
+	execSteps, manifest, err := h.executePlanAfter(ctx, pp.plan, pp.prior, policy, pp.output, pp.parallelism)
+	res.ExecutedSteps = execSteps
+	res.Manifest = manifest
+	markInterrupted(ctx, &res)
+	if err == nil {
+		markPartial(&res)
 	}
-	return jsonResult(res), nil
+	return h.formatRouterResult(res, format, columns), nil
 }
 
 type discoveryFastPath struct {
@@ -441,6 +519,7 @@ func (h *Handler) applyExtractedContextToPlan(plan *router.ModelPlan, ctx map[st
 				}
 			}
 		}
+		h.applyConfiguredContextArgs(step.Name, args, ctx)
 
 		if b, err := json.Marshal(args); err == nil {
 			plan.Steps[i].Args = b
@@ -700,7 +779,8 @@ func (h *Handler) buildRouterCatalog() []router.ToolCatalogItem {
 		})
 	}
 
-	// Upstream tools: iterate over known categories' tool lists (registry doesn't currently expose raw tool map).
+	// Upstream tools: known categories first, then everything else (e.g. namespaced "gitlab.*" tools).
+	seen := make(map[string]struct{})
 	for _, cat := range h.registry.ListCategories() {
 		for _, name := range cat.Tools {
 			// Never route to an upstream tool called query/router (prevents recursion when chaining mcp-lens instances).
@@ -711,6 +791,7 @@ func (h *Handler) buildRouterCatalog() []router.ToolCatalogItem {
 			if !ok {
 				continue
 			}
+			seen[name] = struct{}{}
 			items = append(items, router.ToolCatalogItem{
 				Name:        tool.Name,
 				Description: tool.Description,
@@ -720,14 +801,59 @@ func (h *Handler) buildRouterCatalog() []router.ToolCatalogItem {
 			})
 		}
 	}
-
-	// Also include any tools not in categories (best-effort via summaries map isn't exposed).
-	// Those tools can still be invoked if the model selects them, but catalog won't list them.
+	for _, tool := range h.registry.ListTools() {
+		if _, isLocal := local[tool.Name]; isLocal {
+			continue
+		}
+		if _, ok := seen[tool.Name]; ok {
+			continue
+		}
+		if isEntrypointTool(tool.Name) {
+			continue
+		}
+		category := "other"
+		if prefix, _, ok := strings.Cut(tool.Name, proxy.NamespaceSep); ok {
+			category = prefix
+		}
+		items = append(items, router.ToolCatalogItem{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Category:    category,
+			Source:      "upstream",
+			InputSchema: tool.InputSchema,
+		})
+	}
 
 	return items
 }
 
+// isEntrypointTool reports whether name is a query/router entrypoint, possibly namespaced
+// (a chained mcp-lens instance exposes "<name>.query").
+func isEntrypointTool(name string) bool {
+	if _, rest, ok := strings.Cut(name, proxy.NamespaceSep); ok {
+		name = rest
+	}
+	return name == "router" || name == "query"
+}
+
+// evalOutputExpr evaluates an output expr; it gets the tool's call timeout so a runaway expression cannot stall the plan.
+func evalOutputExpr(ctx context.Context, timeout time.Duration, v any, expr string) (any, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return router.ApplyOutputExpr(ctx, v, expr)
+}
+
 func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy router.Policy, output *router.OutputOptions, parallelism int) ([]router.ExecutedStep, *artifacts.Manifest, error) {
+	return h.executePlanAfter(ctx, plan, nil, policy, output, parallelism)
+}
+
+// executePlanAfter runs plan as the steps following prior, the executed steps of earlier iterative
+// rounds: plan step i is steps[len(prior)+i], and $ref steps[N] with N < len(prior) reads prior[N].Result
+// (the result the planner was shown).
+func (h *Handler) executePlanAfter(ctx context.Context, plan router.ModelPlan, prior []router.ExecutedStep, policy router.Policy, output *router.OutputOptions, parallelism int) ([]router.ExecutedStep, *artifacts.Manifest, error) {
 	if parallelism <= 0 {
 		parallelism = 1
 	}
@@ -741,10 +867,66 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 	// Max continuation iterations to prevent infinite loops
 	maxContinuations := 10
 	continuationCount := 0
+	// pages[i] is the continuation page of steps[i] (1 for planned steps).
+	pages := make([]int, len(steps))
+	for i := range pages {
+		pages[i] = 1
+	}
+	progress := newPlanProgress(ctx)
+
+	// Step output references ($ref) point at planned steps (numbered after prior); stepResults holds
+	// their raw (unshaped) results, and the results of prior steps that are referenced.
+	first := len(prior)
+	refs := make([][]int, len(plan.Steps))
+	referenced := map[int]bool{}
+	stepResults := map[int]any{}
+	for i, step := range plan.Steps {
+		refs[i], _ = router.StepRefs(step.Args)
+		if step.Fallback != nil {
+			// The fallback runs in place of the step (same index) and may reference earlier steps too.
+			fbRefs, _ := router.StepRefs(step.Fallback.Args)
+			refs[i] = append(refs[i], fbRefs...)
+		}
+		for _, r := range refs[i] {
+			if r >= first {
+				referenced[r-first] = true
+			} else if r >= 0 && prior[r].OK && prior[r].Result != nil {
+				stepResults[r] = prior[r].Result
+			}
+		}
+	}
+	var resultsMu sync.Mutex
+
+	// resolveRefs fills in a step's $ref args from the results of earlier steps.
+	resolveRefs := func(idx int, step router.PlanStep) (router.PlanStep, error) {
+		if idx >= len(refs) || len(refs[idx]) == 0 {
+			return step, nil
+		}
+		resultsMu.Lock()
+		args, err := router.ResolveStepRefs(step.Args, stepResults)
+		resultsMu.Unlock()
+		if err != nil {
+			return step, err
+		}
+		step.Args = args
+		return step, nil
+	}
 
 	var manifestMu sync.Mutex
-	execOne := func(step router.PlanStep) (router.ExecutedStep, map[string]any, *artifacts.Item, error) {
-		st := router.ExecutedStep{Name: step.Name, Source: step.Source, Args: step.Args}
+	execOne := func(idx int, step router.PlanStep) (router.ExecutedStep, map[string]any, *artifacts.Item, error) {
+		// st.Args and st.Error are redacted: they reach the caller, the summarizer, the audit log and artifact metadata.
+		st := router.ExecutedStep{Name: step.Name, Source: step.Source, Args: h.redactArgs(step.Args)}
+
+		if idx < len(refs) && len(refs[idx]) > 0 {
+			resolved, err := resolveRefs(idx, step)
+			if err != nil {
+				st.OK = false
+				h.setStepError(&st, "unresolved reference: "+err.Error())
+				return st, nil, nil, err
+			}
+			step = resolved
+			st.Args = h.redactArgs(step.Args)
+		}
 
 		if !policy.IsAllowed(step.Source, step.Name) {
 			st.OK = false
@@ -759,30 +941,65 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 			st.Error = "invalid args: must be JSON object"
 			return st, nil, nil, fmt.Errorf("invalid args for %s", step.Name)
 		}
+		// Re-check with args: continuation steps and context injection change args after validation.
+		if err := policy.Check(step.Source, step.Name, asObj); err != nil {
+			st.OK = false
+			h.setStepError(&st, err.Error())
+			return st, nil, nil, err
+		}
+		// Every confirmable write needs a confirmed plan, however the policy came to allow it.
+		if router.IsConfirmableWrite(step.Source, step.Name) {
+			if _, ok := confirmedWritesFromContext(ctx); !ok {
+				st.OK = false
+				st.Error = "write requires confirmation"
+				return st, nil, nil, fmt.Errorf("unconfirmed write: %s", step.Name)
+			}
+			defer func() { h.auditWrite(ctx, st) }()
+		}
 
-		var res *mcp.CallToolResult
-		var err error
-		if strings.EqualFold(step.Source, "local") {
-			res, err = h.Handle(ctx, step.Name, step.Args)
-		} else {
+		timeout := h.timeouts.forTool(step.Name)
+		res, err := callWithTimeout(ctx, timeout, func(callCtx context.Context) (*mcp.CallToolResult, error) {
+			if strings.EqualFold(step.Source, "local") {
+				return h.Handle(callCtx, step.Name, step.Args)
+			}
 			// Upstream tool execution.
-			res, err = h.executor(step.Name, step.Args)
+			return h.executor(callCtx, step.Name, step.Args)
+		})
+		if !strings.EqualFold(step.Source, "local") {
 			// We intentionally do not expose upstream tools via tools/list; activation is optional.
 			h.registry.Activate(step.Name)
 		}
+		if ctx.Err() != nil {
+			// The request was cancelled (or ran out of time) while the tool was running; discard whatever it returned.
+			st.OK = false
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				st.TimedOut = true
+				st.Error = "timeout: query deadline exceeded"
+			} else {
+				st.Cancelled = true
+				st.Error = "cancelled"
+			}
+			return st, nil, nil, ctx.Err()
+		}
+		if errors.Is(err, errStepTimeout) {
+			st.OK = false
+			st.TimedOut = true
+			st.Error = fmt.Sprintf("timeout: %s did not respond within %s", step.Name, timeout)
+			return st, nil, nil, err
+		}
 		if err != nil {
 			st.OK = false
-			st.Error = err.Error()
+			h.setStepError(&st, err.Error())
 			return st, nil, nil, err
 		}
 		st.OK = !res.IsError
 		if res.IsError {
 			if len(res.Content) > 0 {
-				st.Error = res.Content[0].Text
+				st.Error, st.Redactions = h.redactor.RedactText(res.Content[0].Text)
 			} else {
 				st.Error = "tool returned error"
 			}
-			return st, nil, nil, fmt.Errorf("tool error")
+			return st, nil, nil, errToolFailed
 		}
 
 		// Best-effort parse JSON if tool returned JSON text.
@@ -797,24 +1014,53 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 			}
 		}
 
+		// Secrets and PII are redacted before the result reaches artifacts, the summarizer or the caller.
+		// resultMap (continuations) and $ref values keep the tool's output: they only go back to tools.
+		st.Result, st.Redactions = h.redactor.RedactValue(st.Result)
+
 		var created *artifacts.Item
 		if st.Result != nil {
 			// Artifact decision should be based on the original tool output, before shaping.
 			originalForArtifacts := st.Result
+			if referenced[idx] {
+				// Shaping may modify the result in place; later steps reference the unshaped output.
+				var raw any
+				if json.Unmarshal([]byte(res.Content[0].Text), &raw) != nil {
+					raw = res.Content[0].Text
+				}
+				resultsMu.Lock()
+				stepResults[first+idx] = raw
+				resultsMu.Unlock()
+			}
 
-			shaped, err := router.ApplyOutputShaping(step.Name, st.Result, output)
+			opts := output
+			if step.Output != nil {
+				opts = step.Output
+			}
+			if opts != nil && strings.TrimSpace(opts.Expr) != "" {
+				// The expression selects what the caller wants, so it also decides about artifacts.
+				// A failing expression is reported on the step; the rest of the shaping still applies.
+				v, err := evalOutputExpr(ctx, timeout, st.Result, opts.Expr)
+				if err != nil {
+					st.OutputError = err.Error()
+				} else {
+					st.Result = v
+					originalForArtifacts = v
+				}
+			}
+			shaped, err := router.ApplyOutputShaping(router.ViewPresets(h.registry.Views(step.Name)), st.Result, opts)
 			if err != nil {
 				st.OK = false
-				st.Error = "output shaping error: " + err.Error()
+				h.setStepError(&st, "output shaping error: "+err.Error())
 				return st, resultMap, nil, err
 			}
 			st.Result = shaped
 
 			if h.artifacts != nil && router.JSONSize(originalForArtifacts) > h.artifacts.InlineMaxBytes() {
-				repl, item, err := h.artifacts.MaybeStore(step.Name, step.Args, originalForArtifacts)
+				repl, item, err := h.artifacts.MaybeStore(step.Name, st.Args, originalForArtifacts)
 				if err != nil {
 					st.OK = false
-					st.Error = "artifact store error: " + err.Error()
+					h.setStepError(&st, "artifact store error: "+err.Error())
 					return st, resultMap, nil, err
 				}
 				if item != nil {
@@ -827,7 +1073,64 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 		return st, resultMap, created, nil
 	}
 
+	// execRetry runs execOne and retries transient failures with backoff (see stepRetry).
+	execRetry := func(idx int, step router.PlanStep) (router.ExecutedStep, map[string]any, *artifacts.Item, error) {
+		for attempt := 1; ; attempt++ {
+			st, resultMap, created, err := execOne(idx, step)
+			if attempt > 1 {
+				st.Attempts = attempt
+			}
+			if err == nil || ctx.Err() != nil {
+				return st, resultMap, created, err
+			}
+			wait, ok := h.retry.next(attempt, step, st, err)
+			if !ok {
+				return st, resultMap, created, err
+			}
+			progress.stepRetrying(idx, step.Name, attempt+1, wait, st.Error)
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return st, resultMap, created, err
+			case <-timer.C:
+			}
+		}
+	}
+
+	// runStep runs a step and applies its on_error handling. ran is the step that produced the
+	// result (the fallback, if one ran) with resolved args; failures handled by on_error return no error.
+	runStep := func(idx int, step router.PlanStep) ([]router.ExecutedStep, router.PlanStep, map[string]any, *artifacts.Item, error) {
+		st, resultMap, created, err := execRetry(idx, step)
+		// Continuations reuse the resolved args (st.Args is redacted).
+		if resolved, rerr := resolveRefs(idx, step); rerr == nil {
+			step = resolved
+		}
+		recs := []router.ExecutedStep{st}
+		if err == nil || ctx.Err() != nil {
+			return recs, step, resultMap, created, err
+		}
+		switch {
+		case step.OnError == router.OnErrorContinue:
+			return recs, step, nil, nil, nil
+		case step.OnError == router.OnErrorFallback && step.Fallback != nil:
+			fb := *step.Fallback
+			progress.fallbackStarted(idx, step.Name, fb.Name)
+			fst, resultMap, created, err := execRetry(idx, fb)
+			fst.FallbackFor = step.Name
+			if resolved, rerr := resolveRefs(idx, fb); rerr == nil {
+				fb = resolved
+			}
+			return append(recs, fst), fb, resultMap, created, err
+		}
+		return recs, step, resultMap, created, err
+	}
+
 	for i := 0; i < len(steps); i++ {
+		if ctx.Err() != nil {
+			out = append(out, h.cancelledSteps(steps[i:], ctx.Err())...)
+			return out, manifest, ctx.Err()
+		}
 		step := steps[i]
 
 		pg := strings.TrimSpace(step.ParallelGroup)
@@ -838,9 +1141,13 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 				if strings.TrimSpace(steps[j].ParallelGroup) != pg || !strings.EqualFold(steps[j].Source, "local") {
 					break
 				}
+				if j < len(refs) && refersToRange(refs[j], first+i, first+j) {
+					// Dataflow inside the group: the referencing step starts a new group.
+					break
+				}
 				group = append(group, steps[j])
 			}
-			results := make([]router.ExecutedStep, len(group))
+			results := make([][]router.ExecutedStep, len(group))
 			resultMaps := make([]map[string]any, len(group))
 			createdItems := make([]*artifacts.Item, len(group))
 			errs := make([]error, len(group))
@@ -850,9 +1157,11 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 			for idx := range group {
 				idx := idx
 				step := group[idx]
+				progress.stepStarted(i+idx, len(steps), step.Name, pages[i+idx])
 				g.Go(func() error {
-					st, rm, item, err := execOne(step)
-					results[idx] = st
+					recs, ran, rm, item, err := runStep(i+idx, step)
+					results[idx] = recs
+					group[idx] = ran
 					resultMaps[idx] = rm
 					createdItems[idx] = item
 					errs[idx] = err
@@ -866,8 +1175,9 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 					manifestMu.Lock()
 					manifest.Artifacts = append(manifest.Artifacts, *createdItems[idx])
 					manifestMu.Unlock()
+					progress.artifactCreated(i+idx, group[idx].Name, artifacts.ArtifactURI(createdItems[idx].ID))
 				}
-				out = append(out, results[idx])
+				out = append(out, results[idx]...)
 			}
 
 			// Continuations (append at end, like sequential mode).
@@ -875,6 +1185,7 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 				if resultMaps[idx] != nil && continuationCount < maxContinuations {
 					if contStep := h.createContinuationStep(group[idx], resultMaps[idx]); contStep != nil {
 						steps = append(steps, *contStep)
+						pages = append(pages, pages[i+idx]+1)
 						continuationCount++
 					}
 				}
@@ -891,11 +1202,14 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 			continue
 		}
 
-		st, resultMap, created, err := execOne(step)
+		progress.stepStarted(i, len(steps), step.Name, pages[i])
+		recs, ran, resultMap, created, err := runStep(i, step)
+		step = ran
 		if created != nil {
 			manifest.Artifacts = append(manifest.Artifacts, *created)
+			progress.artifactCreated(i, step.Name, artifacts.ArtifactURI(created.ID))
 		}
-		out = append(out, st)
+		out = append(out, recs...)
 		if err != nil {
 			return out, manifest, err
 		}
@@ -904,6 +1218,7 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 		if resultMap != nil && continuationCount < maxContinuations {
 			if contStep := h.createContinuationStep(step, resultMap); contStep != nil {
 				steps = append(steps, *contStep)
+				pages = append(pages, pages[i]+1)
 				continuationCount++
 			}
 		}
@@ -914,6 +1229,39 @@ func (h *Handler) executePlan(ctx context.Context, plan router.ModelPlan, policy
 	return out, manifest, nil
 }
 
+// refersToRange reports whether any of refs is in [from, to).
+func refersToRange(refs []int, from, to int) bool {
+	for _, r := range refs {
+		if r >= from && r < to {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelledSteps reports plan steps that were never started because the request was cancelled
+// or the query deadline passed.
+func (h *Handler) cancelledSteps(steps []router.PlanStep, cause error) []router.ExecutedStep {
+	timedOut := errors.Is(cause, context.DeadlineExceeded)
+	out := make([]router.ExecutedStep, 0, len(steps))
+	for _, step := range steps {
+		st := router.ExecutedStep{
+			Name:      step.Name,
+			Source:    step.Source,
+			Args:      h.redactArgs(step.Args),
+			OK:        false,
+			Error:     "cancelled before execution",
+			Cancelled: !timedOut,
+			TimedOut:  timedOut,
+		}
+		if timedOut {
+			st.Error = "timeout: query deadline exceeded before execution"
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
 // createContinuationStep creates a continuation step if the result has pagination info
 func (h *Handler) createContinuationStep(originalStep router.PlanStep, result map[string]any) *router.PlanStep {
 	// Parse original args
@@ -3,15 +3,17 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/golovatskygroup/mcp-lens/internal/registry"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
 	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
 )
 
 func TestQueryDiscoveryFastPathDoesNotRequireOpenRouter(t *testing.T) {
 	reg := registry.NewRegistry()
-	h := NewHandler(reg, func(name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
 		t.Fatalf("executor should not be called")
 		return nil, nil
 	})
@@ -33,7 +35,7 @@ func TestQueryDiscoveryFastPathDoesNotRequireOpenRouter(t *testing.T) {
 
 func TestQueryExecutorModeDoesNotRequireOpenRouter(t *testing.T) {
 	reg := registry.NewRegistry()
-	h := NewHandler(reg, func(name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
 		t.Fatalf("upstream executor should not be called")
 		return nil, nil
 	})
@@ -67,3 +69,118 @@ func TestQueryExecutorModeDoesNotRequireOpenRouter(t *testing.T) {
 		t.Fatalf("expected content")
 	}
 }
+
+func TestExecutePlanCancelledReturnsPartialSteps(t *testing.T) {
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, nil)
+	reg.LoadTools(h.BuiltinTools())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	args := json.RawMessage(`{"query":"grafana","format":"json"}`)
+	plan := router.ModelPlan{Steps: []router.PlanStep{
+		{Name: "search_tools", Source: "local", Args: args},
+		{Name: "search_tools", Source: "local", Args: args},
+	}}
+	steps, _, err := h.executePlan(ctx, plan, router.DefaultPolicy(), nil, 1)
+	if err == nil {
+		t.Fatalf("expected cancellation error")
+	}
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 reported steps, got %d", len(steps))
+	}
+	for _, st := range steps {
+		if !st.Cancelled || st.OK {
+			t.Fatalf("expected step marked cancelled, got %+v", st)
+		}
+	}
+}
+
+func TestExecutePlanReportsProgress(t *testing.T) {
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, nil)
+	reg.LoadTools(h.BuiltinTools())
+
+	var got []string
+	var last float64
+	ctx := WithProgress(context.Background(), func(progress float64, message string) {
+		if progress <= last {
+			t.Errorf("progress must increase: %v after %v", progress, last)
+		}
+		last = progress
+		got = append(got, message)
+	})
+
+	args := json.RawMessage(`{"query":"grafana","format":"json"}`)
+	plan := router.ModelPlan{Steps: []router.PlanStep{
+		{Name: "search_tools", Source: "local", Args: args},
+		{Name: "describe_tool", Source: "local", Args: json.RawMessage(`{"name":"search_tools"}`)},
+	}}
+	if _, _, err := h.executePlan(ctx, plan, router.DefaultPolicy(), nil, 1); err != nil {
+		t.Fatalf("executePlan: %v", err)
+	}
+	if len(got) != 2 || got[0] != "step 1/2: search_tools" || got[1] != "step 2/2: describe_tool" {
+		t.Fatalf("unexpected progress messages: %q", got)
+	}
+}
+
+func TestRouterCatalogIncludesNamespacedUpstreamTools(t *testing.T) {
+	reg := registry.NewRegistry()
+	var called string
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		called = name
+		return &mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: `{"ok":true}`}}}, nil
+	})
+	reg.LoadTools(h.BuiltinTools())
+	reg.LoadTools([]mcp.Tool{
+		{Name: "gitlab.list_projects", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		{Name: "chained.query", InputSchema: json.RawMessage(`{"type":"object"}`)},
+	})
+
+	var item *router.ToolCatalogItem
+	for _, it := range h.buildRouterCatalog() {
+		if it.Name == "chained.query" {
+			t.Fatalf("namespaced entrypoint tools must not be routable")
+		}
+		if it.Name == "gitlab.list_projects" {
+			it := it
+			item = &it
+		}
+	}
+	if item == nil || item.Source != "upstream" || item.Category != "gitlab" {
+		t.Fatalf("expected namespaced upstream tool in catalog, got %+v", item)
+	}
+
+	policy := router.DefaultPolicy()
+	policy.AllowUpstream["gitlab.list_projects"] = struct{}{}
+	plan := router.ModelPlan{Steps: []router.PlanStep{{Name: "gitlab.list_projects", Source: "upstream", Args: json.RawMessage(`{}`)}}}
+	if err := router.ValidatePlan(plan, policy, h.buildRouterCatalog(), 1); err != nil {
+		t.Fatalf("ValidatePlan: %v", err)
+	}
+	if _, _, err := h.executePlan(context.Background(), plan, policy, nil, 1); err != nil {
+		t.Fatalf("executePlan: %v", err)
+	}
+	if called != "gitlab.list_projects" {
+		t.Fatalf("expected executor to receive namespaced name, got %q", called)
+	}
+}
+
+func TestSearchToolsAcceptsUpstreamCategory(t *testing.T) {
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, nil)
+	reg.LoadTools(h.BuiltinTools())
+	reg.SetUpstreamTools("gitlab", []mcp.Tool{{Name: "gitlab.list_merge_requests", Description: "List merge requests", InputSchema: json.RawMessage(`{"type":"object"}`)}})
+
+	res := runQuery(t, h, map[string]any{
+		"input": "find gitlab tools",
+		"steps": []map[string]any{{"name": "search_tools", "source": "local", "args": map[string]any{"query": "merge", "category": "gitlab", "format": "json"}}},
+	})
+	if len(res.ExecutedSteps) != 1 || !res.ExecutedSteps[0].OK {
+		t.Fatalf("expected search_tools with an upstream category to run, got %+v", res)
+	}
+	b, _ := json.Marshal(res.ExecutedSteps[0].Result)
+	if !strings.Contains(string(b), "gitlab.list_merge_requests") {
+		t.Fatalf("expected the upstream tool in results, got %s", b)
+	}
+}
@@ -0,0 +1,23 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+type samplingKey struct{}
+
+// WithSampling lets the router fall back to the MCP client's LLM (sampling/createMessage)
+// when no LLM provider is configured. Set it only for clients that declared the sampling capability.
+func WithSampling(ctx context.Context, fn router.CreateMessageFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, samplingKey{}, fn)
+}
+
+func samplingFromContext(ctx context.Context) router.CreateMessageFunc {
+	fn, _ := ctx.Value(samplingKey{}).(router.CreateMessageFunc)
+	return fn
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExecutorResolvesStepRefs(t *testing.T) {
+	h := ciHandler(t)
+
+	res := runQuery(t, h, map[string]any{
+		"input": "log of the failing job",
+		"mode":  "executor",
+		"steps": []map[string]any{
+			{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}},
+			{"name": "ci.get_log", "source": "upstream", "args": map[string]any{"job": map[string]any{"$ref": "steps[0].result.jobs[0].id"}}},
+		},
+		// Shaping the first result must not affect what later steps reference.
+		"output": map[string]any{"exclude_fields": []string{"jobs"}},
+	})
+	if len(res.ExecutedSteps) != 2 || !res.ExecutedSteps[1].OK {
+		t.Fatalf("unexpected steps: %+v", res.ExecutedSteps)
+	}
+	var args map[string]any
+	if err := json.Unmarshal(res.ExecutedSteps[1].Args, &args); err != nil || args["job"] != float64(4711) {
+		t.Fatalf("expected resolved args, got %s", res.ExecutedSteps[1].Args)
+	}
+	logRes, _ := res.ExecutedSteps[1].Result.(map[string]any)
+	if got, _ := logRes["args"].(map[string]any); got["job"] != float64(4711) {
+		t.Fatalf("upstream did not receive the resolved value: %+v", res.ExecutedSteps[1].Result)
+	}
+}
+
+func TestExecutorReportsUnresolvedStepRefs(t *testing.T) {
+	h := ciHandler(t)
+
+	res := runQuery(t, h, map[string]any{
+		"input": "x",
+		"mode":  "executor",
+		"steps": []map[string]any{
+			{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}},
+			{"name": "ci.get_log", "source": "upstream", "args": map[string]any{"job": map[string]any{"$ref": "steps[0].result.jobs[5].id"}}},
+		},
+	})
+	if len(res.ExecutedSteps) != 2 || res.ExecutedSteps[1].OK || !strings.HasPrefix(res.ExecutedSteps[1].Error, "unresolved reference:") {
+		t.Fatalf("expected unresolved reference error, got %+v", res.ExecutedSteps)
+	}
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+const (
+	defaultStepRetries      = 2
+	defaultStepRetryBackoff = 500 * time.Millisecond
+	defaultStepRetryMaxWait = 30 * time.Second
+)
+
+// errToolFailed is returned by executePlan when a tool returned an error result.
+var errToolFailed = errors.New("tool error")
+
+var (
+	// transientStatusRe matches retryable HTTP statuses in tool errors, e.g. "GitHub API error (502)" or "upstream HTTP 503".
+	transientStatusRe = regexp.MustCompile(`(?i)(\(|HTTP |status[=: ]\s*)(408|429|502|503|504)\b`)
+	retryAfterRe      = regexp.MustCompile(`(?i)retry[-_ ]after[:=\s]*(\d+)`)
+	transientTexts    = []string{
+		"timeout", "timed out", "deadline exceeded", "connection reset", "connection refused", "broken pipe",
+		"temporarily unavailable", "rate limit", "upstream process exited", "upstream closed stdout", "unexpected eof",
+	}
+)
+
+// stepRetry is the retry policy executePlan applies to transient step failures.
+type stepRetry struct {
+	retries int
+	backoff time.Duration
+	maxWait time.Duration
+}
+
+// stepRetryFromEnv reads:
+//   - MCP_LENS_STEP_RETRIES: retries per step after a transient failure (default 2, 0 disables)
+//   - MCP_LENS_STEP_RETRY_BACKOFF_MS: first backoff, doubled on every retry (default 500)
+//   - MCP_LENS_STEP_RETRY_MAX_WAIT_SECONDS: longest single wait; a longer Retry-After is not retried (default 30)
+func stepRetryFromEnv() stepRetry {
+	r := stepRetry{
+		retries: defaultStepRetries,
+		backoff: defaultStepRetryBackoff,
+		maxWait: secondsFromEnv("MCP_LENS_STEP_RETRY_MAX_WAIT_SECONDS", defaultStepRetryMaxWait),
+	}
+	if v := strings.TrimSpace(os.Getenv("MCP_LENS_STEP_RETRIES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			r.retries = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("MCP_LENS_STEP_RETRY_BACKOFF_MS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			r.backoff = time.Duration(n) * time.Millisecond
+		}
+	}
+	return r
+}
+
+// next returns how long to wait before retrying a step that failed on attempt n (1-based),
+// or false if the failure is permanent, the step is a write or the retries are used up.
+// Writes (Jira writes and tools whose names look mutating) are never retried: a timeout,
+// gateway error or dropped connection may come after the write was applied.
+func (r stepRetry) next(attempt int, step router.PlanStep, st router.ExecutedStep, err error) (time.Duration, bool) {
+	if attempt > r.retries || router.IsConfirmableWrite(step.Source, step.Name) || router.IsMutatingName(step.Name) {
+		return 0, false
+	}
+	if !errors.Is(err, errStepTimeout) && !isTransientError(st.Error) {
+		return 0, false
+	}
+	if d, ok := retryAfterFromError(st.Error); ok {
+		return d, d <= r.maxWait
+	}
+	wait := r.backoff << (attempt - 1)
+	if wait <= 0 || wait > r.maxWait {
+		wait = r.maxWait
+	}
+	return wait, true
+}
+
+// isTransientError reports whether a step error looks like a rate limit, gateway error or network hiccup.
+func isTransientError(msg string) bool {
+	if transientStatusRe.MatchString(msg) {
+		return true
+	}
+	lower := strings.ToLower(msg)
+	for _, t := range transientTexts {
+		if strings.Contains(lower, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfterFromError extracts a Retry-After delay in seconds from a tool error (see jiraAuthHint).
+func retryAfterFromError(msg string) (time.Duration, bool) {
+	m := retryAfterRe.FindStringSubmatch(msg)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// markPartial flags results of a plan that ran to the end although some steps failed (on_error continue/fallback).
+func markPartial(res *router.RouterResult) {
+	for _, st := range res.ExecutedSteps {
+		if !st.OK {
+			res.Partial = true
+			return
+		}
+	}
+}
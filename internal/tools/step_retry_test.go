@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/internal/registry"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+// flakyHandler serves gh.get_pr, which fails with errText for the first failures calls,
+// and gh.get_pr_cached, which always succeeds.
+func flakyHandler(t *testing.T, failures int32, errText string) (*Handler, *int32) {
+	t.Helper()
+	var calls int32
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		if name == "gh.get_pr" && atomic.AddInt32(&calls, 1) <= failures {
+			return &mcp.CallToolResult{IsError: true, Content: []mcp.ContentBlock{{Type: "text", Text: errText}}}, nil
+		}
+		return &mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: `{"tool":"` + name + `"}`}}}, nil
+	})
+	h.retry = stepRetry{retries: 2, backoff: time.Millisecond, maxWait: time.Second}
+	reg.LoadTools(h.BuiltinTools())
+	reg.LoadTools([]mcp.Tool{
+		{Name: "gh.get_pr", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		{Name: "gh.get_pr_cached", InputSchema: json.RawMessage(`{"type":"object"}`)},
+	})
+	p := router.DefaultPolicy()
+	p.AllowUpstream = map[string]struct{}{"gh.get_pr": {}, "gh.get_pr_cached": {}}
+	h.SetPolicy(p)
+	return h, &calls
+}
+
+func TestStepRetriesTransientErrors(t *testing.T) {
+	h, calls := flakyHandler(t, 2, "GitHub API error (502): Bad Gateway")
+	res := runQuery(t, h, map[string]any{
+		"input": "pr",
+		"steps": []map[string]any{{"name": "gh.get_pr", "source": "upstream", "args": map[string]any{}}},
+	})
+	if len(res.ExecutedSteps) != 1 || !res.ExecutedSteps[0].OK || res.ExecutedSteps[0].Attempts != 3 {
+		t.Fatalf("expected success on the third attempt, got %+v", res.ExecutedSteps)
+	}
+	if *calls != 3 || res.Partial {
+		t.Fatalf("calls=%d partial=%v", *calls, res.Partial)
+	}
+
+	// Permanent errors are not retried.
+	h, calls = flakyHandler(t, 1, "GitHub API error (404): Not Found")
+	res = runQuery(t, h, map[string]any{
+		"input": "pr",
+		"steps": []map[string]any{{"name": "gh.get_pr", "source": "upstream", "args": map[string]any{}}},
+	})
+	if *calls != 1 || len(res.ExecutedSteps) != 1 || res.ExecutedSteps[0].OK {
+		t.Fatalf("expected a single failed attempt, calls=%d steps=%+v", *calls, res.ExecutedSteps)
+	}
+}
+
+func TestStepOnErrorContinueAndFallback(t *testing.T) {
+	h, _ := flakyHandler(t, 100, "GitHub API error (404): Not Found")
+	res := runQuery(t, h, map[string]any{
+		"input": "pr",
+		"steps": []map[string]any{
+			{"name": "gh.get_pr", "source": "upstream", "args": map[string]any{}, "on_error": "continue"},
+			{"name": "gh.get_pr", "source": "upstream", "args": map[string]any{}, "on_error": "fallback",
+				"fallback": map[string]any{"name": "gh.get_pr_cached", "source": "upstream", "args": map[string]any{}}},
+			{"name": "gh.get_pr_cached", "source": "upstream", "args": map[string]any{}},
+		},
+	})
+	if !res.Partial || len(res.ExecutedSteps) != 4 {
+		t.Fatalf("expected partial result with 4 executed steps, got %+v", res)
+	}
+	fb := res.ExecutedSteps[2]
+	if !fb.OK || fb.Name != "gh.get_pr_cached" || fb.FallbackFor != "gh.get_pr" {
+		t.Fatalf("expected fallback step to run, got %+v", fb)
+	}
+	if !res.ExecutedSteps[3].OK {
+		t.Fatalf("expected the plan to go on, got %+v", res.ExecutedSteps[3])
+	}
+
+	// A fallback without on_error=fallback is rejected by validation.
+	args, _ := json.Marshal(map[string]any{
+		"input": "pr",
+		"steps": []map[string]any{{"name": "gh.get_pr", "source": "upstream", "args": map[string]any{},
+			"fallback": map[string]any{"name": "gh.get_pr_cached", "source": "upstream", "args": map[string]any{}}}},
+	})
+	out, err := h.Handle(context.Background(), "query", args)
+	if err != nil || !out.IsError {
+		t.Fatalf("expected validation error, got %+v", out)
+	}
+}
+
+func TestStepRetryNext(t *testing.T) {
+	r := stepRetry{retries: 2, backoff: 100 * time.Millisecond, maxWait: 10 * time.Second}
+	step := router.PlanStep{Name: "jira_get_issue", Source: "local"}
+
+	wait, ok := r.next(2, step, router.ExecutedStep{Error: "Jira API error (429): {}\nJira API returned 429 (rate limited). Respect Retry-After and retry with backoff. Retry-After: 3"}, errToolFailed)
+	if !ok || wait != 3*time.Second {
+		t.Fatalf("expected Retry-After wait, got %s %v", wait, ok)
+	}
+	if _, ok := r.next(1, step, router.ExecutedStep{Error: "Retry-After: 60 (429)"}, errToolFailed); ok {
+		t.Fatal("expected Retry-After beyond max wait not to be retried")
+	}
+	if wait, ok := r.next(2, step, router.ExecutedStep{Error: "timeout"}, errStepTimeout); !ok || wait != 200*time.Millisecond {
+		t.Fatalf("expected exponential backoff, got %s %v", wait, ok)
+	}
+	if _, ok := r.next(3, step, router.ExecutedStep{Error: "timeout"}, errStepTimeout); ok {
+		t.Fatal("expected retries to be exhausted")
+	}
+	if _, ok := r.next(1, router.PlanStep{Name: "jira_add_comment", Source: "local"}, router.ExecutedStep{Error: "(503)"}, errToolFailed); ok {
+		t.Fatal("expected write steps not to be retried")
+	}
+	upstreamWrite := router.PlanStep{Name: "gitlab.create_issue", Source: "upstream"}
+	if _, ok := r.next(1, upstreamWrite, router.ExecutedStep{Error: "timeout"}, errStepTimeout); ok {
+		t.Fatal("expected timed-out mutating upstream steps not to be retried")
+	}
+	if _, ok := r.next(1, router.PlanStep{Name: "gitlab.list_issues", Source: "upstream"}, router.ExecutedStep{Error: "timeout"}, errStepTimeout); !ok {
+		t.Fatal("expected timed-out read-only upstream steps to be retried")
+	}
+}
+
+func TestMutatingStepsAreNotRetried(t *testing.T) {
+	var calls int32
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		atomic.AddInt32(&calls, 1)
+		return &mcp.CallToolResult{IsError: true, Content: []mcp.ContentBlock{{Type: "text", Text: "GitLab API error (502): Bad Gateway"}}}, nil
+	})
+	h.retry = stepRetry{retries: 2, backoff: time.Millisecond, maxWait: time.Second}
+	reg.LoadTools(h.BuiltinTools())
+	reg.LoadTools([]mcp.Tool{{Name: "gitlab.create_issue", InputSchema: json.RawMessage(`{"type":"object"}`)}})
+	p := router.DefaultPolicy()
+	p.AllowMutating = true
+	p.AllowUpstream = map[string]struct{}{"gitlab.create_issue": {}}
+	h.SetPolicy(p)
+
+	res := runQuery(t, h, map[string]any{
+		"input": "file it",
+		"steps": []map[string]any{{"name": "gitlab.create_issue", "source": "upstream", "args": map[string]any{}}},
+	})
+	if calls != 1 || len(res.ExecutedSteps) != 1 || res.ExecutedSteps[0].OK {
+		t.Fatalf("expected a single failed attempt, calls=%d steps=%+v", calls, res.ExecutedSteps)
+	}
+}
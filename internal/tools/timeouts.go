@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+const (
+	defaultToolTimeout  = 2 * time.Minute
+	defaultQueryTimeout = 10 * time.Minute
+)
+
+// errStepTimeout is returned by executePlan when a step exceeded its per-call timeout.
+var errStepTimeout = errors.New("step timed out")
+
+// callTimeouts bounds tool calls made by executePlan and the query as a whole.
+type callTimeouts struct {
+	def     time.Duration
+	perTool map[string]time.Duration
+	query   time.Duration
+}
+
+// timeoutsFromEnv reads:
+//   - MCP_LENS_TOOL_TIMEOUT_SECONDS: default per-call timeout (default 120)
+//   - MCP_LENS_TOOL_TIMEOUTS: per-tool overrides, e.g. "jira_export_issues=600,gitlab.list_pipelines=30"
+//   - MCP_LENS_QUERY_TIMEOUT_SECONDS: overall deadline of one query call (default 600)
+func timeoutsFromEnv() callTimeouts {
+	t := callTimeouts{
+		def:     secondsFromEnv("MCP_LENS_TOOL_TIMEOUT_SECONDS", defaultToolTimeout),
+		perTool: map[string]time.Duration{},
+		query:   secondsFromEnv("MCP_LENS_QUERY_TIMEOUT_SECONDS", defaultQueryTimeout),
+	}
+	for _, part := range strings.Split(os.Getenv("MCP_LENS_TOOL_TIMEOUTS"), ",") {
+		name, secs, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(secs)); err == nil && n > 0 {
+			t.perTool[strings.TrimSpace(name)] = time.Duration(n) * time.Second
+		}
+	}
+	return t
+}
+
+// SetTimeouts applies `timeouts:` from the config file on top of the env/default values.
+func (h *Handler) SetTimeouts(cfg router.TimeoutsConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	t := timeoutsFromEnv()
+	if cfg.DefaultSeconds > 0 {
+		t.def = time.Duration(cfg.DefaultSeconds) * time.Second
+	}
+	if cfg.QuerySeconds > 0 {
+		t.query = time.Duration(cfg.QuerySeconds) * time.Second
+	}
+	for name, secs := range cfg.Tools {
+		t.perTool[strings.TrimSpace(name)] = time.Duration(secs) * time.Second
+	}
+	h.timeouts = t
+	return nil
+}
+
+func secondsFromEnv(key string, def time.Duration) time.Duration {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return def
+}
+
+func (t callTimeouts) forTool(name string) time.Duration {
+	if d, ok := t.perTool[name]; ok {
+		return d
+	}
+	return t.def
+}
+
+// queryDeadline returns the overall budget of a query; a caller-provided budget may only shorten it.
+func (t callTimeouts) queryDeadline(requestedSeconds int) time.Duration {
+	if requestedSeconds > 0 {
+		if d := time.Duration(requestedSeconds) * time.Second; d < t.query {
+			return d
+		}
+	}
+	return t.query
+}
+
+// callWithTimeout runs call with a per-call deadline. It returns as soon as the deadline passes,
+// even if the tool ignores its context; the abandoned call finishes in the background.
+func callWithTimeout(ctx context.Context, timeout time.Duration, call func(context.Context) (*mcp.CallToolResult, error)) (*mcp.CallToolResult, error) {
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		res *mcp.CallToolResult
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := call(callCtx)
+		done <- result{res, err}
+	}()
+	select {
+	case r := <-done:
+		if ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			return nil, errStepTimeout
+		}
+		return r.res, r.err
+	case <-callCtx.Done():
+		if ctx.Err() == nil {
+			return nil, errStepTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// markInterrupted records why a query stopped early: client cancellation or the query deadline.
+func markInterrupted(ctx context.Context, res *router.RouterResult) {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		res.TimedOut = true
+	case ctx.Err() != nil:
+		res.Cancelled = true
+	}
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/internal/registry"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+	"github.com/golovatskygroup/mcp-lens/pkg/mcp"
+)
+
+func runSlowQuery(t *testing.T, h *Handler, extra map[string]any) router.RouterResult {
+	t.Helper()
+	in := map[string]any{
+		"input": "fetch",
+		"mode":  "executor",
+		"steps": []map[string]any{
+			{"name": "slow.fetch", "source": "upstream", "args": map[string]any{}},
+			{"name": "slow.other", "source": "upstream", "args": map[string]any{}},
+		},
+	}
+	for k, v := range extra {
+		in[k] = v
+	}
+	return runQuery(t, h, in)
+}
+
+func slowHandler(t *testing.T, block chan struct{}) *Handler {
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		// Ignores ctx on purpose: a stuck upstream must not hang the query.
+		<-block
+		return &mcp.CallToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: "{}"}}}, nil
+	})
+	reg.LoadTools(h.BuiltinTools())
+	p := router.DefaultPolicy()
+	p.AllowUpstream = map[string]struct{}{"slow.fetch": {}, "slow.other": {}}
+	h.SetPolicy(p)
+	reg.LoadTools([]mcp.Tool{
+		{Name: "slow.fetch", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		{Name: "slow.other", InputSchema: json.RawMessage(`{"type":"object"}`)},
+	})
+	return h
+}
+
+func TestExecutePlanPerToolTimeout(t *testing.T) {
+	t.Setenv("MCP_LENS_TOOL_TIMEOUTS", "slow.fetch=1, bogus")
+	t.Setenv("MCP_LENS_STEP_RETRIES", "0") // timeouts are retried by default
+	block := make(chan struct{})
+	defer close(block)
+	h := slowHandler(t, block)
+
+	start := time.Now()
+	res := runSlowQuery(t, h, nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("query took %s despite 1s tool timeout", elapsed)
+	}
+	if len(res.ExecutedSteps) != 1 {
+		t.Fatalf("expected execution to stop at the timed-out step, got %+v", res.ExecutedSteps)
+	}
+	st := res.ExecutedSteps[0]
+	if st.OK || !st.TimedOut || st.Cancelled || !strings.HasPrefix(st.Error, "timeout:") {
+		t.Fatalf("expected timed-out step, got %+v", st)
+	}
+	if res.TimedOut || res.Cancelled {
+		t.Fatalf("a single step timeout is not a query deadline: %+v", res)
+	}
+}
+
+func TestQueryDeadline(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	h := slowHandler(t, block)
+
+	res := runSlowQuery(t, h, map[string]any{"timeout_seconds": 1})
+	if !res.TimedOut || res.Cancelled {
+		t.Fatalf("expected query deadline, got %+v", res)
+	}
+	if len(res.ExecutedSteps) != 1 || !res.ExecutedSteps[0].TimedOut {
+		t.Fatalf("expected the running step to be reported as timed out, got %+v", res.ExecutedSteps)
+	}
+}
+
+func TestTimeoutsFromEnv(t *testing.T) {
+	t.Setenv("MCP_LENS_TOOL_TIMEOUT_SECONDS", "30")
+	t.Setenv("MCP_LENS_TOOL_TIMEOUTS", "jira_export_issues=600,bad=x")
+	t.Setenv("MCP_LENS_QUERY_TIMEOUT_SECONDS", "90")
+	to := timeoutsFromEnv()
+	if to.forTool("jira_export_issues") != 10*time.Minute || to.forTool("bad") != 30*time.Second {
+		t.Fatalf("unexpected per-tool timeouts: %+v", to)
+	}
+	if to.queryDeadline(0) != 90*time.Second || to.queryDeadline(10) != 10*time.Second || to.queryDeadline(1000) != 90*time.Second {
+		t.Fatalf("unexpected query deadline: %+v", to)
+	}
+}
+
+func TestSetTimeoutsFromConfig(t *testing.T) {
+	t.Setenv("MCP_LENS_TOOL_TIMEOUT_SECONDS", "30")
+	t.Setenv("MCP_LENS_TOOL_TIMEOUTS", "slow.other=5")
+	h := NewHandler(registry.NewRegistry(), nil)
+
+	if err := h.SetTimeouts(router.TimeoutsConfig{QuerySeconds: 90, Tools: map[string]int{"slow.fetch": 600}}); err != nil {
+		t.Fatalf("SetTimeouts: %v", err)
+	}
+	if h.timeouts.forTool("slow.fetch") != 10*time.Minute || h.timeouts.forTool("slow.other") != 5*time.Second || h.timeouts.forTool("x") != 30*time.Second {
+		t.Fatalf("unexpected per-tool timeouts %+v", h.timeouts)
+	}
+	if h.timeouts.queryDeadline(0) != 90*time.Second {
+		t.Fatalf("unexpected query deadline %v", h.timeouts.queryDeadline(0))
+	}
+	if err := h.SetTimeouts(router.TimeoutsConfig{Tools: map[string]int{"slow.fetch": 0}}); err == nil {
+		t.Fatalf("expected invalid per-tool timeout to be rejected")
+	}
+}
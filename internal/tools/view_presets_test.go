@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+func TestToolViewPresets(t *testing.T) {
+	h := ciHandler(t)
+	if err := h.SetToolViews(map[string]router.ViewPresets{"ci.list_jobs": {"summary": {"jobs.id"}}}); err != nil {
+		t.Fatalf("SetToolViews: %v", err)
+	}
+	res := runQuery(t, h, map[string]any{
+		"input":  "jobs",
+		"output": map[string]any{"view": "summary"},
+		"steps":  []map[string]any{{"name": "ci.list_jobs", "source": "upstream", "args": map[string]any{}}},
+	})
+	if b, _ := json.Marshal(res.ExecutedSteps[0].Result); string(b) != `{"jobs":[{"id":4711}]}` {
+		t.Fatalf("unexpected summary %s", b)
+	}
+
+	describe := func(name string) string {
+		args, _ := json.Marshal(map[string]any{"name": name})
+		out, err := h.Handle(context.Background(), "describe_tool", args)
+		if err != nil || out.IsError {
+			t.Fatalf("describe_tool %s: %v %+v", name, err, out)
+		}
+		return out.Content[0].Text
+	}
+	if text := describe("ci.list_jobs"); !strings.Contains(text, "- summary: jobs.id") {
+		t.Fatalf("expected configured view, got %s", text)
+	}
+	if text := describe("jira_search_issues"); !strings.Contains(text, "issues.fields.status.name") {
+		t.Fatalf("expected declared views, got %s", text)
+	}
+
+	if err := h.SetToolViews(map[string]router.ViewPresets{"ci.list_jobs": {"errors_only": {"x"}}}); err == nil {
+		t.Fatalf("expected invalid view error")
+	}
+}
+
+func TestLocalReadToolsDeclareViews(t *testing.T) {
+	h := NewHandler(nil, nil)
+	// Meta and runtime tools, writes and tools returning raw text or files have no field views.
+	skip := map[string]bool{
+		"search_tools": true, "describe_tool": true, "execute_tool": true, "dev_scaffold_tool": true, "router": true, "query": true,
+		"list_adapters": true, "execute_code": true, "start_runtime": true, "register_tool": true, "rollback_tool": true, "discover_api": true,
+		"artifact_save_text": true, "artifact_append_text": true, "artifact_list": true, "artifact_search": true,
+		"get_pull_request_diff": true, "get_pull_request_file_diff": true, "get_file_at_ref": true, "github_download_job_logs": true,
+		"prepare_pull_request_review_bundle": true, "fetch_complete_pr_diff": true, "fetch_complete_pr_files": true,
+		"jira_export_tasks": true, "confluence_download_attachment": true, "confluence_xhtml_to_text": true,
+	}
+	for _, tool := range h.BuiltinTools() {
+		if skip[tool.Name] || router.IsConfirmableWrite("local", tool.Name) {
+			continue
+		}
+		views := router.ViewPresets(tool.Views)
+		if len(views) == 0 {
+			t.Errorf("%s: no view presets", tool.Name)
+			continue
+		}
+		if err := views.Validate(); err != nil {
+			t.Errorf("%s: %v", tool.Name, err)
+		}
+	}
+}
+
+func TestPullRequestChecksSummaryView(t *testing.T) {
+	var views router.ViewPresets
+	for _, tool := range NewHandler(nil, nil).BuiltinTools() {
+		if tool.Name == "get_pull_request_checks" {
+			views = tool.Views
+		}
+	}
+	out := map[string]any{
+		"repo": "org/repo", "number": float64(7), "head_sha": "abc",
+		"checks": map[string]any{"total_count": float64(1), "check_runs": []any{
+			map[string]any{"id": float64(1), "name": "build", "status": "completed", "conclusion": "failure", "html_url": "u", "app_name": "ci"},
+		}},
+	}
+	shaped, err := router.ApplyOutputShaping(views, out, &router.OutputOptions{View: "metadata"})
+	if err != nil {
+		t.Fatalf("shape: %v", err)
+	}
+	b, _ := json.Marshal(shaped)
+	if string(b) != `{"checks":{"check_runs":[{"conclusion":"failure","name":"build"}]},"head_sha":"abc","number":7,"repo":"org/repo"}` {
+		t.Fatalf("unexpected metadata view %s", b)
+	}
+}
@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golovatskygroup/mcp-lens/internal/artifacts"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+	"github.com/google/uuid"
+)
+
+// Write mode: the planner may propose the confirmable Jira write tools, but plans containing them
+// are held as pending actions behind a single-use token. A second `query` call with `confirm=<token>`
+// executes the held plan. Every executed write is appended to the audit log (JSONL).
+
+const defaultPendingTTL = 10 * time.Minute
+
+func writeModeEnabled() bool {
+	v := strings.TrimSpace(os.Getenv("MCP_LENS_WRITE_MODE"))
+	return strings.EqualFold(v, "confirm") || v == "1" || strings.EqualFold(v, "true") || strings.EqualFold(v, "yes")
+}
+
+func pendingTTLFromEnv() time.Duration {
+	if v := strings.TrimSpace(os.Getenv("MCP_LENS_WRITE_CONFIRM_TTL_SECONDS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return defaultPendingTTL
+}
+
+func auditLogPathFromEnv() string {
+	if p := strings.TrimSpace(os.Getenv("MCP_LENS_AUDIT_LOG")); p != "" {
+		return os.ExpandEnv(p)
+	}
+	return filepath.Join(artifacts.ConfigFromEnv().Dir, "mcp-lens-audit.jsonl")
+}
+
+type pendingPlan struct {
+	// session is the MCP session that held the plan; only that session may confirm it.
+	session string
+	input   string
+	plan    router.ModelPlan
+	// prior are the executed steps of earlier iterative rounds, which the plan's $refs may point at.
+	prior       []router.ExecutedStep
+	output      *router.OutputOptions
+	parallelism int
+	expires     time.Time
+}
+
+// pendingStore holds plans awaiting confirmation. Tokens are single-use.
+type pendingStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	plans map[string]pendingPlan
+}
+
+func newPendingStore(ttl time.Duration) *pendingStore {
+	if ttl <= 0 {
+		ttl = defaultPendingTTL
+	}
+	return &pendingStore{ttl: ttl, plans: map[string]pendingPlan{}}
+}
+
+func (s *pendingStore) put(p pendingPlan) (string, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for tok, old := range s.plans {
+		if now.After(old.expires) {
+			delete(s.plans, tok)
+		}
+	}
+	token := uuid.New().String()
+	p.expires = now.Add(s.ttl)
+	s.plans[token] = p
+	return token, p.expires
+}
+
+// take removes and returns the plan for token if it exists, was held by session and has not expired.
+// A token presented by another session is left in place.
+func (s *pendingStore) take(token, session string) (pendingPlan, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.plans[token]
+	if !ok || p.session != session {
+		return pendingPlan{}, false
+	}
+	delete(s.plans, token)
+	if time.Now().After(p.expires) {
+		return pendingPlan{}, false
+	}
+	return p, true
+}
+
+// hasConfirmableWrites reports whether a plan must be confirmed before it runs. This holds for every
+// confirmable write, whether write mode or an explicit allow rule (e.g. the triage profile) permits it.
+func hasConfirmableWrites(plan router.ModelPlan) bool {
+	for _, s := range plan.Steps {
+		if router.IsConfirmableWrite(s.Source, s.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+type writesConfirmedKey struct{}
+
+type confirmedWrites struct {
+	token string
+	input string
+}
+
+// withConfirmedWrites marks ctx as executing a plan the caller has confirmed.
+func withConfirmedWrites(ctx context.Context, token string, input string) context.Context {
+	return context.WithValue(ctx, writesConfirmedKey{}, confirmedWrites{token: token, input: input})
+}
+
+func confirmedWritesFromContext(ctx context.Context) (confirmedWrites, bool) {
+	c, ok := ctx.Value(writesConfirmedKey{}).(confirmedWrites)
+	return c, ok
+}
+
+func (h *Handler) holdPendingPlan(ctx context.Context, in routerInput, plan router.ModelPlan, prior []router.ExecutedStep) *router.PendingActions {
+	token, expires := h.pending.put(pendingPlan{
+		session:     sessionFromContext(ctx),
+		input:       in.Input,
+		plan:        plan,
+		prior:       prior,
+		output:      in.Output,
+		parallelism: in.Parallelism,
+	})
+	return &router.PendingActions{
+		Token:     token,
+		ExpiresAt: expires.UTC().Format(time.RFC3339),
+		Actions:   plan.Steps,
+		Message:   fmt.Sprintf("Plan contains write actions and was not executed. Call query again with confirm=%q to execute it.", token),
+	}
+}
+
+// auditEntry is one line of the write audit log.
+type auditEntry struct {
+	Time   string          `json:"time"`
+	Token  string          `json:"token,omitempty"`
+	Input  string          `json:"input,omitempty"`
+	Tool   string          `json:"tool"`
+	Source string          `json:"source"`
+	Args   json.RawMessage `json:"args"`
+	OK     bool            `json:"ok"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// auditLog appends write executions to a JSONL file.
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (a *auditLog) record(e auditEntry) error {
+	if a == nil || strings.TrimSpace(a.path) == "" {
+		return nil
+	}
+	if e.Time == "" {
+		e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+func (h *Handler) auditWrite(ctx context.Context, st router.ExecutedStep) {
+	c, _ := confirmedWritesFromContext(ctx)
+	if err := h.audit.record(auditEntry{
+		Token:  c.token,
+		Input:  c.input,
+		Tool:   st.Name,
+		Source: st.Source,
+		Args:   st.Args,
+		OK:     st.OK,
+		Error:  st.Error,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "[mcp-proxy] audit log write failed: %v\n", err)
+	}
+}
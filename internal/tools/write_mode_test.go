@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golovatskygroup/mcp-lens/internal/registry"
+	"github.com/golovatskygroup/mcp-lens/internal/router"
+)
+
+func TestWriteModeHoldsJiraWritesUntilConfirmed(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Setenv("MCP_LENS_WRITE_MODE", "confirm")
+	t.Setenv("MCP_LENS_AUDIT_LOG", auditPath)
+	t.Setenv("JIRA_PAT", "dummy")
+
+	var comments int32
+	jiraSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/rest/api/2/issue/GO-1/comment" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&comments, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"100"}`))
+	}))
+	t.Cleanup(jiraSrv.Close)
+
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, nil)
+	reg.LoadTools(h.BuiltinTools())
+
+	args, _ := json.Marshal(map[string]any{
+		"input": "comment on GO-1",
+		"mode":  "executor",
+		"steps": []map[string]any{{
+			"name":   "jira_add_comment",
+			"source": "local",
+			"args":   map[string]any{"issue": "GO-1", "body": "done", "base_url": jiraSrv.URL, "api_version": 2},
+		}},
+	})
+	ctx := WithSession(context.Background(), "s1")
+	res, err := h.Handle(ctx, "query", args)
+	if err != nil || res.IsError {
+		t.Fatalf("query: err=%v res=%+v", err, res)
+	}
+	var held router.RouterResult
+	if err := json.Unmarshal([]byte(res.Content[0].Text), &held); err != nil {
+		t.Fatalf("parse result: %v", err)
+	}
+	if held.Pending == nil || held.Pending.Token == "" || len(held.Pending.Actions) != 1 {
+		t.Fatalf("expected pending actions, got %+v", held)
+	}
+	if len(held.ExecutedSteps) != 0 || atomic.LoadInt32(&comments) != 0 {
+		t.Fatalf("expected nothing executed before confirmation")
+	}
+
+	confirm, _ := json.Marshal(map[string]any{"input": "confirm", "confirm": held.Pending.Token})
+	// Only the session that held the plan can confirm it.
+	res, _ = h.Handle(WithSession(context.Background(), "s2"), "query", confirm)
+	if !res.IsError || atomic.LoadInt32(&comments) != 0 {
+		t.Fatalf("expected confirmation from another session to be rejected, got %+v", res)
+	}
+	res, err = h.Handle(ctx, "query", confirm)
+	if err != nil || res.IsError {
+		t.Fatalf("confirm: err=%v res=%+v", err, res)
+	}
+	var done router.RouterResult
+	if err := json.Unmarshal([]byte(res.Content[0].Text), &done); err != nil {
+		t.Fatalf("parse result: %v", err)
+	}
+	if len(done.ExecutedSteps) != 1 || !done.ExecutedSteps[0].OK {
+		t.Fatalf("expected executed write, got %+v", done.ExecutedSteps)
+	}
+	if got := atomic.LoadInt32(&comments); got != 1 {
+		t.Fatalf("expected 1 comment request, got %d", got)
+	}
+
+	// Tokens are single-use.
+	res, _ = h.Handle(ctx, "query", confirm)
+	if !res.IsError {
+		t.Fatalf("expected reused token to be rejected")
+	}
+
+	f, err := os.Open(auditPath)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	defer f.Close()
+	var entries []auditEntry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e auditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("parse audit line: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 1 || entries[0].Tool != "jira_add_comment" || !entries[0].OK || entries[0].Token != held.Pending.Token || entries[0].Input != "comment on GO-1" {
+		t.Fatalf("unexpected audit entries: %+v", entries)
+	}
+}
+
+func TestWriteModeOffBlocksJiraWrites(t *testing.T) {
+	t.Setenv("MCP_LENS_WRITE_MODE", "")
+
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, nil)
+	reg.LoadTools(h.BuiltinTools())
+
+	args, _ := json.Marshal(map[string]any{
+		"input": "comment on GO-1",
+		"steps": []map[string]any{{"name": "jira_add_comment", "source": "local", "args": map[string]any{"issue": "GO-1", "body": "x"}}},
+	})
+	res, err := h.Handle(context.Background(), "query", args)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if !res.IsError {
+		t.Fatalf("expected policy error without write mode")
+	}
+}
+
+func TestExecutePlanRejectsUnconfirmedWrites(t *testing.T) {
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, nil)
+
+	policy := router.DefaultPolicy()
+	policy.WriteMode = true
+	plan := router.ModelPlan{Steps: []router.PlanStep{{Name: "jira_add_comment", Source: "local", Args: json.RawMessage(`{"issue":"GO-1","body":"x"}`)}}}
+	steps, _, err := h.executePlan(context.Background(), plan, policy, nil, 1)
+	if err == nil || len(steps) != 1 || steps[0].OK || steps[0].Error != "write requires confirmation" {
+		t.Fatalf("expected unconfirmed write to be rejected, got err=%v steps=%+v", err, steps)
+	}
+}
+
+func TestPolicyAllowedWritesNeedConfirmationWithoutWriteMode(t *testing.T) {
+	t.Setenv("MCP_LENS_WRITE_MODE", "")
+
+	reg := registry.NewRegistry()
+	h := NewHandler(reg, nil)
+	reg.LoadTools(h.BuiltinTools())
+	policy, err := router.NewPolicy(router.PolicyConfig{Profile: "triage"})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	h.SetPolicy(policy)
+
+	// The triage profile allows Jira comments, but they are still held for confirmation.
+	args, _ := json.Marshal(map[string]any{
+		"input": "comment on GO-1",
+		"steps": []map[string]any{{"name": "jira_add_comment", "source": "local", "args": map[string]any{"issue": "GO-1", "body": "x"}}},
+	})
+	ctx := WithSession(context.Background(), "s1")
+	res, err := h.Handle(ctx, "query", args)
+	if err != nil || res.IsError {
+		t.Fatalf("query: err=%v res=%+v", err, res)
+	}
+	var held router.RouterResult
+	if err := json.Unmarshal([]byte(res.Content[0].Text), &held); err != nil {
+		t.Fatalf("parse result: %v", err)
+	}
+	if held.Pending == nil || len(held.ExecutedSteps) != 0 {
+		t.Fatalf("expected pending actions and nothing executed, got %+v", held)
+	}
+
+	plan := router.ModelPlan{Steps: []router.PlanStep{{Name: "jira_add_comment", Source: "local", Args: json.RawMessage(`{"issue":"GO-1","body":"x"}`)}}}
+	steps, _, err := h.executePlan(context.Background(), plan, policy, nil, 1)
+	if err == nil || steps[0].Error != "write requires confirmation" {
+		t.Fatalf("expected unconfirmed write to be rejected, got err=%v steps=%+v", err, steps)
+	}
+
+	if got := policy.AllowedWrites(); len(got) != 2 || got[0] != "jira_add_comment" || got[1] != "jira_transition_issue" {
+		t.Fatalf("unexpected allowed writes %v", got)
+	}
+}
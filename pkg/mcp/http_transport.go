@@ -0,0 +1,682 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionHeader is the Streamable HTTP session header.
+const SessionHeader = "Mcp-Session-Id"
+
+// DefaultHTTPPath is the endpoint served by HTTPTransport.
+const DefaultHTTPPath = "/mcp"
+
+// DefaultSessionIdleTimeout is how long a session without requests or open streams is kept.
+const DefaultSessionIdleTimeout = 30 * time.Minute
+
+// HTTPTransport serves MCP over the Streamable HTTP transport:
+// clients POST JSON-RPC messages and receive responses either as JSON or as an SSE stream,
+// and may open a GET SSE stream for server-initiated notifications.
+//
+// Several clients can share one transport. Request IDs are rewritten to transport-unique IDs
+// on the way in and restored on the way out, so the server dispatch loop stays unaware of sessions.
+type HTTPTransport struct {
+	addr string
+	path string
+
+	incoming chan *Request
+	closed   chan struct{}
+	once     sync.Once
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+	inflight map[string]*httpCall
+	nextID   atomic.Int64
+	pending  pendingRequests
+	// onClosed is called with the ID of each session that ends (see OnSessionClosed); guarded by mu.
+	onClosed func(sessionID string)
+	// idleTimeout ends sessions idle for longer (see SetSessionIdleTimeout); guarded by mu.
+	idleTimeout time.Duration
+	reaper      sync.Once
+
+	// allowedOrigins are browser Origins accepted besides localhost (see SetAllowedOrigins).
+	allowedOrigins map[string]bool
+
+	srv *http.Server
+}
+
+type httpSession struct {
+	id      string
+	streams map[chan []byte]struct{}
+	// lastSeen is the end of the session's latest exchange; guarded by HTTPTransport.mu.
+	lastSeen time.Time
+	// active counts the session's open POST and GET exchanges; guarded by HTTPTransport.mu.
+	active int
+}
+
+type httpCall struct {
+	session string
+	origID  any
+	out     chan []byte
+	// notes carries notifications and server requests related to this request (SSE responses only).
+	notes chan []byte
+	sse   bool
+	// cancelled is set when the client cancels the request; its response is dropped. Guarded by mu.
+	cancelled bool
+}
+
+// wireMessage is any JSON-RPC message (request, notification or response) as received over HTTP.
+type wireMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// NewHTTPTransport creates a Streamable HTTP transport listening on addr (e.g. ":8080").
+func NewHTTPTransport(addr string) *HTTPTransport {
+	t := &HTTPTransport{
+		addr:     addr,
+		path:     DefaultHTTPPath,
+		incoming: make(chan *Request, 64),
+		closed:   make(chan struct{}),
+		sessions: make(map[string]*httpSession),
+		inflight: make(map[string]*httpCall),
+
+		idleTimeout: DefaultSessionIdleTimeout,
+	}
+	mux := http.NewServeMux()
+	mux.Handle(t.path, t)
+	t.srv = &http.Server{Addr: addr, Handler: mux}
+	return t
+}
+
+// ListenAndServe starts the HTTP listener and blocks until Close is called.
+func (t *HTTPTransport) ListenAndServe() error {
+	ln, err := net.Listen("tcp", t.addr)
+	if err != nil {
+		return err
+	}
+	return t.Serve(ln)
+}
+
+// Serve accepts connections on ln and blocks until Close is called.
+func (t *HTTPTransport) Serve(ln net.Listener) error {
+	err := t.srv.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Close stops the listener and makes ReadMessage return io.EOF.
+func (t *HTTPTransport) Close() error {
+	var err error
+	t.once.Do(func() {
+		close(t.closed)
+		err = t.srv.Shutdown(context.Background())
+	})
+	return err
+}
+
+// ReadMessage returns the next client request or notification from any session.
+func (t *HTTPTransport) ReadMessage() (*Request, error) {
+	select {
+	case req := <-t.incoming:
+		return req, nil
+	case <-t.closed:
+		return nil, io.EOF
+	}
+}
+
+// WriteResponse routes a response back to the HTTP exchange that carried the request.
+func (t *HTTPTransport) WriteResponse(resp *Response) error {
+	key := fmt.Sprint(resp.ID)
+
+	t.mu.Lock()
+	call, ok := t.inflight[key]
+	if ok {
+		delete(t.inflight, key)
+	}
+	t.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no pending HTTP request for id %v", resp.ID)
+	}
+	if call.cancelled {
+		// The client cancelled the request and no longer expects a response.
+		return nil
+	}
+
+	out := *resp
+	out.ID = call.origID
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	call.out <- data
+	return nil
+}
+
+// WriteRequestNotification delivers a notification on the SSE response stream of an in-flight request.
+// It is a no-op if the client asked for a plain JSON response or already went away.
+func (t *HTTPTransport) WriteRequestNotification(requestID any, method string, params any) error {
+	data, err := marshalNotification(method, params)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	call, ok := t.inflight[fmt.Sprint(requestID)]
+	t.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case call.notes <- data:
+	default:
+		// Slow listener: drop rather than block the server.
+	}
+	return nil
+}
+
+// SendRequest sends a server-initiated request on the SSE response stream of relatedRequestID,
+// or on a GET stream of its session if the client asked for a plain JSON response.
+// The client answers by POSTing the response; it is routed back here.
+func (t *HTTPTransport) SendRequest(ctx context.Context, relatedRequestID any, method string, params any) (*Response, error) {
+	t.mu.Lock()
+	call, ok := t.inflight[fmt.Sprint(relatedRequestID)]
+	t.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no pending HTTP request for id %v", relatedRequestID)
+	}
+
+	return t.pending.roundTrip(ctx, call.session, method, params, func(data []byte) error {
+		if call.sse {
+			select {
+			case call.notes <- data:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-t.closed:
+				return io.EOF
+			}
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if sess, ok := t.sessions[call.session]; ok {
+			for ch := range sess.streams {
+				select {
+				case ch <- data:
+					return nil
+				default:
+				}
+			}
+		}
+		return errors.New("client has no open SSE stream for server requests")
+	})
+}
+
+// RequestSession returns the session that sent an in-flight request.
+func (t *HTTPTransport) RequestSession(requestID any) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if call, ok := t.inflight[fmt.Sprint(requestID)]; ok {
+		return call.session
+	}
+	return ""
+}
+
+// OnSessionClosed registers fn to be called when a session ends: the client sends DELETE or the
+// session stays idle for longer than the idle timeout.
+func (t *HTTPTransport) OnSessionClosed(fn func(sessionID string)) {
+	t.mu.Lock()
+	t.onClosed = fn
+	t.mu.Unlock()
+}
+
+// SetSessionIdleTimeout sets how long a session without requests or open streams is kept
+// (default DefaultSessionIdleTimeout); d <= 0 keeps sessions until DELETE. Must be called before serving.
+func (t *HTTPTransport) SetSessionIdleTimeout(d time.Duration) {
+	t.mu.Lock()
+	t.idleTimeout = d
+	t.mu.Unlock()
+}
+
+// WriteNotification broadcasts a notification to every open GET stream.
+func (t *HTTPTransport) WriteNotification(method string, params any) error {
+	data, err := marshalNotification(method, params)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, sess := range t.sessions {
+		for ch := range sess.streams {
+			select {
+			case ch <- data:
+			default:
+				// Slow listener: drop rather than block the server.
+			}
+		}
+	}
+	return nil
+}
+
+// SetAllowedOrigins sets the browser Origins (e.g. "https://app.example.com") accepted besides
+// localhost; "*" accepts any. Must be called before serving.
+func (t *HTTPTransport) SetAllowedOrigins(origins []string) {
+	t.allowedOrigins = make(map[string]bool, len(origins))
+	for _, o := range origins {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			t.allowedOrigins[strings.ToLower(o)] = true
+		}
+	}
+}
+
+// originAllowed guards against DNS rebinding: requests with an Origin header (i.e. from browsers)
+// must come from localhost or an allowlisted origin. Requests without one are not from a web page.
+func (t *HTTPTransport) originAllowed(origin string) bool {
+	origin = strings.TrimSpace(origin)
+	if origin == "" {
+		return true
+	}
+	if t.allowedOrigins["*"] || t.allowedOrigins[strings.ToLower(strings.TrimRight(origin, "/"))] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Hostname()) {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+// ServeHTTP implements the single Streamable HTTP endpoint.
+func (t *HTTPTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !t.originAllowed(r.Header.Get("Origin")) {
+		writeHTTPError(w, http.StatusForbidden, InvalidRequest, "origin not allowed")
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleGet(w, r)
+	case http.MethodDelete:
+		t.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *HTTPTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	msgs, batch, err := parseWireMessages(body)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, ParseError, "failed to parse message: "+err.Error())
+		return
+	}
+
+	sessionID := strings.TrimSpace(r.Header.Get(SessionHeader))
+	isInit := false
+	for _, m := range msgs {
+		if m.Method == "initialize" {
+			isInit = true
+			break
+		}
+	}
+	if isInit {
+		sessionID = t.newSession()
+	}
+	if !t.enterSession(sessionID) {
+		if sessionID == "" {
+			writeHTTPError(w, http.StatusBadRequest, InvalidRequest, "missing "+SessionHeader+" header")
+		} else {
+			writeHTTPError(w, http.StatusNotFound, InvalidRequest, "unknown session")
+		}
+		return
+	}
+	defer t.leaveSession(sessionID)
+	w.Header().Set(SessionHeader, sessionID)
+
+	out := make(chan []byte, len(msgs))
+	notes := make(chan []byte, 32)
+	sse := acceptsEventStream(r)
+	var keys []string
+	for _, m := range msgs {
+		if resp, ok := responseFromWire(m); ok {
+			// Answer to a server-initiated request (e.g. sampling/createMessage); only the session
+			// the request was sent to may answer it.
+			t.pending.resolve(sessionID, resp)
+			continue
+		}
+		if m.Method == "" {
+			continue
+		}
+		req := &Request{JSONRPC: m.JSONRPC, Method: m.Method, Params: m.Params}
+		if m.Method == "notifications/cancelled" {
+			req.Params = t.rewriteCancelled(sessionID, m.Params)
+		}
+		if len(m.ID) > 0 && !bytes.Equal(m.ID, []byte("null")) {
+			var origID any
+			_ = json.Unmarshal(m.ID, &origID)
+			key := fmt.Sprintf("http-%d", t.nextID.Add(1))
+			t.mu.Lock()
+			t.inflight[key] = &httpCall{session: sessionID, origID: origID, out: out, notes: notes, sse: sse}
+			t.mu.Unlock()
+			req.ID = key
+			keys = append(keys, key)
+		}
+		select {
+		case t.incoming <- req:
+		case <-t.closed:
+			http.Error(w, "server closed", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	if len(keys) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	defer t.forget(keys)
+
+	if sse {
+		flusher, _ := w.(http.Flusher)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		for remaining := len(keys); remaining > 0; remaining-- {
+			select {
+			case data := <-out:
+				if data == nil {
+					// Cancelled by the client: no response.
+					continue
+				}
+				writeSSE(w, data)
+			case data := <-notes:
+				writeSSE(w, data)
+				remaining++
+			case <-r.Context().Done():
+				return
+			case <-t.closed:
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return
+	}
+
+	responses := make([]json.RawMessage, 0, len(keys))
+	for remaining := len(keys); remaining > 0; remaining-- {
+		select {
+		case data := <-out:
+			if data != nil {
+				responses = append(responses, data)
+			}
+		case <-r.Context().Done():
+			return
+		case <-t.closed:
+			http.Error(w, "server closed", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	if len(responses) == 0 {
+		// Every request was cancelled by the client.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if batch {
+		_ = json.NewEncoder(w).Encode(responses)
+		return
+	}
+	_, _ = w.Write(responses[0])
+}
+
+func (t *HTTPTransport) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		http.Error(w, "GET requires Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	sessionID := strings.TrimSpace(r.Header.Get(SessionHeader))
+
+	ch := make(chan []byte, 16)
+	if !t.enterSession(sessionID) {
+		writeHTTPError(w, http.StatusNotFound, InvalidRequest, "unknown session")
+		return
+	}
+	defer t.leaveSession(sessionID)
+	t.mu.Lock()
+	sess, ok := t.sessions[sessionID]
+	if ok {
+		sess.streams[ch] = struct{}{}
+	}
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(sess.streams, ch)
+		t.mu.Unlock()
+	}()
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set(SessionHeader, sessionID)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case data := <-ch:
+			writeSSE(w, data)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		case <-t.closed:
+			return
+		}
+	}
+}
+
+func (t *HTTPTransport) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sessionID := strings.TrimSpace(r.Header.Get(SessionHeader))
+	t.mu.Lock()
+	_, ok := t.sessions[sessionID]
+	delete(t.sessions, sessionID)
+	onClosed := t.onClosed
+	t.mu.Unlock()
+	if !ok {
+		writeHTTPError(w, http.StatusNotFound, InvalidRequest, "unknown session")
+		return
+	}
+	if onClosed != nil {
+		onClosed(sessionID)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (t *HTTPTransport) newSession() string {
+	id := uuid.NewString()
+	t.mu.Lock()
+	t.sessions[id] = &httpSession{id: id, streams: make(map[chan []byte]struct{}), lastSeen: time.Now()}
+	idle := t.idleTimeout
+	t.mu.Unlock()
+	if idle > 0 {
+		t.reaper.Do(func() { go t.reapIdleSessions(idle) })
+	}
+	return id
+}
+
+// enterSession marks an exchange of session id as open; it reports false for unknown sessions.
+func (t *HTTPTransport) enterSession(id string) bool {
+	if id == "" {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sess, ok := t.sessions[id]
+	if ok {
+		sess.active++
+	}
+	return ok
+}
+
+// leaveSession marks an exchange of session id as done; the idle timeout starts when none is left.
+func (t *HTTPTransport) leaveSession(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if sess, ok := t.sessions[id]; ok {
+		sess.active--
+		sess.lastSeen = time.Now()
+	}
+}
+
+// reapIdleSessions ends sessions without open exchanges for longer than idle, until the transport closes.
+func (t *HTTPTransport) reapIdleSessions(idle time.Duration) {
+	tick := time.NewTicker(max(idle/4, 10*time.Millisecond))
+	defer tick.Stop()
+	for {
+		select {
+		case now := <-tick.C:
+			var expired []string
+			t.mu.Lock()
+			for id, sess := range t.sessions {
+				if sess.active == 0 && now.Sub(sess.lastSeen) > idle {
+					delete(t.sessions, id)
+					expired = append(expired, id)
+				}
+			}
+			onClosed := t.onClosed
+			t.mu.Unlock()
+			if onClosed != nil {
+				for _, id := range expired {
+					onClosed(id)
+				}
+			}
+		case <-t.closed:
+			return
+		}
+	}
+}
+
+// forget drops inflight entries whose HTTP exchange ended before a response was written,
+// and tells the server to cancel them (the client is no longer listening).
+func (t *HTTPTransport) forget(keys []string) {
+	var abandoned []string
+	t.mu.Lock()
+	for _, k := range keys {
+		if call, ok := t.inflight[k]; ok {
+			delete(t.inflight, k)
+			if !call.cancelled {
+				abandoned = append(abandoned, k)
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	for _, k := range abandoned {
+		params, _ := json.Marshal(CancelledParams{RequestID: k, Reason: "client disconnected"})
+		select {
+		case t.incoming <- &Request{JSONRPC: "2.0", Method: "notifications/cancelled", Params: params}:
+		case <-t.closed:
+			return
+		}
+	}
+}
+
+// rewriteCancelled maps the client's requestId in a notifications/cancelled message
+// to the transport-unique ID the server saw for that request. The request's HTTP exchange stops
+// waiting for it: a request cancelled before the server picks it up never gets a response.
+func (t *HTTPTransport) rewriteCancelled(sessionID string, params json.RawMessage) json.RawMessage {
+	var p CancelledParams
+	if err := json.Unmarshal(params, &p); err != nil || p.RequestID == nil {
+		return params
+	}
+	want := fmt.Sprint(p.RequestID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, call := range t.inflight {
+		if call.session == sessionID && fmt.Sprint(call.origID) == want {
+			if !call.cancelled {
+				call.cancelled = true
+				// out has room for one message per request of its exchange.
+				call.out <- nil
+			}
+			p.RequestID = key
+			if b, err := json.Marshal(p); err == nil {
+				return b
+			}
+			break
+		}
+	}
+	return params
+}
+
+func parseWireMessages(body []byte) ([]wireMessage, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, false, errors.New("empty body")
+	}
+	if body[0] == '[' {
+		var msgs []wireMessage
+		if err := json.Unmarshal(body, &msgs); err != nil {
+			return nil, true, err
+		}
+		if len(msgs) == 0 {
+			return nil, true, errors.New("empty batch")
+		}
+		return msgs, true, nil
+	}
+	var m wireMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, false, err
+	}
+	return []wireMessage{m}, false, nil
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func writeSSE(w io.Writer, data []byte) {
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+}
+
+func writeHTTPError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(NewErrorResponse(nil, code, message))
+}
@@ -0,0 +1,402 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoLoop answers every request with {"method": <method>} until the transport closes.
+func echoLoop(tr *HTTPTransport) {
+	for {
+		req, err := tr.ReadMessage()
+		if err != nil {
+			return
+		}
+		if req.ID == nil {
+			continue
+		}
+		resp, _ := NewResponse(req.ID, map[string]any{"method": req.Method})
+		_ = tr.WriteResponse(resp)
+	}
+}
+
+func postJSON(t *testing.T, url string, session string, accept string, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if session != "" {
+		req.Header.Set(SessionHeader, session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	return resp
+}
+
+func TestHTTPTransportSessionsAndIDs(t *testing.T) {
+	tr := NewHTTPTransport("127.0.0.1:0")
+	srv := httptest.NewServer(tr)
+	t.Cleanup(func() {
+		tr.Close()
+		srv.Close()
+	})
+	go echoLoop(tr)
+
+	resp := postJSON(t, srv.URL, "", "application/json", `{"jsonrpc":"2.0","id":7,"method":"initialize","params":{}}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize status: %d", resp.StatusCode)
+	}
+	session := resp.Header.Get(SessionHeader)
+	if session == "" {
+		t.Fatalf("expected %s header", SessionHeader)
+	}
+	var got Response
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.ID != float64(7) {
+		t.Fatalf("expected original id 7 restored, got %v", got.ID)
+	}
+
+	// Requests without a known session are rejected.
+	bad := postJSON(t, srv.URL, "nope", "application/json", `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown session, got %d", bad.StatusCode)
+	}
+
+	// Notifications are accepted without a body.
+	notif := postJSON(t, srv.URL, session, "", `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	notif.Body.Close()
+	if notif.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 for notification, got %d", notif.StatusCode)
+	}
+
+	// SSE response stream.
+	sse := postJSON(t, srv.URL, session, "application/json, text/event-stream", `{"jsonrpc":"2.0","id":"abc","method":"ping"}`)
+	defer sse.Body.Close()
+	if ct := sse.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("expected event stream, got %q", ct)
+	}
+	sc := bufio.NewScanner(sse.Body)
+	var data string
+	for sc.Scan() {
+		if line := sc.Text(); strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+			break
+		}
+	}
+	var pong Response
+	if err := json.Unmarshal([]byte(data), &pong); err != nil {
+		t.Fatalf("decode sse data %q: %v", data, err)
+	}
+	if pong.ID != "abc" {
+		t.Fatalf("expected id abc, got %v", pong.ID)
+	}
+}
+
+func TestHTTPTransportCloseReturnsEOF(t *testing.T) {
+	tr := NewHTTPTransport("127.0.0.1:0")
+	tr.Close()
+	if _, err := tr.ReadMessage(); err != io.EOF {
+		t.Fatalf("expected io.EOF after Close, got %v", err)
+	}
+}
+
+func TestHTTPTransportSendRequestOnSSEStream(t *testing.T) {
+	tr := NewHTTPTransport("127.0.0.1:0")
+	srv := httptest.NewServer(tr)
+	t.Cleanup(func() {
+		tr.Close()
+		srv.Close()
+	})
+
+	// Server loop: each tools/call asks the client's LLM before answering.
+	go func() {
+		for {
+			req, err := tr.ReadMessage()
+			if err != nil {
+				return
+			}
+			if req.ID == nil {
+				continue
+			}
+			if req.Method != "tools/call" {
+				resp, _ := NewResponse(req.ID, map[string]any{})
+				_ = tr.WriteResponse(resp)
+				continue
+			}
+			go func(req *Request) {
+				res, err := CreateMessage(context.Background(), tr, req.ID, CreateMessageParams{MaxTokens: 8})
+				text := ""
+				if err != nil {
+					text = err.Error()
+				} else {
+					text = res.Content.Text
+				}
+				resp, _ := NewResponse(req.ID, map[string]any{"sampled": text})
+				_ = tr.WriteResponse(resp)
+			}(req)
+		}
+	}()
+
+	init := postJSON(t, srv.URL, "", "application/json", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	init.Body.Close()
+	session := init.Header.Get(SessionHeader)
+
+	sse := postJSON(t, srv.URL, session, "application/json, text/event-stream", `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{}}`)
+	defer sse.Body.Close()
+	sc := bufio.NewScanner(sse.Body)
+	var msgs []wireMessage
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var m wireMessage
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &m); err != nil {
+			t.Fatalf("decode sse data: %v", err)
+		}
+		msgs = append(msgs, m)
+		if m.Method == "sampling/createMessage" {
+			answer := fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"role":"assistant","content":{"type":"text","text":"from client"},"model":"m"}}`, m.ID)
+			resp := postJSON(t, srv.URL, session, "", answer)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusAccepted {
+				t.Fatalf("expected 202 for client response, got %d", resp.StatusCode)
+			}
+			continue
+		}
+		break
+	}
+	if len(msgs) != 2 || msgs[0].Method != "sampling/createMessage" {
+		t.Fatalf("expected sampling request then response, got %+v", msgs)
+	}
+	if string(msgs[1].ID) != "2" || !strings.Contains(string(msgs[1].Result), "from client") {
+		t.Fatalf("unexpected final response: id=%s result=%s", msgs[1].ID, msgs[1].Result)
+	}
+}
+
+func TestHTTPTransportRejectsForeignOrigins(t *testing.T) {
+	tr := NewHTTPTransport("127.0.0.1:0")
+	tr.SetAllowedOrigins([]string{"https://ide.example.com/"})
+	srv := httptest.NewServer(tr)
+	t.Cleanup(func() {
+		tr.Close()
+		srv.Close()
+	})
+	go echoLoop(tr)
+
+	for origin, want := range map[string]int{
+		"":                         http.StatusOK,
+		"http://localhost:3000":    http.StatusOK,
+		"http://127.0.0.1":         http.StatusOK,
+		"https://ide.example.com":  http.StatusOK,
+		"http://attacker.example":  http.StatusForbidden,
+		"http://localhost.evil.io": http.StatusForbidden,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+		req.Header.Set("Content-Type", "application/json")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("origin %q: expected %d, got %d", origin, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestHTTPTransportMatchesServerRequestResponsesBySession(t *testing.T) {
+	tr := NewHTTPTransport("127.0.0.1:0")
+	srv := httptest.NewServer(tr)
+	t.Cleanup(func() {
+		tr.Close()
+		srv.Close()
+	})
+	closed := make(chan string, 1)
+	tr.OnSessionClosed(func(id string) { closed <- id })
+
+	go func() {
+		for {
+			req, err := tr.ReadMessage()
+			if err != nil {
+				return
+			}
+			if req.ID == nil {
+				continue
+			}
+			if req.Method != "tools/call" {
+				resp, _ := NewResponse(req.ID, map[string]any{})
+				_ = tr.WriteResponse(resp)
+				continue
+			}
+			go func(req *Request) {
+				text := "session " + tr.RequestSession(req.ID) + ": "
+				if res, err := CreateMessage(context.Background(), tr, req.ID, CreateMessageParams{MaxTokens: 8}); err == nil {
+					text += res.Content.Text
+				}
+				resp, _ := NewResponse(req.ID, map[string]any{"sampled": text})
+				_ = tr.WriteResponse(resp)
+			}(req)
+		}
+	}()
+
+	initSession := func() string {
+		resp := postJSON(t, srv.URL, "", "application/json", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+		resp.Body.Close()
+		return resp.Header.Get(SessionHeader)
+	}
+	victim, attacker := initSession(), initSession()
+
+	sse := postJSON(t, srv.URL, victim, "application/json, text/event-stream", `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{}}`)
+	defer sse.Body.Close()
+	sc := bufio.NewScanner(sse.Body)
+	var final wireMessage
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var m wireMessage
+		_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &m)
+		if m.Method != "sampling/createMessage" {
+			final = m
+			break
+		}
+		// Another session answers first: it must not resolve the victim's request.
+		for _, answer := range []struct{ session, text string }{{attacker, "spoofed"}, {victim, "genuine"}} {
+			resp := postJSON(t, srv.URL, answer.session, "", fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"role":"assistant","content":{"type":"text","text":%q},"model":"m"}}`, m.ID, answer.text))
+			resp.Body.Close()
+		}
+	}
+	if want := "session " + victim + ": genuine"; !strings.Contains(string(final.Result), want) {
+		t.Fatalf("expected %q, got %s", want, final.Result)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL, nil)
+	req.Header.Set(SessionHeader, victim)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	resp.Body.Close()
+	if got := <-closed; got != victim {
+		t.Fatalf("expected close callback for %s, got %s", victim, got)
+	}
+}
+
+func TestHTTPTransportEndsIdleSessions(t *testing.T) {
+	tr := NewHTTPTransport("127.0.0.1:0")
+	tr.SetSessionIdleTimeout(50 * time.Millisecond)
+	srv := httptest.NewServer(tr)
+	t.Cleanup(func() {
+		tr.Close()
+		srv.Close()
+	})
+	closed := make(chan string, 1)
+	tr.OnSessionClosed(func(id string) { closed <- id })
+	go echoLoop(tr)
+
+	resp := postJSON(t, srv.URL, "", "application/json", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	resp.Body.Close()
+	session := resp.Header.Get(SessionHeader)
+
+	select {
+	case id := <-closed:
+		if id != session {
+			t.Fatalf("expected session %s to end, got %s", session, id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("idle session was not ended")
+	}
+	resp = postJSON(t, srv.URL, session, "application/json", `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected ended session to be unknown, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPTransportCancelledRequestEndsExchange(t *testing.T) {
+	tr := NewHTTPTransport("127.0.0.1:0")
+	srv := httptest.NewServer(tr)
+	t.Cleanup(func() {
+		tr.Close()
+		srv.Close()
+	})
+	queued := make(chan *Request, 1)
+	cancelled := make(chan *Request, 1)
+	go func() {
+		for {
+			req, err := tr.ReadMessage()
+			if err != nil {
+				return
+			}
+			switch req.Method {
+			case "tools/call":
+				// Never answered, like a request cancelled while waiting for a worker.
+				queued <- req
+			case "notifications/cancelled":
+				cancelled <- req
+			default:
+				if req.ID != nil {
+					resp, _ := NewResponse(req.ID, map[string]any{})
+					_ = tr.WriteResponse(resp)
+				}
+			}
+		}
+	}()
+
+	resp := postJSON(t, srv.URL, "", "application/json", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	resp.Body.Close()
+	session := resp.Header.Get(SessionHeader)
+
+	done := make(chan *http.Response, 1)
+	go func() {
+		done <- postJSON(t, srv.URL, session, "application/json", `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{}}`)
+	}()
+	call := <-queued
+	resp = postJSON(t, srv.URL, session, "", `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7}}`)
+	resp.Body.Close()
+
+	select {
+	case resp := <-done:
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected the cancelled exchange to end with 202, got %d", resp.StatusCode)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("POST of a cancelled request is still waiting")
+	}
+	note := <-cancelled
+	var p CancelledParams
+	_ = json.Unmarshal(note.Params, &p)
+	if fmt.Sprint(p.RequestID) != fmt.Sprint(call.ID) {
+		t.Fatalf("expected the server to see the cancellation of %v, got %s", call.ID, note.Params)
+	}
+	// A late response to the cancelled request is dropped.
+	late, _ := NewResponse(call.ID, map[string]any{})
+	if err := tr.WriteResponse(late); err == nil {
+		t.Fatalf("expected no pending exchange for the cancelled request")
+	}
+}
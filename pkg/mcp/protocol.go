@@ -32,6 +32,28 @@ type Notification struct {
 
 // MCP Protocol types
 
+// LatestProtocolVersion is the newest MCP revision this package implements.
+const LatestProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions are the MCP revisions a server accepts from clients, newest first.
+var supportedProtocolVersions = []string{LatestProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// NegotiateProtocolVersion returns the version a server answers initialize with: the client's
+// version when it is supported, the latest one otherwise.
+func NegotiateProtocolVersion(requested string) string {
+	for _, v := range supportedProtocolVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return LatestProtocolVersion
+}
+
+// SupportsStructuredContent reports whether tool results may carry structuredContent in a negotiated version.
+func SupportsStructuredContent(version string) bool {
+	return version >= "2025-06-18"
+}
+
 type ServerInfo struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
@@ -85,6 +107,9 @@ type Tool struct {
 	Name        string          `json:"name"`
 	Description string          `json:"description,omitempty"`
 	InputSchema json.RawMessage `json:"inputSchema"`
+	// Views declares the fields kept by the proxy's output views (view name -> paths, e.g. "summary").
+	// It is proxy metadata and not part of the protocol.
+	Views map[string][]string `json:"-"`
 }
 
 type ToolSummary struct {
@@ -100,11 +125,14 @@ type ListToolsResult struct {
 type CallToolParams struct {
 	Name      string          `json:"name"`
 	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Meta      *RequestMeta    `json:"_meta,omitempty"`
 }
 
 type CallToolResult struct {
 	Content []ContentBlock `json:"content"`
-	IsError bool           `json:"isError,omitempty"`
+	// StructuredContent is a JSON value returned alongside the text content (MCP 2025-06-18).
+	StructuredContent any  `json:"structuredContent,omitempty"`
+	IsError           bool `json:"isError,omitempty"`
 }
 
 type ContentBlock struct {
@@ -113,6 +141,60 @@ type ContentBlock struct {
 	URI  string `json:"uri,omitempty"`
 }
 
+// Sampling types (sampling/createMessage, sent by the server to the client)
+
+type SamplingMessage struct {
+	Role    string       `json:"role"`
+	Content ContentBlock `json:"content"`
+}
+
+type ModelHint struct {
+	Name string `json:"name,omitempty"`
+}
+
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         float64     `json:"costPriority,omitempty"`
+	SpeedPriority        float64     `json:"speedPriority,omitempty"`
+	IntelligencePriority float64     `json:"intelligencePriority,omitempty"`
+}
+
+type CreateMessageParams struct {
+	Messages         []SamplingMessage `json:"messages"`
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+	SystemPrompt     string            `json:"systemPrompt,omitempty"`
+	IncludeContext   string            `json:"includeContext,omitempty"`
+	Temperature      *float64          `json:"temperature,omitempty"`
+	MaxTokens        int               `json:"maxTokens"`
+	StopSequences    []string          `json:"stopSequences,omitempty"`
+}
+
+type CreateMessageResult struct {
+	Role       string       `json:"role"`
+	Content    ContentBlock `json:"content"`
+	Model      string       `json:"model"`
+	StopReason string       `json:"stopReason,omitempty"`
+}
+
+// CancelledParams are the params of a notifications/cancelled message.
+type CancelledParams struct {
+	RequestID any    `json:"requestId"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ProgressParams are the params of a notifications/progress message.
+type ProgressParams struct {
+	ProgressToken any     `json:"progressToken"`
+	Progress      float64 `json:"progress"`
+	Total         float64 `json:"total,omitempty"`
+	Message       string  `json:"message,omitempty"`
+}
+
+// RequestMeta is the optional _meta object of request params.
+type RequestMeta struct {
+	ProgressToken any `json:"progressToken,omitempty"`
+}
+
 // Helper functions
 
 func NewResponse(id any, result any) (*Response, error) {
@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// RequestSender is implemented by transports that can send server-initiated requests
+// (e.g. sampling/createMessage) and route the client's response back to the caller.
+type RequestSender interface {
+	// SendRequest sends a request to the client that issued relatedRequestID and waits for its response.
+	// When ctx ends first, the client is sent notifications/cancelled for the request.
+	SendRequest(ctx context.Context, relatedRequestID any, method string, params any) (*Response, error)
+}
+
+// CreateMessage asks the client's LLM for a completion (sampling/createMessage).
+func CreateMessage(ctx context.Context, s RequestSender, relatedRequestID any, params CreateMessageParams) (*CreateMessageResult, error) {
+	resp, err := s.SendRequest(ctx, relatedRequestID, "sampling/createMessage", params)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("sampling/createMessage failed (%d): %s", resp.Error.Code, resp.Error.Message)
+	}
+	var res CreateMessageResult
+	if err := json.Unmarshal(resp.Result, &res); err != nil {
+		return nil, fmt.Errorf("sampling/createMessage: invalid result: %w", err)
+	}
+	return &res, nil
+}
+
+// SessionTransport is implemented by transports that serve several client sessions (Streamable HTTP).
+// Transports without it serve a single client, whose session is "".
+type SessionTransport interface {
+	// RequestSession returns the session that sent an in-flight request ("" if unknown).
+	RequestSession(requestID any) string
+	// OnSessionClosed registers fn to be called when a client ends its session.
+	OnSessionClosed(fn func(sessionID string))
+}
+
+// pendingRequests tracks server-initiated requests awaiting a client response.
+// IDs are strings with a fixed prefix so they never collide with IDs chosen by the client.
+// Requests are keyed by session as well, so only the client they were sent to can answer them.
+type pendingRequests struct {
+	mu    sync.Mutex
+	next  int64
+	calls map[pendingKey]chan *Response
+}
+
+type pendingKey struct {
+	session string
+	id      string
+}
+
+// roundTrip registers a new request to session, hands its encoding to write and waits for resolve or ctx.
+func (p *pendingRequests) roundTrip(ctx context.Context, session string, method string, params any, write func([]byte) error) (*Response, error) {
+	var paramsData json.RawMessage
+	if params != nil {
+		var err error
+		paramsData, err = json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ch := make(chan *Response, 1)
+	p.mu.Lock()
+	if p.calls == nil {
+		p.calls = make(map[pendingKey]chan *Response)
+	}
+	p.next++
+	id := fmt.Sprintf("mcp-lens-%d", p.next)
+	key := pendingKey{session: session, id: id}
+	p.calls[key] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.calls, key)
+		p.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(Request{JSONRPC: "2.0", ID: id, Method: method, Params: paramsData})
+	if err != nil {
+		return nil, err
+	}
+	if err := write(data); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		if note, err := marshalNotification("notifications/cancelled", CancelledParams{RequestID: id, Reason: ctx.Err().Error()}); err == nil {
+			_ = write(note)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// resolve delivers a response from session to the waiting request. It reports false for unknown IDs
+// and for responses from any session other than the one the request was sent to.
+func (p *pendingRequests) resolve(session string, resp *Response) bool {
+	key := pendingKey{session: session, id: fmt.Sprint(resp.ID)}
+	p.mu.Lock()
+	ch, ok := p.calls[key]
+	delete(p.calls, key)
+	p.mu.Unlock()
+	if ok {
+		ch <- resp
+	}
+	return ok
+}
+
+// responseFromWire returns the message as a Response if it is one (no method, non-null ID).
+func responseFromWire(m wireMessage) (*Response, bool) {
+	if m.Method != "" || len(m.ID) == 0 || string(m.ID) == "null" {
+		return nil, false
+	}
+	var id any
+	if err := json.Unmarshal(m.ID, &id); err != nil {
+		return nil, false
+	}
+	return &Response{JSONRPC: m.JSONRPC, ID: id, Result: m.Result, Error: m.Error}, true
+}
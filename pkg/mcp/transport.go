@@ -2,44 +2,85 @@ package mcp
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"sync"
 )
 
-// Transport handles MCP communication over stdio
-type Transport struct {
+// Transport carries JSON-RPC messages between the server and MCP client(s).
+// Implementations must be safe for concurrent writes.
+type Transport interface {
+	// ReadMessage blocks until the next client request or notification is available.
+	// It returns io.EOF when the transport is closed.
+	ReadMessage() (*Request, error)
+	// WriteResponse delivers a response to the client that sent the matching request.
+	WriteResponse(resp *Response) error
+	// WriteNotification sends a server-initiated notification.
+	WriteNotification(method string, params any) error
+}
+
+// RequestNotifier is implemented by transports that can deliver a notification
+// alongside a specific in-flight request (e.g. on its Streamable HTTP SSE stream).
+// Transports without it send request-related notifications via WriteNotification.
+type RequestNotifier interface {
+	WriteRequestNotification(requestID any, method string, params any) error
+}
+
+// StdioTransport handles MCP communication over stdio
+type StdioTransport struct {
 	reader *bufio.Reader
 	writer io.Writer
 	mu     sync.Mutex
+
+	pending pendingRequests
 }
 
-// NewTransport creates a new stdio transport
-func NewTransport(r io.Reader, w io.Writer) *Transport {
-	return &Transport{
+// NewStdioTransport creates a new stdio transport
+func NewStdioTransport(r io.Reader, w io.Writer) *StdioTransport {
+	return &StdioTransport{
 		reader: bufio.NewReader(r),
 		writer: w,
 	}
 }
 
-// ReadMessage reads a JSON-RPC message from stdin
-func (t *Transport) ReadMessage() (*Request, error) {
-	line, err := t.reader.ReadBytes('\n')
-	if err != nil {
-		return nil, err
-	}
+// ReadMessage reads a JSON-RPC message from stdin.
+// Responses to server-initiated requests are routed to SendRequest and not returned.
+func (t *StdioTransport) ReadMessage() (*Request, error) {
+	for {
+		line, err := t.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
 
-	var req Request
-	if err := json.Unmarshal(line, &req); err != nil {
-		return nil, fmt.Errorf("failed to parse message: %w", err)
+		var m wireMessage
+		if err := json.Unmarshal(line, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse message: %w", err)
+		}
+		if resp, ok := responseFromWire(m); ok {
+			t.pending.resolve("", resp)
+			continue
+		}
+
+		req := &Request{JSONRPC: m.JSONRPC, Method: m.Method, Params: m.Params}
+		if len(m.ID) > 0 {
+			if err := json.Unmarshal(m.ID, &req.ID); err != nil {
+				return nil, fmt.Errorf("failed to parse message: %w", err)
+			}
+		}
+		return req, nil
 	}
+}
 
-	return &req, nil
+// SendRequest writes a server-initiated request to stdout and waits for the client's response.
+// Stdio serves a single client, so relatedRequestID is not needed for routing.
+func (t *StdioTransport) SendRequest(ctx context.Context, relatedRequestID any, method string, params any) (*Response, error) {
+	return t.pending.roundTrip(ctx, "", method, params, t.writeLine)
 }
 
 // WriteResponse writes a JSON-RPC response to stdout
-func (t *Transport) WriteResponse(resp *Response) error {
+func (t *StdioTransport) WriteResponse(resp *Response) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -53,16 +94,34 @@ func (t *Transport) WriteResponse(resp *Response) error {
 }
 
 // WriteNotification writes a JSON-RPC notification
-func (t *Transport) WriteNotification(method string, params any) error {
+func (t *StdioTransport) WriteNotification(method string, params any) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	data, err := marshalNotification(method, params)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(t.writer, "%s\n", data)
+	return err
+}
+
+func (t *StdioTransport) writeLine(data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, err := fmt.Fprintf(t.writer, "%s\n", data)
+	return err
+}
+
+func marshalNotification(method string, params any) ([]byte, error) {
 	var paramsData json.RawMessage
 	if params != nil {
 		var err error
 		paramsData, err = json.Marshal(params)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -71,12 +130,5 @@ func (t *Transport) WriteNotification(method string, params any) error {
 		Method:  method,
 		Params:  paramsData,
 	}
-
-	data, err := json.Marshal(notif)
-	if err != nil {
-		return err
-	}
-
-	_, err = fmt.Fprintf(t.writer, "%s\n", data)
-	return err
+	return json.Marshal(notif)
 }
@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// stdioPair returns a transport plus the client's ends of its stdin/stdout pipes.
+func stdioPair(t *testing.T) (*StdioTransport, *bufio.Reader, io.Writer) {
+	t.Helper()
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	t.Cleanup(func() {
+		clientOut.Close()
+		serverOut.Close()
+	})
+	return NewStdioTransport(serverIn, serverOut), bufio.NewReader(clientIn), clientOut
+}
+
+func TestStdioTransportSendRequestRoutesResponse(t *testing.T) {
+	tr, fromServer, toServer := stdioPair(t)
+
+	reqs := make(chan *Request, 1)
+	go func() {
+		for {
+			req, err := tr.ReadMessage()
+			if err != nil {
+				close(reqs)
+				return
+			}
+			reqs <- req
+		}
+	}()
+
+	// Fake client: answer the sampling request, then send a regular request.
+	go func() {
+		line, err := fromServer.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var req Request
+		_ = json.Unmarshal(line, &req)
+		if req.Method != "sampling/createMessage" {
+			t.Errorf("unexpected server request %q", req.Method)
+		}
+		resp, _ := NewResponse(req.ID, CreateMessageResult{Role: "assistant", Content: ContentBlock{Type: "text", Text: "hi"}, Model: "client-model"})
+		b, _ := json.Marshal(resp)
+		fmt.Fprintf(toServer, "%s\n", b)
+		fmt.Fprintln(toServer, `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := CreateMessage(ctx, tr, nil, CreateMessageParams{
+		Messages:  []SamplingMessage{{Role: "user", Content: ContentBlock{Type: "text", Text: "hello"}}},
+		MaxTokens: 16,
+	})
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if res.Content.Text != "hi" || res.Model != "client-model" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	// The response was consumed by SendRequest; the next message is the client's request.
+	req := <-reqs
+	if req == nil || req.Method != "ping" || req.ID != float64(1) {
+		t.Fatalf("expected ping request, got %+v", req)
+	}
+}
+
+func TestStdioTransportSendRequestCancelNotifiesClient(t *testing.T) {
+	tr, fromServer, _ := stdioPair(t)
+
+	lines := make(chan string, 2)
+	go func() {
+		for {
+			line, err := fromServer.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- line
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := tr.SendRequest(ctx, nil, "sampling/createMessage", CreateMessageParams{MaxTokens: 1})
+		errc <- err
+	}()
+
+	var req Request
+	_ = json.Unmarshal([]byte(<-lines), &req)
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	var note struct {
+		Method string          `json:"method"`
+		Params CancelledParams `json:"params"`
+	}
+	_ = json.Unmarshal([]byte(<-lines), &note)
+	if note.Method != "notifications/cancelled" || note.Params.RequestID != req.ID {
+		t.Fatalf("expected notifications/cancelled for %v, got %+v", req.ID, note)
+	}
+}